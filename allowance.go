@@ -0,0 +1,201 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrInsufficientAllowance is returned when a spender is not approved for enough of the owner's balance
+var ErrInsufficientAllowance = errors.New("ERC20: insufficient allowance")
+
+// ErrAllowanceChanged is returned by ApproveExpect when the stored allowance is not the expected one
+var ErrAllowanceChanged = errors.New("ERC20: allowance changed")
+
+// Approve lets spender move up to amount of the owner's balance
+// Replaces any existing allowance, which never expires
+func Approve(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner, spender Address, amount int) error {
+	return approve(ctx, conn, tokenID, owner, spender, amount, nil)
+}
+
+// ApproveWithExpiry lets spender move up to amount of the owner's balance until expiresAt
+// After that the allowance is treated as zero
+func ApproveWithExpiry(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner, spender Address, amount int, expiresAt time.Time) error {
+	return approve(ctx, conn, tokenID, owner, spender, amount, &expiresAt)
+}
+
+// upsertAllowanceQ sets an allowance, replacing any existing one
+// Takes token_id, owner, spender, amount, expires_at
+const upsertAllowanceQ = `
+INSERT INTO allowances (token_id, owner, spender, amount, expires_at) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (token_id, owner, spender) DO UPDATE SET amount = EXCLUDED.amount, expires_at = EXCLUDED.expires_at, updated_at = now()`
+
+func approve(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner, spender Address, amount int, expiresAt *time.Time) error {
+	if amount < 0 {
+		return terror.Error(ErrInvalidAmount, "Allowance can not be negative")
+	}
+	_, err := conn.Exec(ctx, upsertAllowanceQ, tokenID, owner, spender, amount, expiresAt)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "owner", owner, "spender", spender, "amount", amount)
+		return terror.Error(err, "Could not approve")
+	}
+	return nil
+}
+
+// ApproveExpect sets the allowance to newAmount only if it is currently currentExpected
+// Missing and expired allowances count as zero. Guards against a spender using the old allowance
+// between a client reading it and replacing it
+func ApproveExpect(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner, spender Address, currentExpected, newAmount int) error {
+	if newAmount < 0 {
+		return terror.Error(ErrInvalidAmount, "Allowance can not be negative")
+	}
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		q := `
+SELECT amount FROM allowances
+WHERE token_id = $1 AND owner = $2 AND spender = $3 AND (expires_at IS NULL OR expires_at > now())
+FOR UPDATE`
+		var current int
+		err := tx.QueryRow(ctx, q, tokenID, owner, spender).Scan(&current)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if current != currentExpected {
+			return ErrAllowanceChanged
+		}
+		_, err = tx.Exec(ctx, upsertAllowanceQ, tokenID, owner, spender, newAmount, nil)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "owner", owner, "spender", spender, "expected", currentExpected, "amount", newAmount)
+		return terror.Error(err, "Could not approve")
+	}
+	return nil
+}
+
+// Allowance returns how much spender may still move of the owner's balance
+// Missing and expired allowances are zero
+func Allowance(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner, spender Address) (int, error) {
+	q := `
+SELECT amount FROM allowances
+WHERE token_id = $1 AND owner = $2 AND spender = $3 AND (expires_at IS NULL OR expires_at > now())`
+	var amount int
+	err := conn.QueryRow(ctx, q, tokenID, owner, spender).Scan(&amount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "owner", owner, "spender", spender)
+		return 0, terror.Error(err, "Could not get allowance")
+	}
+	return amount, nil
+}
+
+// AllowanceEntry is one owner's live allowance to a spender
+// ExpiresAt is nil for an allowance that never expires
+type AllowanceEntry struct {
+	Owner     Address
+	Amount    int
+	ExpiresAt *time.Time
+}
+
+// AllowancesForSpender returns every owner that has approved spender for a non-zero, unexpired amount, ordered by owner
+func AllowancesForSpender(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, spender Address) ([]AllowanceEntry, error) {
+	q := `
+SELECT owner, amount, expires_at FROM allowances
+WHERE token_id = $1 AND spender = $2 AND amount > 0 AND (expires_at IS NULL OR expires_at > now())
+ORDER BY owner`
+	rows, err := conn.Query(ctx, q, tokenID, spender)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "spender", spender)
+		return nil, terror.Error(err, "Could not get allowances")
+	}
+	defer rows.Close()
+	entries := []AllowanceEntry{}
+	for rows.Next() {
+		var entry AllowanceEntry
+		err = rows.Scan(&entry.Owner, &entry.Amount, &entry.ExpiresAt)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "id", tokenID, "spender", spender)
+			return nil, terror.Error(err, "Could not get allowances")
+		}
+		entries = append(entries, entry)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error(), "id", tokenID, "spender", spender)
+		return nil, terror.Error(rows.Err(), "Could not get allowances")
+	}
+	return entries, nil
+}
+
+// spendAllowance deducts amount from the spender's allowance inside tx
+// Any spend limit on the pair is enforced as well
+func spendAllowance(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, owner, spender Address, amount int) error {
+	err := spendLimit(ctx, tx, tokenID, owner, spender, amount)
+	if err != nil {
+		return err
+	}
+	q := `
+SELECT amount FROM allowances
+WHERE token_id = $1 AND owner = $2 AND spender = $3 AND (expires_at IS NULL OR expires_at > now())
+FOR UPDATE`
+	var allowance int
+	err = tx.QueryRow(ctx, q, tokenID, owner, spender).Scan(&allowance)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if allowance < amount {
+		return ErrInsufficientAllowance
+	}
+	updateQ := `UPDATE allowances SET amount = amount - $1, updated_at = now() WHERE token_id = $2 AND owner = $3 AND spender = $4`
+	_, err = tx.Exec(ctx, updateQ, amount, tokenID, owner, spender)
+	return err
+}
+
+// BurnFrom burns amount of the owner's balance on behalf of spender
+// The spender's allowance is reduced by amount
+func BurnFrom(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, spender, owner Address, amount int) error {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := spendAllowance(ctx, tx, tokenID, owner, spender, amount)
+		if err != nil {
+			return err
+		}
+		return burn(ctx, tx, tokenID, owner, amount)
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "spender", spender, "owner", owner, "amount", amount)
+		return terror.Error(err, "Could not burn")
+	}
+	return nil
+}
+
+// TransferFrom moves balance from sender to recipient on behalf of spender
+// The spender's allowance is reduced by amount
+func TransferFrom(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, spender, sender, recipient Address, amount int) (bool, error) {
+	return transferFrom(ctx, conn, tokenID, spender, sender, recipient, amount, transferOptions{})
+}
+
+// transferFrom runs the checks in opts inside the transfer's transaction before any balance changes
+func transferFrom(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, spender, sender, recipient Address, amount int, opts transferOptions) (bool, error) {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := opts.check(ctx, tx, tokenID, sender, recipient, amount)
+		if err != nil {
+			return err
+		}
+		err = spendAllowance(ctx, tx, tokenID, sender, spender, amount)
+		if err != nil {
+			return err
+		}
+		_, err = transfer(ctx, tx, tokenID, sender, recipient, amount, opts.rounding)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "spender", spender, "sender", sender, "recipient", recipient, "amount", amount)
+		return false, terror.Error(err, "Could not transfer")
+	}
+	return true, nil
+}
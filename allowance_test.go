@@ -0,0 +1,120 @@
+package erc20_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestAllowanceExpiry(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, spender, recipient := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+
+	err := erc20.ApproveWithExpiry(ctx, conn, tokenID, owner, spender, 100, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.TransferFrom(ctx, conn, tokenID, spender, owner, recipient, 40)
+	if err != nil {
+		t.Fatalf("TransferFrom before expiry: %v", err)
+	}
+	if got := allowance(t, conn, tokenID, owner, spender); got != 60 {
+		t.Errorf("allowance = %d, want 60", got)
+	}
+
+	err = erc20.ApproveWithExpiry(ctx, conn, tokenID, owner, spender, 100, time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := allowance(t, conn, tokenID, owner, spender); got != 0 {
+		t.Errorf("expired allowance = %d, want 0", got)
+	}
+	_, err = erc20.TransferFrom(ctx, conn, tokenID, spender, owner, recipient, 40)
+	if !errors.Is(err, erc20.ErrInsufficientAllowance) {
+		t.Errorf("TransferFrom after expiry error = %v, want ErrInsufficientAllowance", err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 960, recipient: 40})
+}
+
+func TestApproveExpect(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, spender, recipient := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+
+	err := erc20.ApproveExpect(ctx, conn, tokenID, owner, spender, 0, 100)
+	if err != nil {
+		t.Fatalf("ApproveExpect from no allowance: %v", err)
+	}
+	// The spender uses part of the allowance before the owner lowers it
+	_, err = erc20.TransferFrom(ctx, conn, tokenID, spender, owner, recipient, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.ApproveExpect(ctx, conn, tokenID, owner, spender, 100, 50)
+	if !errors.Is(err, erc20.ErrAllowanceChanged) {
+		t.Errorf("ApproveExpect with a stale expectation error = %v, want ErrAllowanceChanged", err)
+	}
+	if got := allowance(t, conn, tokenID, owner, spender); got != 70 {
+		t.Errorf("allowance after rejected change = %d, want 70", got)
+	}
+	err = erc20.ApproveExpect(ctx, conn, tokenID, owner, spender, 70, 50)
+	if err != nil {
+		t.Fatalf("ApproveExpect with the current value: %v", err)
+	}
+	if got := allowance(t, conn, tokenID, owner, spender); got != 50 {
+		t.Errorf("allowance = %d, want 50", got)
+	}
+}
+
+func TestAllowancesForSpender(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	issuer, spender := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, issuer, 0)
+	approved, dated, zeroed, expired := newAddress(t), newAddress(t), newAddress(t), newAddress(t)
+	approve := func(owner erc20.Address, amount int, expiry time.Time) {
+		err := erc20.ApproveWithExpiry(ctx, conn, tokenID, owner, spender, amount, expiry)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := erc20.Approve(ctx, conn, tokenID, approved, spender, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approve(dated, 50, time.Now().Add(time.Hour))
+	approve(zeroed, 0, time.Now().Add(time.Hour))
+	approve(expired, 75, time.Now().Add(-time.Second))
+	// Allowances for other spenders are not listed
+	err = erc20.Approve(ctx, conn, tokenID, approved, newAddress(t), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := erc20.AllowancesForSpender(ctx, conn, tokenID, spender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[erc20.Address]int{}
+	for _, entry := range entries {
+		got[entry.Owner] = entry.Amount
+		if (entry.Owner == dated) != (entry.ExpiresAt != nil) {
+			t.Errorf("allowance from %s expires at %v", uuid.UUID(entry.Owner), entry.ExpiresAt)
+		}
+	}
+	want := map[erc20.Address]int{approved: 100, dated: 50}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AllowancesForSpender = %v, want %v", got, want)
+	}
+	for i := 1; i < len(entries); i++ {
+		if uuid.UUID(entries[i-1].Owner).String() > uuid.UUID(entries[i].Owner).String() {
+			t.Error("AllowancesForSpender is not ordered by owner")
+		}
+	}
+}
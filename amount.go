@@ -0,0 +1,198 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrInvalidAmount is returned when a display amount can not be parsed
+var ErrInvalidAmount = errors.New("ERC20: invalid amount")
+
+// Amount is a quantity of a token in base units, never display units
+// Use it in place of a bare int where a display amount could be passed by mistake
+type Amount int
+
+// Bounds of Amount, spelled out as math.MaxInt and math.MinInt need Go 1.17
+const (
+	maxAmount = Amount(^uint(0) >> 1)
+	minAmount = -maxAmount - 1
+)
+
+// NewAmount returns n base units as an Amount, rejecting negatives
+func NewAmount(n int) (Amount, error) {
+	if n < 0 {
+		return 0, terror.Error(ErrNegativeAmount, "Amount can not be negative")
+	}
+	return Amount(n), nil
+}
+
+// Add returns a + b, or ErrInvalidAmount if the sum overflows
+func (a Amount) Add(b Amount) (Amount, error) {
+	if (b > 0 && a > maxAmount-b) || (b < 0 && a < minAmount-b) {
+		return 0, terror.Error(ErrInvalidAmount, "Amount overflows")
+	}
+	return a + b, nil
+}
+
+// Sub returns a - b, or ErrNegativeAmount if b is larger than a
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if b > a {
+		return 0, terror.Error(ErrNegativeAmount, "Amount can not be negative")
+	}
+	return a - b, nil
+}
+
+// Cmp returns -1, 0 or +1 as a is less than, equal to or greater than b
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Int returns the amount in base units
+func (a Amount) Int() int {
+	return int(a)
+}
+
+// Format renders the amount in display units for a token with the given decimals
+func (a Amount) Format(decimals int) string {
+	return FormatAmount(int(a), decimals)
+}
+
+// FormatAmount converts an amount in base units into a human readable decimal string
+// 1500000000000000000 with 18 decimals formats as 1.5
+func FormatAmount(amount int, decimals int) string {
+	negative := amount < 0
+	digits := strconv.Itoa(amount)
+	if negative {
+		digits = digits[1:]
+	}
+	if decimals > 0 {
+		if len(digits) <= decimals {
+			digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+		}
+		whole := digits[:len(digits)-decimals]
+		frac := strings.TrimRight(digits[len(digits)-decimals:], "0")
+		digits = whole
+		if frac != "" {
+			digits = whole + "." + frac
+		}
+	}
+	if negative {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Errors returned by ParseAmount, each wrapping ErrInvalidAmount
+var (
+	ErrNegativeAmount   = fmt.Errorf("%w: negative", ErrInvalidAmount)
+	ErrTooPrecise       = fmt.Errorf("%w: more fractional digits than decimals", ErrInvalidAmount)
+	ErrScientificAmount = fmt.Errorf("%w: scientific notation", ErrInvalidAmount)
+	ErrNotNumeric       = fmt.Errorf("%w: not a decimal number", ErrInvalidAmount)
+)
+
+// ParseAmount converts a human readable decimal string into base units without losing precision
+// Negative amounts, scientific notation and inputs with more fractional digits than decimals are rejected
+func ParseAmount(display string, decimals int) (*big.Int, error) {
+	return parseAmount(display, decimals, false)
+}
+
+// ParseSignedAmount is ParseAmount allowing a leading minus sign
+func ParseSignedAmount(display string, decimals int) (*big.Int, error) {
+	return parseAmount(display, decimals, true)
+}
+
+func parseAmount(display string, decimals int, allowNegative bool) (*big.Int, error) {
+	s := strings.TrimSpace(display)
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		if !allowNegative {
+			return nil, terror.Error(ErrNegativeAmount, "Amount can not be negative")
+		}
+		s = s[1:]
+	}
+	if strings.ContainsAny(s, "eE") {
+		return nil, terror.Error(ErrScientificAmount, "Amount can not use scientific notation")
+	}
+	whole, frac := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" && frac == "" {
+		return nil, terror.Error(ErrNotNumeric, "Amount is empty")
+	}
+	digits := whole + frac
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return nil, terror.Error(ErrNotNumeric, "Amount is not a decimal number")
+		}
+	}
+	if len(frac) > decimals {
+		return nil, terror.Error(ErrTooPrecise, "Amount has more fractional digits than the token decimals")
+	}
+	amount, ok := new(big.Int).SetString(digits+strings.Repeat("0", decimals-len(frac)), 10)
+	if !ok {
+		return nil, terror.Error(ErrNotNumeric, "Amount is not a decimal number")
+	}
+	if negative {
+		amount.Neg(amount)
+	}
+	return amount, nil
+}
+
+// TransferDisplay transfers an amount given in display units, such as "1.5", converted with the token's decimals
+func TransferDisplay(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, from, to Address, displayAmount string) (bool, error) {
+	decimals, err := Decimals(ctx, conn, tokenID)
+	if err != nil {
+		return false, err
+	}
+	amount, err := ParseAmount(displayAmount, decimals)
+	if err != nil {
+		return false, err
+	}
+	if !amount.IsInt64() {
+		return false, terror.Error(ErrInvalidAmount, "Amount is out of range")
+	}
+	return Transfer(ctx, conn, tokenID, from, to, Amount(amount.Int64()))
+}
+
+// TransferInt is Transfer taking a bare int, kept for callers not yet using Amount
+func TransferInt(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, from, to Address, amount int) (bool, error) {
+	return Transfer(ctx, conn, tokenID, from, to, Amount(amount))
+}
+
+// MintInt is Mint taking a bare int, kept for callers not yet using Amount
+func MintInt(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount int) error {
+	return Mint(ctx, conn, tokenID, account, Amount(amount))
+}
+
+// BurnInt is Burn taking a bare int, kept for callers not yet using Amount
+func BurnInt(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount int) error {
+	return Burn(ctx, conn, tokenID, account, Amount(amount))
+}
+
+// BalanceOfInt is BalanceOf returning a bare int
+func BalanceOfInt(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner Address) (int, error) {
+	balance, err := BalanceOf(ctx, conn, tokenID, owner)
+	return balance.Int(), err
+}
+
+// TotalSupplyInt is TotalSupply returning a bare int
+func TotalSupplyInt(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) (int, error) {
+	supply, err := TotalSupply(ctx, conn, tokenID)
+	return supply.Int(), err
+}
@@ -0,0 +1,220 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount   int
+		decimals int
+		want     string
+	}{
+		{1500000000000000000, 18, "1.5"},
+		{1000000000000000000, 18, "1"},
+		{1, 18, "0.000000000000000001"},
+		{0, 18, "0"},
+		{120, 2, "1.2"},
+		{100, 2, "1"},
+		{5, 0, "5"},
+		{-250, 2, "-2.5"},
+	}
+	for _, tt := range tests {
+		got := erc20.FormatAmount(tt.amount, tt.decimals)
+		if got != tt.want {
+			t.Errorf("FormatAmount(%d, %d) = %q, want %q", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		display  string
+		decimals int
+		want     string
+		err      error
+	}{
+		{"1.5", 18, "1500000000000000000", nil},
+		{"1.50", 18, "1500000000000000000", nil},
+		{"1", 18, "1000000000000000000", nil},
+		{"0.000000000000000001", 18, "1", nil},
+		{".5", 2, "50", nil},
+		{"7", 0, "7", nil},
+		{"0", 18, "0", nil},
+		{"123456789012345678901234567890123456789012345678901234567890", 0, "123456789012345678901234567890123456789012345678901234567890", nil},
+		{"123456789012345678901234567890123456789012.345678901234567890", 18, "123456789012345678901234567890123456789012345678901234567890", nil},
+		{"1.234", 2, "", erc20.ErrTooPrecise},
+		{"0.5", 0, "", erc20.ErrTooPrecise},
+		{"0.0000000000000000001", 18, "", erc20.ErrTooPrecise},
+		{"-1", 2, "", erc20.ErrNegativeAmount},
+		{"1e3", 2, "", erc20.ErrScientificAmount},
+		{"1E3", 2, "", erc20.ErrScientificAmount},
+		{"1.2.3", 2, "", erc20.ErrNotNumeric},
+		{"12a", 2, "", erc20.ErrNotNumeric},
+		{"+1", 2, "", erc20.ErrNotNumeric},
+		{"1 000", 2, "", erc20.ErrNotNumeric},
+		{"", 2, "", erc20.ErrNotNumeric},
+	}
+	for _, tt := range tests {
+		got, err := erc20.ParseAmount(tt.display, tt.decimals)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) || !errors.Is(err, erc20.ErrInvalidAmount) {
+				t.Errorf("ParseAmount(%q, %d) error = %v, want %v", tt.display, tt.decimals, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAmount(%q, %d): %v", tt.display, tt.decimals, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseAmount(%q, %d) = %s, want %s", tt.display, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestParseSignedAmount(t *testing.T) {
+	got, err := erc20.ParseSignedAmount("-1.25", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != "-125" {
+		t.Errorf("ParseSignedAmount(%q, 2) = %s, want -125", "-1.25", got)
+	}
+	_, err = erc20.ParseSignedAmount("-1.255", 2)
+	if !errors.Is(err, erc20.ErrTooPrecise) {
+		t.Errorf("ParseSignedAmount over precision error = %v, want ErrTooPrecise", err)
+	}
+}
+
+func TestAmountRoundTrip(t *testing.T) {
+	for _, display := range []string{"0", "1", "1.5", "0.000000000000000001", "4.25"} {
+		amount, err := erc20.ParseAmount(display, 18)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := erc20.FormatAmount(int(amount.Int64()), 18)
+		if got != display {
+			t.Errorf("round trip of %q = %q", display, got)
+		}
+	}
+}
+
+func TestTransferDisplay(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, recipient := newAddress(t), newAddress(t)
+	// Balances are INTEGER columns, too narrow for whole units of an 18 decimal token
+	tokenID, err := erc20.Factory(ctx, conn, erc20test.NewAccountBook(t, conn), owner, "Display", "DSP", 6, 10000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = erc20.TransferDisplay(ctx, conn, tokenID, owner, recipient, "1.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 8500000, recipient: 1500000})
+
+	_, err = erc20.TransferDisplay(ctx, conn, tokenID, owner, recipient, "0.0000001")
+	if !errors.Is(err, erc20.ErrTooPrecise) {
+		t.Errorf("TransferDisplay with 7 fractional digits error = %v, want ErrTooPrecise", err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 8500000, recipient: 1500000})
+}
+
+func TestAmountArithmetic(t *testing.T) {
+	a, err := erc20.NewAmount(150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := erc20.NewAmount(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.NewAmount(-1)
+	if !errors.Is(err, erc20.ErrNegativeAmount) {
+		t.Errorf("NewAmount(-1) error = %v, want ErrNegativeAmount", err)
+	}
+
+	sum, err := a.Add(b)
+	if err != nil || sum != 200 {
+		t.Errorf("150 + 50 = %d, %v, want 200", sum, err)
+	}
+	diff, err := a.Sub(b)
+	if err != nil || diff != 100 {
+		t.Errorf("150 - 50 = %d, %v, want 100", diff, err)
+	}
+	_, err = b.Sub(a)
+	if !errors.Is(err, erc20.ErrNegativeAmount) {
+		t.Errorf("50 - 150 error = %v, want ErrNegativeAmount", err)
+	}
+	max := erc20.Amount(int(^uint(0) >> 1))
+	_, err = max.Add(1)
+	if !errors.Is(err, erc20.ErrInvalidAmount) {
+		t.Errorf("overflowing Add error = %v, want ErrInvalidAmount", err)
+	}
+	for _, tt := range []struct {
+		a, b erc20.Amount
+		want int
+	}{{a, b, 1}, {b, a, -1}, {a, a, 0}} {
+		if got := tt.a.Cmp(tt.b); got != tt.want {
+			t.Errorf("%d.Cmp(%d) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestAmountFormat(t *testing.T) {
+	tests := []struct {
+		amount   erc20.Amount
+		decimals int
+		want     string
+	}{
+		{1500000000000000000, 18, "1.5"},
+		{1, 18, "0.000000000000000001"},
+		{150, 2, "1.5"},
+		{150, 0, "150"},
+		{0, 6, "0"},
+		{1000000, 6, "1"},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.Format(tt.decimals); got != tt.want {
+			t.Errorf("Amount(%d).Format(%d) = %q, want %q", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestIntShims(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, recipient := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+
+	transferred, minted, burned := 300, 50, 100
+	_, err := erc20.TransferInt(ctx, conn, tokenID, owner, recipient, transferred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.MintInt(ctx, conn, tokenID, recipient, minted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.BurnInt(ctx, conn, tokenID, owner, burned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance, err := erc20.BalanceOfInt(ctx, conn, tokenID, recipient)
+	if err != nil || balance != 350 {
+		t.Errorf("BalanceOfInt = %d, %v, want 350", balance, err)
+	}
+	supply, err := erc20.TotalSupplyInt(ctx, conn, tokenID)
+	if err != nil || supply != 950 {
+		t.Errorf("TotalSupplyInt = %d, %v, want 950", supply, err)
+	}
+	amount, err := erc20.BalanceOf(ctx, conn, tokenID, recipient)
+	if err != nil || amount.Int() != balance {
+		t.Errorf("BalanceOf = %d, %v, want %d", amount, err, balance)
+	}
+}
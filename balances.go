@@ -0,0 +1,91 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrEscrowAddress is returned when setting the balance of an escrow address, which only its hold, stake,
+// vesting schedule or scheduled transfer may change
+var ErrEscrowAddress = errors.New("ERC20: address is an escrow")
+
+// SetBalances writes the given balances of a token in one transaction, for migrating from another system
+// Balances not in the map are left untouched, each changed balance is recorded as an adjustment event.
+// Escrow addresses are rejected with ErrEscrowAddress. With reconcileSupply the total supply is set to the sum of all balances,
+// otherwise the write is rejected with ErrSupplyMismatch unless the sum already matches the total supply
+func SetBalances(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, balances map[Address]int, reconcileSupply bool) error {
+	for _, balance := range balances {
+		if balance < 0 {
+			return terror.Error(ErrInvalidAmount, "Balance must not be negative")
+		}
+	}
+	err := beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		err := activeToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		for owner, balance := range balances {
+			err = adjustBalance(ctx, tx, tokenID, owner, balance)
+			if err != nil {
+				return err
+			}
+		}
+		var totalSupply, summed int
+		checkQ := `
+SELECT total_supply, (SELECT COALESCE(SUM(balance), 0) FROM addresses WHERE token_id = $1)
+FROM tokens WHERE id = $1 FOR UPDATE`
+		err = tx.QueryRow(ctx, checkQ, tokenID).Scan(&totalSupply, &summed)
+		if err != nil {
+			return err
+		}
+		if totalSupply == summed {
+			return nil
+		}
+		if !reconcileSupply {
+			return ErrSupplyMismatch
+		}
+		supplyQ := `UPDATE tokens SET total_supply = $1, updated_at = now() WHERE id = $2`
+		_, err = tx.Exec(ctx, supplyQ, summed, tokenID)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return terror.Error(err, "Could not set balances")
+	}
+	return nil
+}
+
+// adjustBalance sets the owner's balance inside tx, creating the address if needed
+// A change is recorded as an adjustment event, so replaying the ledger still arrives at the new balance.
+// Returns ErrEscrowAddress for an escrow address
+func adjustBalance(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, owner Address, balance int) error {
+	q := `SELECT balance, NOT (` + notEscrowQ + `) FROM addresses WHERE token_id = $1 AND owner = $2 FOR UPDATE`
+	var current int
+	var escrow bool
+	err := tx.QueryRow(ctx, q, tokenID, owner).Scan(&current, &escrow)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if escrow {
+		return ErrEscrowAddress
+	}
+	upsertQ := `
+INSERT INTO addresses (token_id, owner, balance) VALUES ($1, $2, $3)
+ON CONFLICT (token_id, owner) DO UPDATE SET balance = EXCLUDED.balance, updated_at = now()`
+	_, err = tx.Exec(ctx, upsertQ, tokenID, owner, balance)
+	if err != nil {
+		return err
+	}
+	switch {
+	case balance > current:
+		_, err = recordEvent(ctx, tx, tokenID, EventAdjustment, ZeroAddress, owner, balance-current)
+	case balance < current:
+		_, err = recordEvent(ctx, tx, tokenID, EventAdjustment, owner, ZeroAddress, current-balance)
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestSetBalancesReconcileSupply(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, a, b := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+
+	err := erc20.SetBalances(ctx, conn, tokenID, map[erc20.Address]int{owner: 40, a: 250, b: 10}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 40, a: 250, b: 10})
+	if got := totalSupply(t, conn, tokenID); got != 300 {
+		t.Errorf("total supply = %d, want 300", got)
+	}
+	wantConserved(t, conn, tokenID)
+}
+
+func TestSetBalancesSupplyMismatch(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, a := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+
+	err := erc20.SetBalances(ctx, conn, tokenID, map[erc20.Address]int{owner: 60, a: 50}, false)
+	if !errors.Is(err, erc20.ErrSupplyMismatch) {
+		t.Errorf("SetBalances adding up to more than the supply = %v, want %v", err, erc20.ErrSupplyMismatch)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 100, a: 0})
+
+	err = erc20.SetBalances(ctx, conn, tokenID, map[erc20.Address]int{owner: 50, a: 50}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 50, a: 50})
+	if got := totalSupply(t, conn, tokenID); got != 100 {
+		t.Errorf("total supply = %d, want 100", got)
+	}
+}
+
+func TestSetBalancesRecordsAdjustments(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, a := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+
+	err := erc20.SetBalances(ctx, conn, tokenID, map[erc20.Address]int{owner: 70, a: 80}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	historical, err := erc20.BalanceOfAtTime(ctx, conn, tokenID, a, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if historical != 80 {
+		t.Errorf("BalanceOfAtTime after SetBalances = %d, want 80", historical)
+	}
+	statement, err := erc20.Statement(ctx, conn, tokenID, owner, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statement.ClosingBalance != 70 {
+		t.Errorf("statement closing balance = %d, want 70", statement.ClosingBalance)
+	}
+
+	err = erc20.RebuildBalances(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 70, a: 80})
+	if got := totalSupply(t, conn, tokenID); got != 150 {
+		t.Errorf("total supply after rebuild = %d, want 150", got)
+	}
+}
+
+func TestSetBalancesInactiveToken(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+	err := erc20.Pause(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.SetBalances(ctx, conn, tokenID, map[erc20.Address]int{owner: 1}, true)
+	if !errors.Is(err, erc20.ErrPaused) {
+		t.Errorf("SetBalances while paused = %v, want %v", err, erc20.ErrPaused)
+	}
+}
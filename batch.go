@@ -0,0 +1,232 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// TransferLeg is a single debit and credit within TransferMany
+type TransferLeg struct {
+	From   Address
+	To     Address
+	Amount int
+}
+
+// TransferMany applies every leg in one transaction
+// Each leg is a full transfer, paying the token's fee and burn and held to its max transfer.
+// If any leg fails the whole batch is rolled back
+func TransferMany(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, legs []TransferLeg) error {
+	return transferManyWith(ctx, conn, tokenID, legs, transferOptions{})
+}
+
+// transferManyWith is TransferMany running opts before every leg
+func transferManyWith(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, legs []TransferLeg, opts transferOptions) error {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		return transferMany(ctx, tx, tokenID, legs, opts)
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "legs", len(legs))
+		return terror.Error(err, "Could not transfer")
+	}
+	return nil
+}
+
+// transferMany applies every leg inside tx
+func transferMany(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, legs []TransferLeg, opts transferOptions) error {
+	err := activeToken(ctx, tx, tokenID)
+	if err != nil {
+		return err
+	}
+	for _, leg := range legs {
+		err = opts.check(ctx, tx, tokenID, leg.From, leg.To, leg.Amount)
+		if err != nil {
+			return err
+		}
+		_, err = transfer(ctx, tx, tokenID, leg.From, leg.To, leg.Amount, opts.rounding)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LegResult is the outcome of a single leg of TransferBestEffort
+// Err is nil when the leg was committed
+type LegResult struct {
+	Leg TransferLeg
+	Err error
+}
+
+// TransferBestEffort applies each leg in its own transaction, so one failing leg does not undo the others
+// Each leg is a full transfer, paying the token's fee and burn and held to its max transfer.
+// Legs rejected for balance, amount or token reasons are reported in their LegResult.
+// Any other error stops the batch and is returned with the results of the legs attempted so far
+func TransferBestEffort(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, legs []TransferLeg) ([]LegResult, error) {
+	return transferBestEffortWith(ctx, conn, tokenID, legs, transferOptions{})
+}
+
+// transferBestEffortWith is TransferBestEffort running opts before every leg
+// A leg the options reject is reported in its LegResult
+func transferBestEffortWith(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, legs []TransferLeg, opts transferOptions) ([]LegResult, error) {
+	results := make([]LegResult, 0, len(legs))
+	for _, leg := range legs {
+		leg := leg
+		var rejected error
+		err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+			rejected = opts.check(ctx, tx, tokenID, leg.From, leg.To, leg.Amount)
+			if rejected != nil {
+				return rejected
+			}
+			_, err := transfer(ctx, tx, tokenID, leg.From, leg.To, leg.Amount, opts.rounding)
+			return err
+		})
+		if err != nil && err != rejected && !isLegError(err) {
+			logger(ctx).Errorw(err.Error(), "id", tokenID, "from", leg.From, "to", leg.To, "amount", leg.Amount)
+			return results, terror.Error(err, "Could not transfer")
+		}
+		results = append(results, LegResult{Leg: leg, Err: err})
+	}
+	return results, nil
+}
+
+// isLegError reports whether err is specific to a single leg rather than a systemic failure
+func isLegError(err error) bool {
+	for _, legErr := range []error{ErrInsufficientBalance, ErrInvalidAmount, ErrTransferTooLarge, ErrTokenNotFound, ErrPaused} {
+		if errors.Is(err, legErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// MintItem is a single recipient and amount within MintBatch
+type MintItem struct {
+	Account Address
+	Amount  int
+}
+
+// MintBatch credits every item in one transaction and adds the batch sum to the total supply once
+// Work is done a statement at a time over the whole batch rather than per item, so the number of
+// round trips does not grow with the batch. A negative amount rejects the whole batch
+func MintBatch(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, mints []MintItem) error {
+	total := 0
+	accounts := make([]string, 0, len(mints))
+	amounts := make([]int, 0, len(mints))
+	for _, item := range mints {
+		if item.Amount < 0 {
+			return terror.Error(ErrInvalidAmount, "Amount must not be negative")
+		}
+		total += item.Amount
+		accounts = append(accounts, uuid.UUID(item.Account).String())
+		amounts = append(amounts, item.Amount)
+	}
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := activeToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		q := `UPDATE tokens SET total_supply = total_supply + $1, updated_at = now() WHERE id = $2`
+		_, err = tx.Exec(ctx, q, total, tokenID)
+		if err != nil {
+			return err
+		}
+		return mintBulk(ctx, tx, tokenID, accounts, amounts)
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "mints", len(mints), "total", total)
+		return terror.Error(err, "Could not mint")
+	}
+	return nil
+}
+
+// mintBulk credits amounts[i] to accounts[i] inside tx, tracks the mint totals and records a mint event per item
+// An account listed more than once is credited its summed amount in a single upsert
+func mintBulk(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, accounts []string, amounts []int) error {
+	creditQ := `
+INSERT INTO addresses (token_id, owner, balance)
+SELECT $1, owner, SUM(amount) FROM unnest($2::uuid[], $3::int[]) AS m (owner, amount) GROUP BY owner
+ON CONFLICT (token_id, owner) DO UPDATE SET balance = addresses.balance + EXCLUDED.balance, updated_at = now()`
+	_, err := tx.Exec(ctx, creditQ, tokenID, accounts, amounts)
+	if err != nil {
+		return err
+	}
+	totalsQ := `
+WITH totals AS (
+	INSERT INTO mint_totals (token_id, account, minted)
+	SELECT $1, account, SUM(amount) FROM unnest($2::uuid[], $3::int[]) AS m (account, amount) GROUP BY account
+	ON CONFLICT (token_id, account) DO UPDATE SET minted = mint_totals.minted + EXCLUDED.minted
+	RETURNING minted
+)
+SELECT EXISTS (
+	SELECT 1 FROM totals, tokens
+	WHERE tokens.id = $1 AND tokens.max_mint_per_address > 0 AND totals.minted > tokens.max_mint_per_address
+)`
+	var exceeded bool
+	err = tx.QueryRow(ctx, totalsQ, tokenID, accounts, amounts).Scan(&exceeded)
+	if err != nil {
+		return err
+	}
+	if exceeded {
+		return ErrMintCapExceeded
+	}
+	eventsQ := `
+INSERT INTO ledger_events (token_id, event_type, sender, recipient, amount)
+SELECT $1, $2, $3, recipient, amount FROM unnest($4::uuid[], $5::int[]) WITH ORDINALITY AS m (recipient, amount, n)
+ORDER BY n`
+	_, err = tx.Exec(ctx, eventsQ, tokenID, string(EventMint), ZeroAddress, accounts, amounts)
+	return err
+}
+
+// BurnItem is a single account and amount within BurnBatch
+type BurnItem struct {
+	Account Address
+	Amount  int
+}
+
+// BurnBatch debits every item in one transaction and removes the batch sum from the total supply once
+// If any account is short, or the supply would fall below its floor, nothing is burned
+func BurnBatch(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, burns []BurnItem) error {
+	total := 0
+	for _, item := range burns {
+		if item.Amount < 0 {
+			return terror.Error(ErrInvalidAmount, "Amount must not be negative")
+		}
+		total += item.Amount
+	}
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := activeToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		for _, item := range burns {
+			_, err = debit(ctx, tx, tokenID, item.Account, item.Amount)
+			if err != nil {
+				return err
+			}
+			_, err = recordEvent(ctx, tx, tokenID, EventBurn, item.Account, ZeroAddress, item.Amount)
+			if err != nil {
+				return err
+			}
+		}
+		q := `UPDATE tokens SET total_supply = total_supply - $1, updated_at = now() WHERE id = $2 RETURNING total_supply, min_supply`
+		var totalSupply, minSupply int
+		err = tx.QueryRow(ctx, q, total, tokenID).Scan(&totalSupply, &minSupply)
+		if err != nil {
+			return err
+		}
+		if totalSupply < minSupply {
+			return ErrBelowMinSupply
+		}
+		return nil
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "burns", len(burns), "total", total)
+		return terror.Error(err, "Could not burn")
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package erc20_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestTransferManyAllOrNothing(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	a, b, c := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, a, 100)
+
+	err := erc20.TransferMany(ctx, conn, tokenID, []erc20.TransferLeg{
+		{From: a, To: b, Amount: 30},
+		{From: b, To: c, Amount: 10},
+		{From: c, To: a, Amount: 50},
+	})
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Fatalf("TransferMany with an overdrawn last leg = %v, want %v", err, erc20.ErrInsufficientBalance)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{a: 100, b: 0, c: 0})
+	wantConserved(t, conn, tokenID)
+}
+
+func TestTransferManyPaysFeePerLeg(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	a, b, c, collector := newAddress(t), newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, a, 1000)
+	err := erc20.SetTransferFee(ctx, conn, tokenID, 100, collector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.TransferMany(ctx, conn, tokenID, []erc20.TransferLeg{
+		{From: a, To: b, Amount: 500},
+		{From: a, To: c, Amount: 200},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{a: 300, b: 495, c: 198, collector: 7})
+	wantConserved(t, conn, tokenID)
+}
+
+func TestTransferManyMaxTransfer(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	a, b := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, a, 100)
+	err := erc20.SetMaxTransfer(ctx, conn, tokenID, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.TransferMany(ctx, conn, tokenID, []erc20.TransferLeg{
+		{From: a, To: b, Amount: 40},
+		{From: a, To: b, Amount: 41},
+	})
+	if !errors.Is(err, erc20.ErrTransferTooLarge) {
+		t.Errorf("TransferMany over the max transfer = %v, want %v", err, erc20.ErrTransferTooLarge)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{a: 100, b: 0})
+}
+
+func TestClientTransferManyRunsHook(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	a, b, blocked := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, a, 100)
+	errBlocked := errors.New("blocked")
+	client := erc20.NewClient(conn, erc20.WithAutoCreateAddresses(true), erc20.WithTransferHook(func(ctx context.Context, tokenID uuid.UUID, sender, recipient erc20.Address, amount int) error {
+		if recipient == blocked {
+			return errBlocked
+		}
+		return nil
+	}))
+
+	err := client.TransferMany(ctx, tokenID, []erc20.TransferLeg{
+		{From: a, To: b, Amount: 10},
+		{From: a, To: blocked, Amount: 10},
+	})
+	if !errors.Is(err, errBlocked) {
+		t.Errorf("TransferMany to a blocked recipient = %v, want %v", err, errBlocked)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{a: 100, b: 0, blocked: 0})
+
+	results, err := client.TransferBestEffort(ctx, tokenID, []erc20.TransferLeg{
+		{From: a, To: b, Amount: 10},
+		{From: a, To: blocked, Amount: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err != nil || !errors.Is(results[1].Err, errBlocked) {
+		t.Errorf("TransferBestEffort results = %v, %v, want nil, %v", results[0].Err, results[1].Err, errBlocked)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{a: 90, b: 10, blocked: 0})
+}
+
+func TestTransferBestEffort(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	a, b, collector := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, a, 100)
+	err := erc20.SetTransferFee(ctx, conn, tokenID, 1000, collector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := erc20.TransferBestEffort(ctx, conn, tokenID, []erc20.TransferLeg{
+		{From: a, To: b, Amount: 50},
+		{From: b, To: a, Amount: 50},
+		{From: a, To: b, Amount: -1},
+		{From: a, To: b, Amount: 50},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []error{nil, erc20.ErrInsufficientBalance, erc20.ErrInvalidAmount, nil}
+	for i, result := range results {
+		if !errors.Is(result.Err, want[i]) {
+			t.Errorf("leg %d = %v, want %v", i, result.Err, want[i])
+		}
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{a: 0, b: 90, collector: 10})
+	wantConserved(t, conn, tokenID)
+}
+
+func TestTransferCAS(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	a, b, collector := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, a, 1000)
+	err := erc20.SetTransferFee(ctx, conn, tokenID, 100, collector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.SetTransferBurn(ctx, conn, tokenID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.TransferCAS(ctx, conn, tokenID, a, b, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{a: 500, b: 490, collector: 5})
+	if got := totalSupply(t, conn, tokenID); got != 995 {
+		t.Errorf("total supply = %d, want 995", got)
+	}
+	wantConserved(t, conn, tokenID)
+
+	err = erc20.TransferCAS(ctx, conn, tokenID, a, b, 501)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("TransferCAS overdraw = %v, want %v", err, erc20.ErrInsufficientBalance)
+	}
+	err = erc20.SetMaxTransfer(ctx, conn, tokenID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.TransferCAS(ctx, conn, tokenID, a, b, 101)
+	if !errors.Is(err, erc20.ErrTransferTooLarge) {
+		t.Errorf("TransferCAS over the max transfer = %v, want %v", err, erc20.ErrTransferTooLarge)
+	}
+	err = erc20.Pause(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.TransferCAS(ctx, conn, tokenID, a, b, 1)
+	if !errors.Is(err, erc20.ErrPaused) {
+		t.Errorf("TransferCAS while paused = %v, want %v", err, erc20.ErrPaused)
+	}
+}
+
+func TestBurnBatch(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, alice, bob := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	transfer(t, conn, tokenID, owner, alice, 100)
+	transfer(t, conn, tokenID, owner, bob, 50)
+	unchanged := map[erc20.Address]int{owner: 850, alice: 100, bob: 50}
+
+	err := erc20.BurnBatch(ctx, conn, tokenID, []erc20.BurnItem{{Account: owner, Amount: 200}, {Account: alice, Amount: 100}, {Account: bob, Amount: 51}})
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("BurnBatch with a short account error = %v, want ErrInsufficientBalance", err)
+	}
+	wantBalances(t, conn, tokenID, unchanged)
+	if got := totalSupply(t, conn, tokenID); got != 1000 {
+		t.Errorf("total supply after failed batch = %d, want 1000", got)
+	}
+
+	err = erc20.SetMinSupply(ctx, conn, tokenID, 700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.BurnBatch(ctx, conn, tokenID, []erc20.BurnItem{{Account: owner, Amount: 200}, {Account: alice, Amount: 100}, {Account: bob, Amount: 50}})
+	if !errors.Is(err, erc20.ErrBelowMinSupply) {
+		t.Errorf("BurnBatch below the supply floor error = %v, want ErrBelowMinSupply", err)
+	}
+	wantBalances(t, conn, tokenID, unchanged)
+
+	err = erc20.BurnBatch(ctx, conn, tokenID, []erc20.BurnItem{{Account: owner, Amount: 150}, {Account: alice, Amount: 100}, {Account: bob, Amount: 50}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 700, alice: 0, bob: 0})
+	if got := totalSupply(t, conn, tokenID); got != 700 {
+		t.Errorf("total supply = %d, want 700", got)
+	}
+	wantConserved(t, conn, tokenID)
+}
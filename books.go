@@ -0,0 +1,70 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrAccountBookNotFound is returned when creating a token in an account book that does not exist
+var ErrAccountBookNotFound = errors.New("ERC20: account book not found")
+
+// AccountBook groups the tokens of one tenant
+type AccountBook struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ListAccountBooks returns every account book, oldest first
+func ListAccountBooks(ctx context.Context, conn *pgxpool.Pool) ([]AccountBook, error) {
+	q := `SELECT id, created_at, updated_at FROM account_books ORDER BY created_at, id`
+	rows, err := conn.Query(ctx, q)
+	if err != nil {
+		logger(ctx).Errorw(err.Error())
+		return nil, terror.Error(err, "Could not list account books")
+	}
+	defer rows.Close()
+	books := []AccountBook{}
+	for rows.Next() {
+		var book AccountBook
+		err = rows.Scan(&book.ID, &book.CreatedAt, &book.UpdatedAt)
+		if err != nil {
+			logger(ctx).Errorw(err.Error())
+			return nil, terror.Error(err, "Could not list account books")
+		}
+		books = append(books, book)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error())
+		return nil, terror.Error(rows.Err(), "Could not list account books")
+	}
+	return books, nil
+}
+
+// FactoryInNewBook creates a fresh account book and a token administered by owner within it, in one transaction
+// The initial supply is minted to owner. A one call setup for single tenant users
+func FactoryInNewBook(ctx context.Context, conn *pgxpool.Pool, owner Address, name string, symbol string, decimals int, totalSupply int) (tokenID, accountBookID uuid.UUID, err error) {
+	err = ValidateToken(name, symbol, decimals)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	err = beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `INSERT INTO account_books DEFAULT VALUES RETURNING id`).Scan(&accountBookID)
+		if err != nil {
+			return err
+		}
+		tokenID, err = createToken(ctx, tx, accountBookID, owner, name, symbol, decimals, totalSupply)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "name", name, "symbol", symbol)
+		return uuid.Nil, uuid.Nil, terror.Error(err, "Could not create token")
+	}
+	return tokenID, accountBookID, nil
+}
@@ -0,0 +1,104 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestFactoryInNewBook(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+
+	tokenID, bookID, err := erc20.FactoryInNewBook(ctx, conn, owner, "Solo", "SOLO", 6, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokenID == uuid.Nil || bookID == uuid.Nil {
+		t.Fatalf("FactoryInNewBook = %s, %s", tokenID, bookID)
+	}
+	token, err := erc20.GetToken(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccountBookID != bookID {
+		t.Errorf("token account book = %s, want %s", token.AccountBookID, bookID)
+	}
+	books, err := erc20.ListAccountBooks(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, book := range books {
+		found = found || book.ID == bookID
+	}
+	if !found {
+		t.Errorf("ListAccountBooks = %v, missing %s", books, bookID)
+	}
+}
+
+func TestFactoryMintsInitialSupplyToOwner(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+
+	inNewBook, _, err := erc20.FactoryInNewBook(ctx, conn, owner, "Solo", "SOLO", 6, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tokenID := range map[string]uuid.UUID{
+		"Factory":          newToken(t, conn, owner, 1000),
+		"FactoryInNewBook": inNewBook,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := balanceOf(t, conn, tokenID, owner); got != 1000 {
+				t.Errorf("owner balance = %d, want 1000", got)
+			}
+			wantConserved(t, conn, tokenID)
+			replayed, err := erc20.BalanceOfAtTime(ctx, conn, tokenID, owner, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if replayed != 1000 {
+				t.Errorf("owner balance from the ledger = %d, want 1000", replayed)
+			}
+
+			err = erc20.RebuildBalances(ctx, conn, tokenID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := totalSupply(t, conn, tokenID); got != 1000 {
+				t.Errorf("total supply after rebuild = %d, want 1000", got)
+			}
+			if got := balanceOf(t, conn, tokenID, owner); got != 1000 {
+				t.Errorf("owner balance after rebuild = %d, want 1000", got)
+			}
+		})
+	}
+}
+
+func TestFactoryZeroSupplyCreatesNoHolder(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	tokenID := newToken(t, conn, newAddress(t), 0)
+
+	holders, err := erc20.HolderCount(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holders != 0 {
+		t.Errorf("HolderCount = %d, want 0", holders)
+	}
+	wantConserved(t, conn, tokenID)
+}
+
+func TestFactoryRejectsUnknownBook(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	_, err := erc20.Factory(ctx, conn, uuid.Must(uuid.NewV4()), newAddress(t), "Test", "TST", 18, 10)
+	if !errors.Is(err, erc20.ErrAccountBookNotFound) {
+		t.Errorf("Factory in unknown book = %v, want %v", err, erc20.ErrAccountBookNotFound)
+	}
+}
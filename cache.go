@@ -0,0 +1,49 @@
+package erc20
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+)
+
+// BalanceCache serves hot balances without a database round trip
+// The client only reads it outside of transactions and invalidates entries after a write commits
+type BalanceCache interface {
+	// Get returns the cached balance and whether there was one
+	Get(ctx context.Context, tokenID uuid.UUID, owner Address) (int, bool, error)
+	Set(ctx context.Context, tokenID uuid.UUID, owner Address, balance int) error
+	Invalidate(ctx context.Context, tokenID uuid.UUID, owners ...Address) error
+}
+
+// WithBalanceCache serves BalanceOf from cache and invalidates it on Transfer, TransferFrom, Mint and Burn
+func WithBalanceCache(cache BalanceCache) Option {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// invalidate drops the cached balances of owners after a committed write
+// A failure is logged rather than returned, the write itself has already succeeded
+func (c *Client) invalidate(ctx context.Context, tokenID uuid.UUID, owners ...Address) {
+	if c.cache == nil {
+		return
+	}
+	err := c.cache.Invalidate(ctx, tokenID, owners...)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "owners", owners)
+	}
+}
+
+// invalidateTransfer drops the cached balances of the parties to one or more transfers, including the token's fee collector
+func (c *Client) invalidateTransfer(ctx context.Context, tokenID uuid.UUID, owners ...Address) {
+	if c.cache == nil {
+		return
+	}
+	token, err := GetToken(ctx, c.conn, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+	} else if token.FeeCollector != nil {
+		owners = append(owners, *token.FeeCollector)
+	}
+	c.invalidate(ctx, tokenID, owners...)
+}
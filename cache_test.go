@@ -0,0 +1,106 @@
+package erc20_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+type cacheKey struct {
+	tokenID uuid.UUID
+	owner   erc20.Address
+}
+
+// fakeCache is an in-memory erc20.BalanceCache
+type fakeCache struct {
+	mu       sync.Mutex
+	balances map[cacheKey]int
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{balances: map[cacheKey]int{}}
+}
+
+func (c *fakeCache) Get(_ context.Context, tokenID uuid.UUID, owner erc20.Address) (int, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	balance, ok := c.balances[cacheKey{tokenID, owner}]
+	return balance, ok, nil
+}
+
+func (c *fakeCache) Set(_ context.Context, tokenID uuid.UUID, owner erc20.Address, balance int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.balances[cacheKey{tokenID, owner}] = balance
+	return nil
+}
+
+func (c *fakeCache) Invalidate(_ context.Context, tokenID uuid.UUID, owners ...erc20.Address) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, owner := range owners {
+		delete(c.balances, cacheKey{tokenID, owner})
+	}
+	return nil
+}
+
+func (c *fakeCache) has(tokenID uuid.UUID, owner erc20.Address) bool {
+	_, ok, _ := c.Get(ctx, tokenID, owner)
+	return ok
+}
+
+func TestBalanceCacheHit(t *testing.T) {
+	cache := newFakeCache()
+	tokenID, owner := uuid.Must(uuid.NewV4()), newAddress(t)
+	err := cache.Set(ctx, tokenID, owner, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No pool at all, a cache miss would panic
+	client := erc20.NewClient(nil, erc20.WithBalanceCache(cache))
+
+	balance, err := client.BalanceOf(ctx, tokenID, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 42 {
+		t.Errorf("BalanceOf = %d, want the cached 42", balance)
+	}
+}
+
+func TestBalanceCacheInvalidatedByTransfer(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, recipient := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	cache := newFakeCache()
+	client := erc20.NewClient(conn, erc20.WithBalanceCache(cache))
+
+	for _, addr := range []erc20.Address{owner, recipient} {
+		_, err := client.BalanceOf(ctx, tokenID, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cache.has(tokenID, addr) {
+			t.Fatal("BalanceOf did not fill the cache")
+		}
+	}
+	_, err := client.Transfer(ctx, tokenID, owner, recipient, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.has(tokenID, owner) || cache.has(tokenID, recipient) {
+		t.Error("Transfer left stale balances in the cache")
+	}
+	balance, err := client.BalanceOf(ctx, tokenID, recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 100 {
+		t.Errorf("BalanceOf after the transfer = %d, want 100", balance)
+	}
+}
@@ -0,0 +1,108 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrConcurrentModification is returned when a compare and swap update keeps losing to concurrent writers
+var ErrConcurrentModification = errors.New("ERC20: concurrent modification")
+
+// errVersionMismatch signals that a row changed between read and write
+var errVersionMismatch = errors.New("version mismatch")
+
+// TransferCAS moves balance between accounts without holding row locks across the read
+// Balances are read with their version and written only if the version is unchanged, retrying otherwise.
+// Every balance update bumps the version, so this is safe alongside the locking writers.
+// The token's fee, burn and max transfer apply as they do to Transfer
+func TransferCAS(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, sender, recipient Address, amount int) error {
+	return transferCASWith(ctx, conn, tokenID, sender, recipient, amount, transferOptions{})
+}
+
+// transferCASWith is TransferCAS running opts first
+func transferCASWith(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, sender, recipient Address, amount int, opts transferOptions) error {
+	if amount < 0 {
+		return terror.Error(ErrInvalidAmount, "Amount can not be negative")
+	}
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := opts.check(ctx, tx, tokenID, sender, recipient, amount)
+		if err != nil {
+			return err
+		}
+		return transferCAS(ctx, tx, tokenID, sender, recipient, amount, opts.rounding)
+	})
+	if errors.Is(err, errVersionMismatch) {
+		err = ErrConcurrentModification
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "sender", sender, "recipient", recipient, "amount", amount)
+		return terror.Error(err, "Could not update balances")
+	}
+	return nil
+}
+
+// transferCAS is transfer with the balances written by compare and swap instead of under row locks
+func transferCAS(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, sender, recipient Address, amount int, rounding RoundingMode) error {
+	plan, err := planTransfer(ctx, tx, tokenID, amount, rounding)
+	if err != nil {
+		return err
+	}
+	ensureQ := `INSERT INTO addresses (token_id, owner, balance) VALUES ($1, $2, 0) ON CONFLICT (token_id, owner) DO NOTHING`
+	_, err = tx.Exec(ctx, ensureQ, tokenID, recipient)
+	if err != nil {
+		return err
+	}
+	readQ := `SELECT balance, version FROM addresses WHERE token_id = $1 AND owner = $2`
+	var senderBalance int
+	var senderVersion int64
+	err = tx.QueryRow(ctx, readQ, tokenID, sender).Scan(&senderBalance, &senderVersion)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if senderBalance < amount {
+		return ErrInsufficientBalance
+	}
+	if sender == recipient {
+		err = casAdd(ctx, tx, tokenID, sender, plan.net-amount, senderVersion)
+		if err != nil {
+			return err
+		}
+		return plan.settle(ctx, tx, tokenID, sender, recipient, &TransferResult{})
+	}
+	var recipientVersion int64
+	err = tx.QueryRow(ctx, readQ, tokenID, recipient).Scan(new(int), &recipientVersion)
+	if err != nil {
+		return err
+	}
+	err = casAdd(ctx, tx, tokenID, sender, -amount, senderVersion)
+	if err != nil {
+		return err
+	}
+	err = casAdd(ctx, tx, tokenID, recipient, plan.net, recipientVersion)
+	if err != nil {
+		return err
+	}
+	return plan.settle(ctx, tx, tokenID, sender, recipient, &TransferResult{})
+}
+
+// casAdd adds delta to the owner's balance inside tx if the row is still at version
+// Returns errVersionMismatch if it has moved on. A zero delta writes nothing
+func casAdd(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, owner Address, delta int, version int64) error {
+	if delta == 0 {
+		return nil
+	}
+	q := `UPDATE addresses SET balance = balance + $1, updated_at = now() WHERE token_id = $2 AND owner = $3 AND version = $4`
+	tag, err := tx.Exec(ctx, q, delta, tokenID, owner, version)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errVersionMismatch
+	}
+	return nil
+}
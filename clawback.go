@@ -0,0 +1,57 @@
+package erc20
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ForceTransfer moves balance out of an address without its consent, for regulated tokens
+// Sufficient balance is still enforced, a paused or deleted token is refused, and the move is recorded as a clawback event
+// Authorization is the caller's responsibility, nothing here checks who is asking
+func ForceTransfer(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, from, to Address, amount int) (bool, error) {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := activeToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		_, err = debit(ctx, tx, tokenID, from, amount)
+		if err != nil {
+			return err
+		}
+		_, err = credit(ctx, tx, tokenID, to, amount)
+		if err != nil {
+			return err
+		}
+		_, err = recordEvent(ctx, tx, tokenID, EventClawback, from, to, amount)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "from", from, "to", to, "amount", amount)
+		return false, terror.Error(err, "Could not force transfer")
+	}
+	return true, nil
+}
+
+// AdminBurn destroys amount of any account's balance, reducing the total supply
+// caller must own the token or hold RoleBurner. Recorded as an admin burn event
+func AdminBurn(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount int, caller Address) error {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := requireRole(ctx, tx, tokenID, caller, RoleBurner)
+		if err != nil {
+			return err
+		}
+		return burnAs(ctx, tx, tokenID, account, amount, EventAdminBurn)
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "account", account, "amount", amount, "caller", caller)
+		return terror.Error(err, "Could not burn")
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestForceTransfer(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, sanctioned, treasury := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+	transfer(t, conn, tokenID, owner, sanctioned, 60)
+
+	ok, err := erc20.ForceTransfer(ctx, conn, tokenID, sanctioned, treasury, 60)
+	if err != nil || !ok {
+		t.Fatalf("ForceTransfer = %v, %v", ok, err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{sanctioned: 0, treasury: 60})
+	wantConserved(t, conn, tokenID)
+
+	events, err := erc20.TransfersForAddress(ctx, conn, tokenID, treasury, time.Time{}, time.Time{}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != erc20.EventClawback || events[0].From != sanctioned || events[0].Amount != 60 {
+		t.Errorf("events = %+v, want a single clawback of 60 from the sanctioned address", events)
+	}
+
+	_, err = erc20.ForceTransfer(ctx, conn, tokenID, sanctioned, treasury, 1)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("ForceTransfer overdraw = %v, want %v", err, erc20.ErrInsufficientBalance)
+	}
+}
+
+func TestForceTransferInactiveToken(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, treasury := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+
+	err := erc20.Pause(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.ForceTransfer(ctx, conn, tokenID, owner, treasury, 10)
+	if !errors.Is(err, erc20.ErrPaused) {
+		t.Errorf("ForceTransfer while paused = %v, want %v", err, erc20.ErrPaused)
+	}
+	err = erc20.Unpause(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.DeleteToken(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.ForceTransfer(ctx, conn, tokenID, owner, treasury, 10)
+	if !errors.Is(err, erc20.ErrTokenNotFound) {
+		t.Errorf("ForceTransfer on a deleted token = %v, want %v", err, erc20.ErrTokenNotFound)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 100, treasury: 0})
+}
+
+func TestAdminBurn(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, holder, burner := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	transfer(t, conn, tokenID, owner, holder, 300)
+
+	err := erc20.AdminBurn(ctx, conn, tokenID, holder, 100, burner)
+	if !errors.Is(err, erc20.ErrUnauthorized) {
+		t.Errorf("AdminBurn without the role error = %v, want ErrUnauthorized", err)
+	}
+	err = erc20.AdminBurn(ctx, conn, tokenID, holder, 100, owner)
+	if err != nil {
+		t.Fatalf("AdminBurn by the owner: %v", err)
+	}
+	err = erc20.GrantRole(ctx, conn, tokenID, owner, burner, erc20.RoleBurner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.AdminBurn(ctx, conn, tokenID, holder, 50, burner)
+	if err != nil {
+		t.Fatalf("AdminBurn with the role: %v", err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 700, holder: 150})
+	if got := totalSupply(t, conn, tokenID); got != 850 {
+		t.Errorf("total supply = %d, want 850", got)
+	}
+
+	err = erc20.AdminBurn(ctx, conn, tokenID, holder, 151, burner)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("AdminBurn over balance error = %v, want ErrInsufficientBalance", err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{holder: 150})
+	if got := totalSupply(t, conn, tokenID); got != 850 {
+		t.Errorf("total supply after rejected burn = %d, want 850", got)
+	}
+	wantConserved(t, conn, tokenID)
+
+	var adminBurns int
+	err = conn.QueryRow(ctx, `SELECT count(*) FROM ledger_events WHERE token_id = $1 AND event_type = $2 AND sender = $3`, tokenID, string(erc20.EventAdminBurn), holder).Scan(&adminBurns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adminBurns != 2 {
+		t.Errorf("admin burn events = %d, want 2", adminBurns)
+	}
+}
@@ -0,0 +1,641 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"erc20/metrics"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DB is the set of ledger operations shared by every backend
+// Client implements it on postgres, memstore implements it in memory
+type DB interface {
+	Factory(ctx context.Context, accountBookID uuid.UUID, owner Address, name string, symbol string, decimals int, totalSupply int) (uuid.UUID, error)
+	TokenIDBySymbol(ctx context.Context, symbol string) (uuid.UUID, error)
+	TotalSupply(ctx context.Context, tokenID uuid.UUID) (Amount, error)
+	BalanceOf(ctx context.Context, tokenID uuid.UUID, owner Address) (Amount, error)
+	Transfer(ctx context.Context, tokenID uuid.UUID, sender Address, recipient Address, amount Amount) (bool, error)
+	TransferMany(ctx context.Context, tokenID uuid.UUID, legs []TransferLeg) error
+	Approve(ctx context.Context, tokenID uuid.UUID, owner, spender Address, amount int) error
+	Allowance(ctx context.Context, tokenID uuid.UUID, owner, spender Address) (int, error)
+	TransferFrom(ctx context.Context, tokenID uuid.UUID, spender, sender, recipient Address, amount int) (bool, error)
+	Mint(ctx context.Context, tokenID uuid.UUID, account Address, amount Amount) error
+	Burn(ctx context.Context, tokenID uuid.UUID, account Address, amount Amount) error
+	Pause(ctx context.Context, tokenID uuid.UUID) error
+	Unpause(ctx context.Context, tokenID uuid.UUID) error
+	DeleteToken(ctx context.Context, tokenID uuid.UUID) error
+}
+
+var _ DB = (*Client)(nil)
+
+// ErrClientClosed is returned by operations started after Close
+var ErrClientClosed = errors.New("ERC20: client closed")
+
+// DefaultTimeout bounds each client operation when the caller's context has no deadline
+const DefaultTimeout = 5 * time.Second
+
+// Client wraps a connection pool and instruments the ledger operations run through it
+type Client struct {
+	conn       *pgxpool.Pool
+	readConn   *pgxpool.Pool
+	metrics    *metrics.Metrics
+	timeout    time.Duration
+	tracer     trace.Tracer
+	cache      BalanceCache
+	caller     *Address
+	hook       TransferHook
+	autoCreate bool
+	rounding   RoundingMode
+
+	mu       sync.Mutex
+	closed   bool
+	inFlight sync.WaitGroup
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithMetrics records every operation on the given metrics
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+// WithReadPool sends read only queries to a replica pool
+// Writes and anything inside a transaction always go to the primary
+func WithReadPool(pool *pgxpool.Pool) Option {
+	return func(c *Client) {
+		c.readConn = pool
+	}
+}
+
+// WithCaller runs administrative operations as caller
+// Mint requires RoleMinter and Pause and Unpause require RolePauser, unless caller owns the token
+func WithCaller(caller Address) Option {
+	return func(c *Client) {
+		c.caller = &caller
+	}
+}
+
+// TransferHook approves or rejects a transfer before it is applied, for compliance checks
+// A non-nil error aborts the transfer and is returned to the caller. The hook runs inside the
+// transfer's transaction and may run again if the transaction is retried
+type TransferHook func(ctx context.Context, tokenID uuid.UUID, from, to Address, amount int) error
+
+// WithTransferHook runs hook before every Transfer and TransferFrom
+func WithTransferHook(hook TransferHook) Option {
+	return func(c *Client) {
+		c.hook = hook
+	}
+}
+
+// WithAutoCreateAddresses sets whether operations create addresses they have not seen before
+// When false, BalanceOf, Transfer and TransferFrom on a missing address fail with ErrAddressNotFound.
+// Mint still creates the recipient. Defaults to true
+func WithAutoCreateAddresses(autoCreate bool) Option {
+	return func(c *Client) {
+		c.autoCreate = autoCreate
+	}
+}
+
+// WithRounding sets how the client rounds transfer fees and burns, swap outputs and reward shares
+// Defaults to RoundFloor, see RoundingMode for how each mode affects the totals
+func WithRounding(mode RoundingMode) Option {
+	return func(c *Client) {
+		c.rounding = mode
+	}
+}
+
+// WithDefaultTimeout bounds each operation by d when the caller's context has no deadline
+// A caller supplied deadline is never overridden. Zero disables the timeout
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithTracerProvider traces every operation with tp instead of the global provider
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *Client) {
+		c.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// tracerName is the instrumentation name spans are recorded under
+const tracerName = "erc20"
+
+// NewClient creates a client on top of an existing pool
+// The caller still owns the pool lifecycle
+func NewClient(conn *pgxpool.Pool, opts ...Option) *Client {
+	c := &Client{
+		conn:       conn,
+		timeout:    DefaultTimeout,
+		autoCreate: true,
+		tracer:     otel.GetTracerProvider().Tracer(tracerName),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// reader returns the pool read only queries should use
+// The replica when one is configured, otherwise the primary
+func (c *Client) reader() *pgxpool.Pool {
+	if c.readConn != nil {
+		return c.readConn
+	}
+	return c.conn
+}
+
+// transferOptions returns the checks configured for transfers run through the client
+func (c *Client) transferOptions() transferOptions {
+	return transferOptions{hook: c.hook, strict: !c.autoCreate, rounding: c.rounding}
+}
+
+// begin tracks an operation as in flight and applies the default timeout to ctx
+// unless it already has a deadline. The returned func must be called when the operation ends
+func (c *Client) begin(ctx context.Context) (context.Context, func(), error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, nil, ErrClientClosed
+	}
+	c.inFlight.Add(1)
+	if _, ok := ctx.Deadline(); ok || c.timeout <= 0 {
+		return ctx, c.inFlight.Done, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	return ctx, func() {
+		cancel()
+		c.inFlight.Done()
+	}, nil
+}
+
+// Close stops the client accepting new operations and waits for in-flight ones to finish
+// Returns the context error if the deadline hits first. The pool is left open, the caller owns it
+func (c *Client) Close(ctx context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		c.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// observe logs the outcome of an operation and records it if metrics are enabled
+func (c *Client) observe(ctx context.Context, op string, tokenID uuid.UUID, amount int, started time.Time, err error) {
+	logOperation(ctx, op, tokenID, amount, started, err)
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.Observe(op, started, errorType(err))
+}
+
+// startSpan opens an erc20.<op> span for an operation on tokenID
+func (c *Client) startSpan(ctx context.Context, op string, tokenID uuid.UUID, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("token.id", tokenID.String()))
+	return c.tracer.Start(ctx, "erc20."+op, trace.WithAttributes(attrs...))
+}
+
+// legsAmount sums the amounts moved by a batch of legs, for logs, metrics and spans
+func legsAmount(legs []TransferLeg) int {
+	total := 0
+	for _, leg := range legs {
+		total += leg.Amount
+	}
+	return total
+}
+
+// endSpan records the outcome of an operation on its span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("outcome", errorType(err)))
+	} else {
+		span.SetAttributes(attribute.String("outcome", "success"))
+	}
+	span.End()
+}
+
+// errorType buckets an error into a low cardinality label
+func errorType(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrInsufficientBalance):
+		return "insufficient_balance"
+	case errors.Is(err, ErrInsufficientAllowance):
+		return "insufficient_allowance"
+	case errors.Is(err, ErrTokenNotFound):
+		return "token_not_found"
+	case errors.Is(err, ErrNotOwner):
+		return "not_owner"
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrPaused):
+		return "paused"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	default:
+		return "error"
+	}
+}
+
+// Factory creates a new token administered by owner in an account book and returns its ID
+func (c *Client) Factory(ctx context.Context, accountBookID uuid.UUID, owner Address, name string, symbol string, decimals int, totalSupply int) (uuid.UUID, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer done()
+	ctx, span := c.tracer.Start(ctx, "erc20.Factory", trace.WithAttributes(attribute.String("symbol", symbol), attribute.Int("amount", totalSupply)))
+	started := time.Now()
+	tokenID, err := Factory(ctx, c.conn, accountBookID, owner, name, symbol, decimals, totalSupply)
+	if err == nil {
+		span.SetAttributes(attribute.String("token.id", tokenID.String()))
+	}
+	c.observe(ctx, "factory", tokenID, totalSupply, started, err)
+	endSpan(span, err)
+	return tokenID, err
+}
+
+// TokenIDBySymbol retrieves the token ID given its unique symbol
+func (c *Client) TokenIDBySymbol(ctx context.Context, symbol string) (uuid.UUID, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer done()
+	return TokenIDBySymbol(ctx, c.reader(), symbol)
+}
+
+// TotalSupply of the token
+func (c *Client) TotalSupply(ctx context.Context, tokenID uuid.UUID) (Amount, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+	return TotalSupply(ctx, c.reader(), tokenID)
+}
+
+// BalanceOf an address
+func (c *Client) BalanceOf(ctx context.Context, tokenID uuid.UUID, owner Address) (Amount, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+	if c.cache != nil {
+		balance, ok, err := c.cache.Get(ctx, tokenID, owner)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "id", tokenID, "owner", owner)
+		}
+		if ok {
+			return Amount(balance), nil
+		}
+	}
+	var balance int
+	switch {
+	case !c.autoCreate:
+		balance, err = strictBalanceOf(ctx, c.reader(), tokenID, owner)
+	case c.readConn != nil:
+		balance, err = balanceOf(ctx, c.readConn, tokenID, owner)
+	default:
+		balance, err = BalanceOfInt(ctx, c.conn, tokenID, owner)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if c.cache != nil {
+		err = c.cache.Set(ctx, tokenID, owner, balance)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "id", tokenID, "owner", owner)
+		}
+	}
+	return Amount(balance), nil
+}
+
+// GetToken retrieves a token by ID
+func (c *Client) GetToken(ctx context.Context, tokenID uuid.UUID) (*Token, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "GetToken", tokenID)
+	token, err := GetToken(ctx, c.reader(), tokenID)
+	endSpan(span, err)
+	return token, err
+}
+
+// ListTokens returns every token in an account book, ordered by symbol
+func (c *Client) ListTokens(ctx context.Context, accountBookID uuid.UUID) ([]Token, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return ListTokens(ctx, c.reader(), accountBookID)
+}
+
+// Transfer moves balance between accounts
+func (c *Client) Transfer(ctx context.Context, tokenID uuid.UUID, sender Address, recipient Address, amount Amount) (bool, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "Transfer", tokenID, attribute.Int("amount", amount.Int()))
+	started := time.Now()
+	_, err = transferWith(ctx, c.conn, tokenID, sender, recipient, amount.Int(), c.transferOptions())
+	ok := err == nil
+	c.observe(ctx, "transfer", tokenID, amount.Int(), started, err)
+	endSpan(span, err)
+	if err == nil {
+		c.invalidateTransfer(ctx, tokenID, sender, recipient)
+	}
+	return ok, err
+}
+
+// TransferMany applies every leg in one transaction, all or nothing
+// The client's hook and strict mode check every leg
+func (c *Client) TransferMany(ctx context.Context, tokenID uuid.UUID, legs []TransferLeg) error {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	amount := legsAmount(legs)
+	ctx, span := c.startSpan(ctx, "TransferMany", tokenID, attribute.Int("amount", amount), attribute.Int("legs", len(legs)))
+	started := time.Now()
+	err = transferManyWith(ctx, c.conn, tokenID, legs, c.transferOptions())
+	c.observe(ctx, "transfer_many", tokenID, amount, started, err)
+	endSpan(span, err)
+	if err == nil {
+		owners := make([]Address, 0, 2*len(legs))
+		for _, leg := range legs {
+			owners = append(owners, leg.From, leg.To)
+		}
+		c.invalidateTransfer(ctx, tokenID, owners...)
+	}
+	return err
+}
+
+// TransferBestEffort applies each leg in its own transaction, see TransferBestEffort
+// The client's hook and strict mode check every leg, a leg they reject is reported in its LegResult
+func (c *Client) TransferBestEffort(ctx context.Context, tokenID uuid.UUID, legs []TransferLeg) ([]LegResult, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	amount := legsAmount(legs)
+	ctx, span := c.startSpan(ctx, "TransferBestEffort", tokenID, attribute.Int("amount", amount), attribute.Int("legs", len(legs)))
+	started := time.Now()
+	results, err := transferBestEffortWith(ctx, c.conn, tokenID, legs, c.transferOptions())
+	c.observe(ctx, "transfer_best_effort", tokenID, amount, started, err)
+	endSpan(span, err)
+	owners := make([]Address, 0, 2*len(results))
+	for _, result := range results {
+		if result.Err == nil {
+			owners = append(owners, result.Leg.From, result.Leg.To)
+		}
+	}
+	if len(owners) > 0 {
+		c.invalidateTransfer(ctx, tokenID, owners...)
+	}
+	return results, err
+}
+
+// TransferCAS moves balance between accounts by compare and swap, see TransferCAS
+func (c *Client) TransferCAS(ctx context.Context, tokenID uuid.UUID, sender, recipient Address, amount int) error {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "TransferCAS", tokenID, attribute.Int("amount", amount))
+	started := time.Now()
+	err = transferCASWith(ctx, c.conn, tokenID, sender, recipient, amount, c.transferOptions())
+	c.observe(ctx, "transfer_cas", tokenID, amount, started, err)
+	endSpan(span, err)
+	if err == nil {
+		c.invalidateTransfer(ctx, tokenID, sender, recipient)
+	}
+	return err
+}
+
+// Approve lets spender move up to amount of the owner's balance
+func (c *Client) Approve(ctx context.Context, tokenID uuid.UUID, owner, spender Address, amount int) error {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "Approve", tokenID, attribute.Int("amount", amount))
+	started := time.Now()
+	err = Approve(ctx, c.conn, tokenID, owner, spender, amount)
+	c.observe(ctx, "approve", tokenID, amount, started, err)
+	endSpan(span, err)
+	return err
+}
+
+// Allowance returns how much spender may still move of the owner's balance
+func (c *Client) Allowance(ctx context.Context, tokenID uuid.UUID, owner, spender Address) (int, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+	return Allowance(ctx, c.reader(), tokenID, owner, spender)
+}
+
+// TransferFrom moves balance from sender to recipient on behalf of spender
+func (c *Client) TransferFrom(ctx context.Context, tokenID uuid.UUID, spender, sender, recipient Address, amount int) (bool, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "TransferFrom", tokenID, attribute.Int("amount", amount))
+	started := time.Now()
+	ok, err := transferFrom(ctx, c.conn, tokenID, spender, sender, recipient, amount, c.transferOptions())
+	c.observe(ctx, "transfer_from", tokenID, amount, started, err)
+	endSpan(span, err)
+	if err == nil {
+		c.invalidateTransfer(ctx, tokenID, sender, recipient)
+	}
+	return ok, err
+}
+
+// Mint new tokens to an address
+func (c *Client) Mint(ctx context.Context, tokenID uuid.UUID, account Address, amount Amount) error {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "Mint", tokenID, attribute.Int("amount", amount.Int()))
+	started := time.Now()
+	if c.caller != nil {
+		err = MintAs(ctx, c.conn, tokenID, *c.caller, account, amount.Int())
+	} else {
+		err = Mint(ctx, c.conn, tokenID, account, amount)
+	}
+	c.observe(ctx, "mint", tokenID, amount.Int(), started, err)
+	endSpan(span, err)
+	if err == nil {
+		c.invalidate(ctx, tokenID, account)
+	}
+	return err
+}
+
+// Burn existing tokens from an address
+func (c *Client) Burn(ctx context.Context, tokenID uuid.UUID, account Address, amount Amount) error {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "Burn", tokenID, attribute.Int("amount", amount.Int()))
+	started := time.Now()
+	err = Burn(ctx, c.conn, tokenID, account, amount)
+	c.observe(ctx, "burn", tokenID, amount.Int(), started, err)
+	endSpan(span, err)
+	if err == nil {
+		c.invalidate(ctx, tokenID, account)
+	}
+	return err
+}
+
+// BurnFrom burns amount of the owner's balance on behalf of spender, reducing the spender's allowance
+func (c *Client) BurnFrom(ctx context.Context, tokenID uuid.UUID, spender, owner Address, amount int) error {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "BurnFrom", tokenID, attribute.Int("amount", amount))
+	started := time.Now()
+	err = BurnFrom(ctx, c.conn, tokenID, spender, owner, amount)
+	c.observe(ctx, "burn_from", tokenID, amount, started, err)
+	endSpan(span, err)
+	if err == nil {
+		c.invalidate(ctx, tokenID, owner)
+	}
+	return err
+}
+
+// Swap exchanges amountIn of fromToken for toToken at a fixed rate, the output rounded by the client's rounding mode
+func (c *Client) Swap(ctx context.Context, fromToken, toToken uuid.UUID, account Address, amountIn int, rateNumerator, rateDenominator int) (int, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "Swap", fromToken, attribute.Int("amount", amountIn), attribute.String("to_token.id", toToken.String()))
+	started := time.Now()
+	amountOut, err := swap(ctx, c.conn, fromToken, toToken, account, amountIn, rateNumerator, rateDenominator, c.rounding)
+	c.observe(ctx, "swap", fromToken, amountIn, started, err)
+	endSpan(span, err)
+	if err == nil {
+		c.invalidate(ctx, fromToken, account)
+		c.invalidate(ctx, toToken, account)
+	}
+	return amountOut, err
+}
+
+// DistributeRewards mints totalReward to the current holders in proportion to their balances,
+// the shares rounded by the client's rounding mode
+func (c *Client) DistributeRewards(ctx context.Context, tokenID uuid.UUID, totalReward int) error {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "DistributeRewards", tokenID, attribute.Int("amount", totalReward))
+	started := time.Now()
+	credited, err := distributeRewards(ctx, c.conn, tokenID, totalReward, c.rounding)
+	c.observe(ctx, "distribute_rewards", tokenID, totalReward, started, err)
+	endSpan(span, err)
+	if err == nil {
+		c.invalidate(ctx, tokenID, credited...)
+	}
+	return err
+}
+
+// DeleteToken retires a token, keeping its history
+func (c *Client) DeleteToken(ctx context.Context, tokenID uuid.UUID) error {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "DeleteToken", tokenID)
+	started := time.Now()
+	err = DeleteToken(ctx, c.conn, tokenID)
+	c.observe(ctx, "delete_token", tokenID, 0, started, err)
+	endSpan(span, err)
+	return err
+}
+
+// Pause stops every balance change of a token until Unpause
+func (c *Client) Pause(ctx context.Context, tokenID uuid.UUID) error {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "Pause", tokenID)
+	started := time.Now()
+	if c.caller != nil {
+		err = PauseAs(ctx, c.conn, tokenID, *c.caller)
+	} else {
+		err = Pause(ctx, c.conn, tokenID)
+	}
+	c.observe(ctx, "pause", tokenID, 0, started, err)
+	endSpan(span, err)
+	return err
+}
+
+// Unpause lets balances of a paused token change again
+func (c *Client) Unpause(ctx context.Context, tokenID uuid.UUID) error {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ctx, span := c.startSpan(ctx, "Unpause", tokenID)
+	started := time.Now()
+	if c.caller != nil {
+		err = UnpauseAs(ctx, c.conn, tokenID, *c.caller)
+	} else {
+		err = Unpause(ctx, c.conn, tokenID)
+	}
+	c.observe(ctx, "unpause", tokenID, 0, started, err)
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,342 @@
+package erc20_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+	"erc20/metrics"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestClientMetrics(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	m := metrics.New()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m.Collector())
+	client := erc20.NewClient(conn, erc20.WithMetrics(m))
+
+	_, err := client.Transfer(ctx, tokenID, owner, newAddress(t), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Transfer(ctx, tokenID, newAddress(t), owner, 100)
+	if err == nil {
+		t.Fatal("Transfer from an empty address succeeded")
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "op" && label.GetValue() == "transfer" {
+					counts[family.GetName()] += metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	if counts["erc20_operations_total"] != 1 {
+		t.Errorf("erc20_operations_total{op=transfer} = %v, want 1", counts["erc20_operations_total"])
+	}
+	if counts["erc20_operation_failures_total"] != 1 {
+		t.Errorf("erc20_operation_failures_total{op=transfer} = %v, want 1", counts["erc20_operation_failures_total"])
+	}
+}
+
+// clientWrites runs every write the client offers once against fresh tokens, each expected to succeed
+// It returns the metric op label of every write it ran
+func clientWrites(t *testing.T, conn *pgxpool.Pool, client *erc20.Client) []string {
+	t.Helper()
+	owner, spender, other := newAddress(t), newAddress(t), newAddress(t)
+	tokenID, err := client.Factory(ctx, erc20test.NewAccountBook(t, conn), owner, "Writes", "WRT", 18, 1000)
+	if err != nil {
+		t.Fatalf("Factory: %v", err)
+	}
+	toToken := newToken(t, conn, newAddress(t), 0)
+	steps := []struct {
+		op  string
+		run func() error
+	}{
+		{"transfer", func() error { _, err := client.Transfer(ctx, tokenID, owner, other, 100); return err }},
+		{"transfer_many", func() error {
+			return client.TransferMany(ctx, tokenID, []erc20.TransferLeg{{From: owner, To: other, Amount: 10}, {From: other, To: owner, Amount: 5}})
+		}},
+		{"transfer_best_effort", func() error {
+			_, err := client.TransferBestEffort(ctx, tokenID, []erc20.TransferLeg{{From: owner, To: other, Amount: 10}})
+			return err
+		}},
+		{"transfer_cas", func() error { return client.TransferCAS(ctx, tokenID, owner, other, 10) }},
+		{"approve", func() error { return client.Approve(ctx, tokenID, owner, spender, 100) }},
+		{"transfer_from", func() error { _, err := client.TransferFrom(ctx, tokenID, spender, owner, other, 10); return err }},
+		{"burn_from", func() error { return client.BurnFrom(ctx, tokenID, spender, owner, 10) }},
+		{"mint", func() error { return client.Mint(ctx, tokenID, owner, 50) }},
+		{"burn", func() error { return client.Burn(ctx, tokenID, owner, 50) }},
+		{"swap", func() error { _, err := client.Swap(ctx, tokenID, toToken, owner, 10, 1, 1); return err }},
+		{"distribute_rewards", func() error { return client.DistributeRewards(ctx, tokenID, 100) }},
+		{"pause", func() error { return client.Pause(ctx, tokenID) }},
+		{"unpause", func() error { return client.Unpause(ctx, tokenID) }},
+		{"delete_token", func() error { return client.DeleteToken(ctx, tokenID) }},
+	}
+	ops := []string{"factory"}
+	for _, step := range steps {
+		err := step.run()
+		if err != nil {
+			t.Fatalf("%s: %v", step.op, err)
+		}
+		ops = append(ops, step.op)
+	}
+	return ops
+}
+
+func TestClientMetricsCoverEveryWrite(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	m := metrics.New()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m.Collector())
+	client := erc20.NewClient(conn, erc20.WithMetrics(m))
+
+	ops := clientWrites(t, conn, client)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != "erc20_operations_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "op" {
+					counts[label.GetValue()] += metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	for _, op := range ops {
+		if counts[op] != 1 {
+			t.Errorf("erc20_operations_total{op=%s} = %v, want 1", op, counts[op])
+		}
+	}
+}
+
+func TestClientDefaultTimeout(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	client := erc20.NewClient(conn, erc20.WithDefaultTimeout(50*time.Millisecond))
+
+	opCtx, done, err := client.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := time.Now()
+	_, err = conn.Exec(opCtx, `SELECT pg_sleep(5)`)
+	done()
+	if err == nil {
+		t.Fatal("pg_sleep finished without the default timeout firing")
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("timeout fired after %v, want about 50ms", elapsed)
+	}
+
+	deadline := time.Now().Add(time.Hour)
+	callerCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	opCtx, done, err = client.Begin(callerCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+	got, ok := opCtx.Deadline()
+	if !ok || !got.Equal(deadline) {
+		t.Errorf("operation deadline = %v, want the caller's %v", got, deadline)
+	}
+}
+
+func TestClientCloseWaitsForOperations(t *testing.T) {
+	client := erc20.NewClient(nil)
+	_, done, err := client.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- client.Close(ctx)
+	}()
+	select {
+	case err := <-closed:
+		t.Fatalf("Close returned %v while an operation was in flight", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_, _, err = client.Begin(ctx)
+	if !errors.Is(err, erc20.ErrClientClosed) {
+		t.Errorf("Begin after Close error = %v, want ErrClientClosed", err)
+	}
+	done()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return once the operation finished")
+	}
+}
+
+func TestClientCloseDeadline(t *testing.T) {
+	client := erc20.NewClient(nil)
+	_, done, err := client.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+
+	closeCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	err = client.Close(closeCtx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close error = %v, want the deadline", err)
+	}
+}
+
+func TestClientReadPool(t *testing.T) {
+	primary := erc20test.NewTestDB(t)
+	replica := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, primary, owner, 1000)
+	client := erc20.NewClient(primary, erc20.WithReadPool(replica))
+
+	// The token only exists on the primary, so a read that reaches it would find the token
+	_, err := client.TotalSupply(ctx, tokenID)
+	if !errors.Is(err, erc20.ErrTokenNotFound) {
+		t.Errorf("TotalSupply error = %v, want ErrTokenNotFound from the replica", err)
+	}
+	balance, err := client.BalanceOf(ctx, tokenID, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 0 {
+		t.Errorf("BalanceOf = %d, want 0 from the replica", balance)
+	}
+
+	err = client.Mint(ctx, tokenID, owner, 50)
+	if err != nil {
+		t.Fatalf("Mint on the primary: %v", err)
+	}
+	if got := totalSupply(t, primary, tokenID); got != 1050 {
+		t.Errorf("primary total supply = %d, want 1050", got)
+	}
+
+	supply, err := erc20.NewClient(primary).TotalSupply(ctx, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if supply != 1050 {
+		t.Errorf("TotalSupply without a read pool = %d, want 1050 from the primary", supply)
+	}
+}
+
+func TestClientTransferHook(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, blocked, allowed := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	errBlocked := errors.New("recipient failed compliance")
+	client := erc20.NewClient(conn, erc20.WithTransferHook(func(ctx context.Context, tokenID uuid.UUID, from, to erc20.Address, amount int) error {
+		if to == blocked {
+			return errBlocked
+		}
+		return nil
+	}))
+
+	_, err := client.Transfer(ctx, tokenID, owner, blocked, 100)
+	if !errors.Is(err, errBlocked) {
+		t.Errorf("Transfer to a blocked recipient error = %v, want the hook's error", err)
+	}
+	err = erc20.Approve(ctx, conn, tokenID, owner, allowed, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.TransferFrom(ctx, tokenID, allowed, owner, blocked, 100)
+	if !errors.Is(err, errBlocked) {
+		t.Errorf("TransferFrom to a blocked recipient error = %v, want the hook's error", err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 1000, blocked: 0})
+	if got := allowance(t, conn, tokenID, owner, allowed); got != 100 {
+		t.Errorf("allowance = %d, want 100 untouched", got)
+	}
+
+	_, err = client.Transfer(ctx, tokenID, owner, allowed, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 900, allowed: 100})
+}
+
+func TestClientAutoCreateAddresses(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	exists := func(account erc20.Address) bool {
+		var exists bool
+		err := conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM addresses WHERE token_id = $1 AND owner = $2)`, tokenID, account).Scan(&exists)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return exists
+	}
+
+	t.Run("default creates", func(t *testing.T) {
+		client := erc20.NewClient(conn)
+		unseen, recipient := newAddress(t), newAddress(t)
+		balance, err := client.BalanceOf(ctx, tokenID, unseen)
+		if err != nil || balance != 0 {
+			t.Errorf("BalanceOf unseen address = %d, %v, want 0", balance, err)
+		}
+		if !exists(unseen) {
+			t.Error("BalanceOf did not create the address")
+		}
+		_, err = client.Transfer(ctx, tokenID, owner, recipient, 10)
+		if err != nil {
+			t.Errorf("Transfer to an unseen recipient: %v", err)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		client := erc20.NewClient(conn, erc20.WithAutoCreateAddresses(false))
+		unseen, known := newAddress(t), newAddress(t)
+		transfer(t, conn, tokenID, owner, known, 10)
+		_, err := client.BalanceOf(ctx, tokenID, unseen)
+		if !errors.Is(err, erc20.ErrAddressNotFound) {
+			t.Errorf("BalanceOf unseen address error = %v, want ErrAddressNotFound", err)
+		}
+		_, err = client.Transfer(ctx, tokenID, owner, unseen, 10)
+		if !errors.Is(err, erc20.ErrAddressNotFound) {
+			t.Errorf("Transfer to an unseen recipient error = %v, want ErrAddressNotFound", err)
+		}
+		if exists(unseen) {
+			t.Error("strict client created the address")
+		}
+		_, err = client.Transfer(ctx, tokenID, owner, known, 10)
+		if err != nil {
+			t.Errorf("Transfer to a known recipient: %v", err)
+		}
+		balance, err := client.BalanceOf(ctx, tokenID, known)
+		if err != nil || balance != 20 {
+			t.Errorf("BalanceOf known address = %d, %v, want 20", balance, err)
+		}
+	})
+}
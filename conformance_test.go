@@ -0,0 +1,22 @@
+//go:build integration
+// +build integration
+
+package erc20_test
+
+import (
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+// TestConformance runs the backend conformance suite against postgres
+// Run with -tags integration and ERC20_TEST_DATABASE_URL set
+func TestConformance(t *testing.T) {
+	erc20test.RunConformance(t, func(t *testing.T) (erc20.DB, uuid.UUID) {
+		conn := erc20test.NewTestDB(t)
+		return erc20.NewClient(conn), erc20test.NewAccountBook(t, conn)
+	})
+}
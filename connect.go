@@ -0,0 +1,135 @@
+package erc20
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgconn/stmtcache"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// Connection pool defaults applied by Connect unless the DSN or an option sets them
+const (
+	DefaultMaxConns          = 10
+	DefaultHealthCheckPeriod = 30 * time.Second
+	DefaultStatementTimeout  = 30 * time.Second
+	// DefaultPreparedStatements is the size of pgx's own per connection statement cache, which Connect leaves as is
+	DefaultPreparedStatements = 512
+)
+
+// ConnectOption configures the pool built by Connect
+type ConnectOption func(*pgxpool.Config)
+
+// WithMaxConns caps the number of open connections
+func WithMaxConns(n int32) ConnectOption {
+	return func(cfg *pgxpool.Config) {
+		cfg.MaxConns = n
+	}
+}
+
+// WithHealthCheckPeriod sets how often idle connections are checked
+func WithHealthCheckPeriod(d time.Duration) ConnectOption {
+	return func(cfg *pgxpool.Config) {
+		cfg.HealthCheckPeriod = d
+	}
+}
+
+// WithStatementTimeout makes the server cancel any statement running longer than d
+// Zero disables the timeout
+func WithStatementTimeout(d time.Duration) ConnectOption {
+	return func(cfg *pgxpool.Config) {
+		cfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(d.Milliseconds(), 10)
+	}
+}
+
+// WithPreparedStatements resizes pgx's per connection statement cache, which prepares every query on first use
+// pgx already keeps DefaultPreparedStatements of them, so this only changes the capacity.
+// Zero disables the cache, which is needed behind poolers like PgBouncer in transaction mode
+func WithPreparedStatements(capacity int) ConnectOption {
+	return func(cfg *pgxpool.Config) {
+		if capacity <= 0 {
+			cfg.ConnConfig.BuildStatementCache = nil
+			return
+		}
+		cfg.ConnConfig.BuildStatementCache = func(conn *pgconn.PgConn) stmtcache.Cache {
+			return stmtcache.New(conn, stmtcache.ModePrepare, capacity)
+		}
+	}
+}
+
+// hotStatements are the queries run by every BalanceOf and Transfer
+var hotStatements = []string{balanceQ, planTransferQ, moveQ}
+
+// WithHotStatements prepares the BalanceOf and Transfer queries on every new pooled connection
+// pgx runs a query through a statement prepared under its own SQL as name, so these skip parsing
+// even with the statement cache disabled. Not for poolers like PgBouncer in transaction mode
+func WithHotStatements() ConnectOption {
+	return func(cfg *pgxpool.Config) {
+		afterConnect := cfg.AfterConnect
+		cfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			if afterConnect != nil {
+				err := afterConnect(ctx, conn)
+				if err != nil {
+					return err
+				}
+			}
+			for _, sql := range hotStatements {
+				_, err := conn.Prepare(ctx, sql, sql)
+				if err != nil {
+					return terror.Error(err, "Could not prepare statement")
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// Connect opens a pool on dsn with the package defaults and pings it before returning
+// Settings in the DSN take precedence over the defaults, the options override both
+func Connect(ctx context.Context, dsn string, opts ...ConnectOption) (*pgxpool.Pool, error) {
+	cfg, err := connectConfig(dsn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "host", cfg.ConnConfig.Host)
+		return nil, terror.Error(err, "Could not connect to database")
+	}
+	err = Ping(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// connectConfig parses dsn and applies each package default the DSN leaves unset, then opts
+func connectConfig(dsn string, opts ...ConnectOption) (*pgxpool.Config, error) {
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, terror.Error(err, "Invalid database DSN")
+	}
+	// pgxpool strips its pool_ settings once parsed, so look for them in a plain parse
+	params, err := pgconn.ParseConfig(dsn)
+	if err != nil {
+		return nil, terror.Error(err, "Invalid database DSN")
+	}
+	if _, ok := params.RuntimeParams["pool_max_conns"]; !ok {
+		cfg.MaxConns = DefaultMaxConns
+	}
+	if _, ok := params.RuntimeParams["pool_health_check_period"]; !ok {
+		cfg.HealthCheckPeriod = DefaultHealthCheckPeriod
+	}
+	if _, ok := params.RuntimeParams["statement_timeout"]; !ok {
+		WithStatementTimeout(DefaultStatementTimeout)(cfg)
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg, nil
+}
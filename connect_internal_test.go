@@ -0,0 +1,62 @@
+package erc20
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnectConfigKeepsDSNSettings(t *testing.T) {
+	tests := []struct {
+		name              string
+		dsn               string
+		opts              []ConnectOption
+		maxConns          int32
+		healthCheckPeriod time.Duration
+		statementTimeout  string
+	}{
+		{
+			name:              "defaults",
+			dsn:               "postgres://erc20@localhost:5432/erc20",
+			maxConns:          DefaultMaxConns,
+			healthCheckPeriod: DefaultHealthCheckPeriod,
+			statementTimeout:  "30000",
+		},
+		{
+			name:              "dsn",
+			dsn:               "postgres://erc20@localhost:5432/erc20?pool_max_conns=3&pool_health_check_period=5s&statement_timeout=1500",
+			maxConns:          3,
+			healthCheckPeriod: 5 * time.Second,
+			statementTimeout:  "1500",
+		},
+		{
+			name:              "dsn at the pgxpool defaults",
+			dsn:               "postgres://erc20@localhost:5432/erc20?pool_max_conns=4&pool_health_check_period=1m",
+			maxConns:          4,
+			healthCheckPeriod: time.Minute,
+			statementTimeout:  "30000",
+		},
+		{
+			name:              "options over dsn",
+			dsn:               "postgres://erc20@localhost:5432/erc20?pool_max_conns=3&statement_timeout=1500",
+			opts:              []ConnectOption{WithMaxConns(7), WithStatementTimeout(2 * time.Second)},
+			maxConns:          7,
+			healthCheckPeriod: DefaultHealthCheckPeriod,
+			statementTimeout:  "2000",
+		},
+	}
+	for _, tt := range tests {
+		cfg, err := connectConfig(tt.dsn, tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if cfg.MaxConns != tt.maxConns {
+			t.Errorf("%s: MaxConns = %d, want %d", tt.name, cfg.MaxConns, tt.maxConns)
+		}
+		if cfg.HealthCheckPeriod != tt.healthCheckPeriod {
+			t.Errorf("%s: HealthCheckPeriod = %v, want %v", tt.name, cfg.HealthCheckPeriod, tt.healthCheckPeriod)
+		}
+		if got := cfg.ConnConfig.RuntimeParams["statement_timeout"]; got != tt.statementTimeout {
+			t.Errorf("%s: statement_timeout = %q, want %q", tt.name, got, tt.statementTimeout)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package erc20
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrSupplyMismatch is returned when balances do not add up to the total supply
+var ErrSupplyMismatch = errors.New("ERC20: balances do not match total supply")
+
+var csvHeader = []string{"address", "balance"}
+
+// ExportBalances writes every address balance of a token as address,balance CSV rows
+// Escrow addresses are left out, see IterateBalances
+func ExportBalances(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(csvHeader)
+	if err != nil {
+		return terror.Error(err, "Could not write csv")
+	}
+	err = IterateBalances(ctx, conn, tokenID, func(addr Address, balance int) error {
+		err := cw.Write([]string{uuid.UUID(addr).String(), strconv.Itoa(balance)})
+		if err != nil {
+			return terror.Error(err, "Could not write csv")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	if cw.Error() != nil {
+		return terror.Error(cw.Error(), "Could not write csv")
+	}
+	return nil
+}
+
+// ImportBalances replaces the balances of a token with the address,balance CSV rows in r
+// Addresses missing from the CSV are zeroed and every changed balance is recorded as an adjustment event.
+// Escrow balances are left as they are, as ExportBalances leaves them out, and ErrEscrowAddress is returned if the CSV names one.
+// Rejected if the balances do not add up to the total supply
+func ImportBalances(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, r io.Reader) error {
+	balances := map[uuid.UUID]int{}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return terror.Error(err, fmt.Sprintf("Malformed csv on line %d", line))
+		}
+		if line == 1 && record[0] == csvHeader[0] && record[1] == csvHeader[1] {
+			continue
+		}
+		address, err := uuid.FromString(record[0])
+		if err != nil {
+			return terror.Error(err, fmt.Sprintf("Invalid address on line %d", line))
+		}
+		balance, err := strconv.Atoi(record[1])
+		if err != nil || balance < 0 {
+			return terror.Error(ErrInvalidAmount, fmt.Sprintf("Invalid balance on line %d", line))
+		}
+		balances[address] = balance
+	}
+
+	err := beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		err := activeToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		held, err := heldAddresses(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		for _, address := range held {
+			if _, ok := balances[uuid.UUID(address)]; ok {
+				continue
+			}
+			err = adjustBalance(ctx, tx, tokenID, address, 0)
+			if err != nil {
+				return err
+			}
+		}
+		for address, balance := range balances {
+			err = adjustBalance(ctx, tx, tokenID, Address(address), balance)
+			if err != nil {
+				return err
+			}
+		}
+		var totalSupply, summed int
+		checkQ := `
+SELECT total_supply, (SELECT COALESCE(SUM(balance), 0) FROM addresses WHERE token_id = $1)
+FROM tokens WHERE id = $1`
+		err = tx.QueryRow(ctx, checkQ, tokenID).Scan(&totalSupply, &summed)
+		if err != nil {
+			return err
+		}
+		if totalSupply != summed {
+			return ErrSupplyMismatch
+		}
+		return nil
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return terror.Error(err, "Could not import balances")
+	}
+	return nil
+}
+
+// heldAddresses returns every address of a token with a non-zero balance inside tx, escrow addresses excepted
+func heldAddresses(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID) ([]Address, error) {
+	q := `SELECT owner FROM addresses WHERE token_id = $1 AND balance <> 0 AND ` + notEscrowQ
+	rows, err := tx.Query(ctx, q, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	addresses := []Address{}
+	for rows.Next() {
+		var address Address
+		err = rows.Scan(&address)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return addresses, nil
+}
@@ -0,0 +1,70 @@
+package erc20_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestExportImportBalances(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, a, b := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+	transfer(t, conn, tokenID, owner, a, 30)
+	transfer(t, conn, tokenID, owner, b, 20)
+
+	var snapshot bytes.Buffer
+	err := erc20.ExportBalances(ctx, conn, tokenID, &snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newAddress(t)
+	transfer(t, conn, tokenID, a, c, 30)
+	transfer(t, conn, tokenID, owner, b, 50)
+
+	err = erc20.ImportBalances(ctx, conn, tokenID, bytes.NewReader(snapshot.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[erc20.Address]int{owner: 50, a: 30, b: 20, c: 0}
+	wantBalances(t, conn, tokenID, want)
+	wantConserved(t, conn, tokenID)
+
+	err = erc20.RebuildBalances(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, want)
+}
+
+func TestImportBalancesRejects(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+	csv := "address,balance\n" + uuid.UUID(owner).String() + ",100\n"
+
+	err := erc20.ImportBalances(ctx, conn, tokenID, strings.NewReader("address,balance\nnot-an-address,5\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ImportBalances with a bad address = %v, want an error naming line 2", err)
+	}
+	err = erc20.ImportBalances(ctx, conn, tokenID, strings.NewReader("address,balance\n"+uuid.UUID(owner).String()+",90\n"))
+	if !errors.Is(err, erc20.ErrSupplyMismatch) {
+		t.Errorf("ImportBalances short of the supply = %v, want %v", err, erc20.ErrSupplyMismatch)
+	}
+
+	err = erc20.Pause(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.ImportBalances(ctx, conn, tokenID, strings.NewReader(csv))
+	if !errors.Is(err, erc20.ErrPaused) {
+		t.Errorf("ImportBalances while paused = %v, want %v", err, erc20.ErrPaused)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 100})
+}
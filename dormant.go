@@ -0,0 +1,49 @@
+package erc20
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// DormantAddresses returns the IDs of zero balance addresses with no activity since the given time, stalest first
+func DormantAddresses(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, since time.Time, limit int) ([]uuid.UUID, error) {
+	return dormantAddresses(ctx, conn, tokenID, since, limit, false)
+}
+
+// DormantAddressesAnyBalance is DormantAddresses without the zero balance requirement
+func DormantAddressesAnyBalance(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, since time.Time, limit int) ([]uuid.UUID, error) {
+	return dormantAddresses(ctx, conn, tokenID, since, limit, true)
+}
+
+func dormantAddresses(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, since time.Time, limit int, anyBalance bool) ([]uuid.UUID, error) {
+	q := `
+SELECT id FROM addresses
+WHERE token_id = $1 AND updated_at < $2 AND ($3 OR balance = 0)
+ORDER BY updated_at, id
+LIMIT $4`
+	rows, err := conn.Query(ctx, q, tokenID, since, anyBalance, limit)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "since", since)
+		return nil, terror.Error(err, "Could not get dormant addresses")
+	}
+	defer rows.Close()
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		err = rows.Scan(&id)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "id", tokenID, "since", since)
+			return nil, terror.Error(err, "Could not scan address")
+		}
+		ids = append(ids, id)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error(), "id", tokenID, "since", since)
+		return nil, terror.Error(rows.Err(), "Could not get dormant addresses")
+	}
+	return ids, nil
+}
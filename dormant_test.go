@@ -0,0 +1,58 @@
+package erc20_test
+
+import (
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ageAddress backdates the last activity of an address
+func ageAddress(t *testing.T, conn *pgxpool.Pool, addressID uuid.UUID, age time.Duration) {
+	t.Helper()
+	_, err := conn.Exec(ctx, `UPDATE addresses SET updated_at = now() - make_interval(secs => $1) WHERE id = $2`, age.Seconds(), addressID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDormantAddresses(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	addressID := func(owner erc20.Address) uuid.UUID {
+		id, err := erc20.GetOrCreateAddress(ctx, conn, tokenID, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	holding := newAddress(t)
+	transfer(t, conn, tokenID, owner, holding, 10)
+	staleHolding := addressID(holding)
+	staleEmpty := addressID(newAddress(t))
+	addressID(newAddress(t))
+	ageAddress(t, conn, staleHolding, 90*24*time.Hour)
+	ageAddress(t, conn, staleEmpty, 60*24*time.Hour)
+	since := time.Now().Add(-30 * 24 * time.Hour)
+
+	ids, err := erc20.DormantAddresses(ctx, conn, tokenID, since, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != staleEmpty {
+		t.Errorf("DormantAddresses = %v, want only the stale empty address", ids)
+	}
+
+	ids, err = erc20.DormantAddressesAnyBalance(ctx, conn, tokenID, since, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != staleHolding || ids[1] != staleEmpty {
+		t.Errorf("DormantAddressesAnyBalance = %v, want both stale addresses, stalest first", ids)
+	}
+}
@@ -0,0 +1,113 @@
+package erc20
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// DumpRecord is a single line of a ledger dump, one row of Table as JSON
+type DumpRecord struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+// dumpQueries select the rows of an account book for each dumped table, parents before children
+var dumpQueries = []struct {
+	table string
+	query string
+}{
+	{"account_books", `SELECT row_to_json(t) FROM account_books t WHERE id = $1`},
+	{"tokens", `SELECT row_to_json(t) FROM tokens t WHERE account_book_id = $1 ORDER BY id`},
+	{"addresses", `
+SELECT row_to_json(t) FROM addresses t
+WHERE token_id IN (SELECT id FROM tokens WHERE account_book_id = $1) ORDER BY id`},
+	{"allowances", `
+SELECT row_to_json(t) FROM allowances t
+WHERE token_id IN (SELECT id FROM tokens WHERE account_book_id = $1) ORDER BY token_id, owner, spender`},
+}
+
+// restoreQueries insert a dumped row into its table, keeping every column including IDs
+var restoreQueries = map[string]string{
+	"account_books": `INSERT INTO account_books SELECT * FROM json_populate_record(NULL::account_books, $1)`,
+	"tokens":        `INSERT INTO tokens SELECT * FROM json_populate_record(NULL::tokens, $1)`,
+	"addresses":     `INSERT INTO addresses SELECT * FROM json_populate_record(NULL::addresses, $1)`,
+	"allowances":    `INSERT INTO allowances SELECT * FROM json_populate_record(NULL::allowances, $1)`,
+}
+
+// DumpLedger writes the account book, its tokens, addresses and allowances to w as JSON lines of DumpRecord
+// Rows are streamed as they are read, from a single repeatable read snapshot
+func DumpLedger(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	err := beginTxFunc(ctx, conn, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		for _, dump := range dumpQueries {
+			rows, err := tx.Query(ctx, dump.query, accountBookID)
+			if err != nil {
+				return err
+			}
+			for rows.Next() {
+				var row json.RawMessage
+				err = rows.Scan(&row)
+				if err == nil {
+					err = enc.Encode(DumpRecord{Table: dump.table, Row: row})
+				}
+				if err != nil {
+					rows.Close()
+					return err
+				}
+			}
+			rows.Close()
+			if rows.Err() != nil {
+				return rows.Err()
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID)
+		return terror.Error(err, "Could not dump ledger")
+	}
+	return nil
+}
+
+// RestoreLedger loads a DumpLedger stream from r in one transaction, preserving IDs
+// Nothing is restored if any line fails
+func RestoreLedger(ctx context.Context, conn *pgxpool.Pool, r io.Reader) error {
+	err := beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		dec := json.NewDecoder(r)
+		for line := 1; ; line++ {
+			var record DumpRecord
+			err := dec.Decode(&record)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			q, ok := restoreQueries[record.Table]
+			if !ok {
+				return fmt.Errorf("line %d: unknown table %q", line, record.Table)
+			}
+			_, err = tx.Exec(ctx, q, string(record.Row))
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error())
+		return terror.Error(err, "Could not restore ledger")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package erc20_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestDumpRestoreLedger(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	bookID := erc20test.NewAccountBook(t, conn)
+	owner, alice, bob := newAddress(t), newAddress(t), newAddress(t)
+	gold, err := erc20.Factory(ctx, conn, bookID, owner, "Gold", "GLD", 18, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	silver, err := erc20.Factory(ctx, conn, bookID, owner, "Silver", "SLV", 6, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer(t, conn, gold, owner, alice, 250)
+	transfer(t, conn, silver, owner, bob, 75)
+	err = erc20.Approve(ctx, conn, gold, alice, bob, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dump bytes.Buffer
+	err = erc20.DumpLedger(ctx, conn, bookID, &dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := map[string]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(dump.Bytes()))
+	for scanner.Scan() {
+		var record erc20.DumpRecord
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			t.Fatalf("dump line %q: %v", scanner.Text(), err)
+		}
+		tables[record.Table]++
+	}
+	wantTables := map[string]int{"account_books": 1, "tokens": 2, "addresses": 4, "allowances": 1}
+	if !reflect.DeepEqual(tables, wantTables) {
+		t.Errorf("dumped rows per table = %v, want %v", tables, wantTables)
+	}
+
+	restored := erc20test.NewTestDB(t)
+	err = erc20.RestoreLedger(ctx, restored, bytes.NewReader(dump.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := erc20.ListTokens(ctx, conn, bookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := erc20.ListTokens(ctx, restored, bookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("restored %d tokens, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].CreatedAt.Equal(want[i].CreatedAt) || !got[i].UpdatedAt.Equal(want[i].UpdatedAt) {
+			t.Errorf("restored token %s timestamps = %v/%v, want %v/%v", want[i].Symbol, got[i].CreatedAt, got[i].UpdatedAt, want[i].CreatedAt, want[i].UpdatedAt)
+		}
+		got[i].CreatedAt, got[i].UpdatedAt = want[i].CreatedAt, want[i].UpdatedAt
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("restored token = %+v, want %+v", got[i], want[i])
+		}
+	}
+	wantBalances(t, restored, gold, map[erc20.Address]int{owner: 750, alice: 250})
+	wantBalances(t, restored, silver, map[erc20.Address]int{owner: 425, bob: 75})
+	if got := allowance(t, restored, gold, alice, bob); got != 40 {
+		t.Errorf("restored allowance = %d, want 40", got)
+	}
+}
+
+func TestRestoreLedgerIsAllOrNothing(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	dump := `{"table":"account_books","row":{"id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","created_at":"2021-01-01T00:00:00Z","updated_at":"2021-01-01T00:00:00Z"}}
+{"table":"balances","row":{}}
+`
+	err := erc20.RestoreLedger(ctx, conn, strings.NewReader(dump))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("RestoreLedger error = %v, want it to name line 2", err)
+	}
+	books, err := erc20.ListAccountBooks(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(books) != 0 {
+		t.Errorf("account books after a failed restore = %d, want none", len(books))
+	}
+}
@@ -3,6 +3,10 @@ package erc20
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/ninja-software/terror/v2"
 
@@ -22,126 +26,524 @@ func init() {
 	log = l.Sugar()
 }
 
+// Address identifies the owner of a balance
+// Each owner has one addresses row per token
 type Address uuid.UUID
 
+// ZeroAddress stands in for the missing side of a mint or burn, as address(0) does on chain
+var ZeroAddress = Address(uuid.Nil)
+
+// ErrInsufficientBalance is returned when an address does not hold enough to cover an amount
+var ErrInsufficientBalance = errors.New("ERC20: amount exceeds balance")
+
+// ErrTokenNotFound is returned when a token does not exist
+var ErrTokenNotFound = errors.New("ERC20: token not found")
+
+// ErrAddressExists is returned by CreateAddress when the owner already has an address for the token
+var ErrAddressExists = errors.New("ERC20: address already exists")
+
+// ErrAmbiguousSymbol is returned when tokens in more than one account book have the requested symbol
+var ErrAmbiguousSymbol = errors.New("ERC20: token symbol is ambiguous")
+
+// ErrAmbiguousName is returned when more than one token in an account book has the requested name
+// Only possible on a schema that has not applied the unique token names migration
+var ErrAmbiguousName = errors.New("ERC20: token name is ambiguous")
+
+// ErrTransferTooLarge is returned when a transfer exceeds the token's maximum transfer amount
+var ErrTransferTooLarge = errors.New("ERC20: transfer exceeds maximum amount")
+
+// ErrBelowMinSupply is returned when a burn would take the total supply below the token's minimum
+var ErrBelowMinSupply = errors.New("ERC20: burn below minimum supply")
+
+// Token is a single token row
+type Token struct {
+	ID            uuid.UUID
+	AccountBookID uuid.UUID
+	Name          string
+	Symbol        string
+	Decimals      int
+	TotalSupply   int
+	// MinSupply is the floor Burn and BurnFrom can not take TotalSupply below
+	MinSupply int
+	// TransferBurnBps is the share of every transfer burned, in basis points
+	TransferBurnBps int
+	// FeeBps is the share of every transfer sent to FeeCollector, in basis points
+	FeeBps       int
+	FeeCollector *Address
+	// Owner is the only address allowed to administer the token
+	Owner     Address
+	Paused    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+}
+
+// tokenColumns is the column list scanned by scanToken
+const tokenColumns = `tokens.id, tokens.account_book_id, tokens.name, tokens.symbol, tokens.decimals, tokens.total_supply, tokens.min_supply, tokens.transfer_burn_bps, tokens.fee_bps, tokens.fee_collector, tokens.owner, tokens.paused, tokens.created_at, tokens.updated_at, tokens.deleted_at`
+
+// scanToken scans a row selected with tokenColumns
+func scanToken(row pgx.Row) (Token, error) {
+	var token Token
+	err := row.Scan(&token.ID, &token.AccountBookID, &token.Name, &token.Symbol, &token.Decimals, &token.TotalSupply, &token.MinSupply, &token.TransferBurnBps, &token.FeeBps, &token.FeeCollector, &token.Owner, &token.Paused, &token.CreatedAt, &token.UpdatedAt, &token.DeletedAt)
+	if err != nil {
+		return Token{}, err
+	}
+	return token, nil
+}
+
+// scanTokens collects every row selected with tokenColumns
+func scanTokens(rows pgx.Rows) ([]Token, error) {
+	defer rows.Close()
+	tokens := []Token{}
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return tokens, nil
+}
+
+// Migration is the initial schema, applied by Migrate as version 1
 const Migration = `
 CREATE EXTENSION IF NOT EXISTS pg_trgm;
 CREATE EXTENSION IF NOT EXISTS pgcrypto;
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 CREATE TABLE account_books (
-	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid ()
-)
+	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid (),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
 CREATE TABLE tokens (
 	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid (),
 	account_book_id UUID NOT NULL REFERENCES account_books(id),
 	name TEXT NOT NULL,
-	symbol TEXT UNIQUE NOT NULL,
+	symbol TEXT UNIQUE NOT NULL CHECK (symbol = upper(symbol)),
 	decimals INTEGER NOT NULL,
-	total_supply INTEGER NOT NULL
+	total_supply INTEGER NOT NULL CONSTRAINT tokens_total_supply_non_negative CHECK (total_supply >= 0),
+	min_supply INTEGER NOT NULL DEFAULT 0 CHECK (min_supply >= 0),
+	mint_threshold INTEGER NOT NULL DEFAULT 2 CHECK (mint_threshold >= 1),
+	transfer_burn_bps INTEGER NOT NULL DEFAULT 0 CHECK (transfer_burn_bps BETWEEN 0 AND 10000),
+	fee_bps INTEGER NOT NULL DEFAULT 0 CHECK (fee_bps BETWEEN 0 AND 10000),
+	fee_collector UUID,
+	owner UUID NOT NULL,
+	paused BOOLEAN NOT NULL DEFAULT false,
+	metadata JSONB NOT NULL DEFAULT '{}',
+	deleted_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
 );
-CREATE INDEX idx_tokens_symbol ON tokens (symbol)
+CREATE INDEX idx_tokens_symbol ON tokens (symbol);
+CREATE INDEX idx_tokens_name_trgm ON tokens USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_tokens_symbol_trgm ON tokens USING GIN (symbol gin_trgm_ops);
+CREATE INDEX idx_tokens_metadata ON tokens USING GIN (metadata);
 CREATE TABLE addresses (
-	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid ()
+	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid (),
 	token_id UUID REFERENCES tokens(id),
-	balance INTEGER NOT NULL
+	owner UUID NOT NULL,
+	balance INTEGER NOT NULL CONSTRAINT addresses_balance_non_negative CHECK (balance >= 0),
+	eth_address TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_addresses_token ON addresses (token_id);
+CREATE UNIQUE INDEX idx_addresses_token_owner ON addresses (token_id, owner);
+CREATE UNIQUE INDEX idx_addresses_token_eth ON addresses (token_id, eth_address);
+CREATE TABLE ledger_events (
+	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid (),
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	event_type TEXT NOT NULL,
+	sender UUID NOT NULL,
+	recipient UUID NOT NULL,
+	amount INTEGER NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_ledger_events_token ON ledger_events (token_id, created_at);
+CREATE INDEX idx_ledger_events_sender ON ledger_events (token_id, sender, created_at);
+CREATE INDEX idx_ledger_events_recipient ON ledger_events (token_id, recipient, created_at);
+CREATE TABLE allowances (
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	owner UUID NOT NULL,
+	spender UUID NOT NULL,
+	amount INTEGER NOT NULL,
+	expires_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (token_id, owner, spender)
+);
+CREATE TABLE mint_requests (
+	request_key TEXT NOT NULL PRIMARY KEY,
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE snapshots (
+	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid (),
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE snapshot_balances (
+	snapshot_id UUID NOT NULL REFERENCES snapshots(id),
+	owner UUID NOT NULL,
+	balance INTEGER NOT NULL,
+	PRIMARY KEY (snapshot_id, owner)
+);
+CREATE FUNCTION snapshot_balances_immutable() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'snapshot balances are immutable';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER snapshot_balances_immutable BEFORE UPDATE OR DELETE ON snapshot_balances
+	FOR EACH ROW EXECUTE FUNCTION snapshot_balances_immutable();
+CREATE TABLE vesting_schedules (
+	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid (),
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	beneficiary UUID NOT NULL,
+	total INTEGER NOT NULL CHECK (total >= 0),
+	released INTEGER NOT NULL DEFAULT 0,
+	start_at TIMESTAMPTZ NOT NULL,
+	cliff_at TIMESTAMPTZ NOT NULL,
+	duration INTERVAL NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE scheduled_transfers (
+	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid (),
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	sender UUID NOT NULL,
+	recipient UUID NOT NULL,
+	amount INTEGER NOT NULL CHECK (amount >= 0),
+	release_at TIMESTAMPTZ NOT NULL,
+	released_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_scheduled_transfers_due ON scheduled_transfers (release_at) WHERE released_at IS NULL;
+CREATE TABLE holds (
+	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid (),
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	account UUID NOT NULL,
+	amount INTEGER NOT NULL CHECK (amount >= 0),
+	settled_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_holds_account ON holds (token_id, account) WHERE settled_at IS NULL;
+CREATE TABLE permit_keys (
+	owner UUID NOT NULL PRIMARY KEY,
+	public_key BYTEA NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE permit_nonces (
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	owner UUID NOT NULL,
+	nonce INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (token_id, owner)
+);
+CREATE TABLE token_roles (
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	address UUID NOT NULL,
+	role TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (token_id, address, role)
+);
+CREATE TABLE mint_proposals (
+	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid (),
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	account UUID NOT NULL,
+	amount INTEGER NOT NULL CHECK (amount >= 0),
+	proposer UUID NOT NULL,
+	threshold INTEGER NOT NULL,
+	executed_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE mint_approvals (
+	proposal_id UUID NOT NULL REFERENCES mint_proposals(id),
+	approver UUID NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (proposal_id, approver)
+);
+CREATE TABLE webhooks (
+	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid (),
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE webhook_deliveries (
+	webhook_id UUID NOT NULL REFERENCES webhooks(id),
+	event_id UUID NOT NULL REFERENCES ledger_events(id),
+	delivered_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (webhook_id, event_id)
 );
-CREATE INDEX idx_addresses_token ON addresses (token_id)
 `
 
-// Factory creates a new token
-func Factory(conn *pgxpool.Pool, name string, symbol string, decimals int, totalSupply int) error {
-	ctx := context.Background()
-	err := conn.BeginFunc(ctx, func(tx pgx.Tx) error {
-		q := `INSERT INTO tokens (name, symbol, decimals, total_supply) VALUES ($1, $2, $3, $4);`
-		tx.Exec(ctx, q, name, symbol, decimals, totalSupply)
-		return nil
-	})
+// NormalizeSymbol returns the stored form of a symbol, trimmed and upper case
+// Symbols are unique and looked up in this form
+func NormalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// Errors returned by ValidateToken
+var (
+	ErrInvalidDecimals = errors.New("ERC20: invalid decimals")
+	ErrInvalidName     = errors.New("ERC20: invalid token name")
+	ErrInvalidSymbol   = errors.New("ERC20: invalid token symbol")
+)
+
+// MaxDecimals and MaxSymbolLength bound what ValidateToken accepts
+// Raise MaxDecimals before creating tokens with more precision than any on chain token uses
+var (
+	MaxDecimals     = 36
+	MaxSymbolLength = 11
+)
+
+// ValidateToken checks the details of a new token
+// Decimals must be between 0 and MaxDecimals, the name not blank and the symbol between 1 and MaxSymbolLength characters
+func ValidateToken(name string, symbol string, decimals int) error {
+	if decimals < 0 || decimals > MaxDecimals {
+		return terror.Error(ErrInvalidDecimals, fmt.Sprintf("Decimals must be between 0 and %d", MaxDecimals))
+	}
+	if strings.TrimSpace(name) == "" {
+		return terror.Error(ErrInvalidName, "Name can not be empty")
+	}
+	symbol = NormalizeSymbol(symbol)
+	if symbol == "" || utf8.RuneCountInString(symbol) > MaxSymbolLength {
+		return terror.Error(ErrInvalidSymbol, fmt.Sprintf("Symbol must be between 1 and %d characters", MaxSymbolLength))
+	}
+	return nil
+}
+
+// Factory creates a new token administered by owner in an account book and returns its ID
+// The initial supply is minted to owner. Returns ErrAccountBookNotFound if the account book does not exist. The symbol is stored normalized, the name keeps its casing
+func Factory(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID, owner Address, name string, symbol string, decimals int, totalSupply int) (uuid.UUID, error) {
+	err := ValidateToken(name, symbol, decimals)
 	if err != nil {
+		return uuid.Nil, err
+	}
+	var tokenID uuid.UUID
+	err = beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		tokenID, err = createToken(ctx, tx, accountBookID, owner, name, symbol, decimals, totalSupply)
 		return err
+	})
+	if isForeignKeyViolation(err, "tokens_account_book_id_fkey") {
+		return uuid.Nil, terror.Error(ErrAccountBookNotFound, "Account book not found")
 	}
-	return nil
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "name", name, "symbol", symbol)
+		return uuid.Nil, terror.Error(err, "Could not create token")
+	}
+	return tokenID, nil
 }
 
-// TokenIDBySymbol retrieves the token ID given its unique symbol
-func TokenIDBySymbol(conn *pgxpool.Pool, name string) (uuid.UUID, error) {
-	ctx := context.Background()
-	q := `SELECT id FROM tokens WHERE symbol = $1`
+// createToken inserts a token inside tx and mints its initial supply to owner
+// The mint is recorded like any other, so the supply reconciles with the balances and the ledger
+func createToken(ctx context.Context, tx pgx.Tx, accountBookID uuid.UUID, owner Address, name string, symbol string, decimals int, totalSupply int) (uuid.UUID, error) {
+	if totalSupply < 0 {
+		return uuid.Nil, ErrInvalidAmount
+	}
+	q := `
+INSERT INTO tokens (account_book_id, owner, name, symbol, decimals, total_supply) VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id`
 	var tokenID uuid.UUID
-	row := conn.QueryRow(ctx, q, name)
-	err := row.Scan(&tokenID)
+	err := tx.QueryRow(ctx, q, accountBookID, owner, name, NormalizeSymbol(symbol), decimals, totalSupply).Scan(&tokenID)
 	if err != nil {
-		log.Errorw(err.Error(), "id", name)
-		return uuid.Nil, terror.Error(err, "Could not fetch from database")
+		return uuid.Nil, err
+	}
+	if totalSupply == 0 {
+		return tokenID, nil
+	}
+	_, err = credit(ctx, tx, tokenID, owner, totalSupply)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	err = trackMint(ctx, tx, tokenID, owner, totalSupply)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	_, err = recordEvent(ctx, tx, tokenID, EventMint, ZeroAddress, owner, totalSupply)
+	if err != nil {
+		return uuid.Nil, err
 	}
 	return tokenID, nil
 }
 
-// AddressByAccountBookIDSymbol retrieves the address given a symbol and account book ID
-// It will create an address on the fly if not found
-func AddressByAccountBookIDSymbol(conn *pgxpool.Pool, symbol string, accountBookID uuid.UUID) (uuid.UUID, error) {
-	ctx := context.Background()
-	countQ := `
-SELECT count(addresses.id) FROM addresses
-JOIN tokens ON tokens.id = addresses.id
-JOIN account_books ON account_books.id = tokens.account_book_id
-WHERE tokens.symbol = $1 AND account_book_id = $2`
-	var count int
-	row := conn.QueryRow(ctx, countQ, symbol, accountBookID)
-	err := row.Scan(&count)
-	if err != nil {
-		log.Errorw(err.Error(), "symbol", symbol, "accountBookID", accountBookID)
-		return uuid.Nil, terror.Error(err, "Could not get count addresses")
-	}
-	if count == 0 {
-		var addressID uuid.UUID
-		tokenID, err := TokenIDBySymbol(conn, symbol)
+// TokenIDBySymbol retrieves the token ID given its symbol
+// Case insensitive. Returns ErrAmbiguousSymbol if more than one account book has a token with the symbol
+func TokenIDBySymbol(ctx context.Context, conn *pgxpool.Pool, name string) (uuid.UUID, error) {
+	q := `SELECT id FROM tokens WHERE symbol = $1 AND deleted_at IS NULL LIMIT 2`
+	rows, err := conn.Query(ctx, q, NormalizeSymbol(name))
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", name)
+		return uuid.Nil, terror.Error(err, "Could not fetch from database")
+	}
+	defer rows.Close()
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var tokenID uuid.UUID
+		err = rows.Scan(&tokenID)
 		if err != nil {
-			return uuid.Nil, terror.Error(err, "Could not get address")
+			logger(ctx).Errorw(err.Error(), "id", name)
+			return uuid.Nil, terror.Error(err, "Could not fetch from database")
 		}
-		insertQ := `INSERT INTO addresses (token_id, balance) VALUES ($1, $2) RETURNING id;`
-		err = conn.BeginFunc(ctx, func(tx pgx.Tx) error {
-			row, err := tx.Query(ctx, insertQ, tokenID, 0)
-			if err != nil {
-				return err
-			}
-			err = row.Scan(&tokenID)
-			if err != nil {
-				return err
-			}
-			return nil
-		})
+		ids = append(ids, tokenID)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error(), "id", name)
+		return uuid.Nil, terror.Error(rows.Err(), "Could not fetch from database")
+	}
+	switch len(ids) {
+	case 0:
+		return uuid.Nil, terror.Error(ErrTokenNotFound, "Token not found")
+	case 1:
+		return ids[0], nil
+	default:
+		return uuid.Nil, terror.Error(ErrAmbiguousSymbol, "Token symbol is ambiguous")
+	}
+}
+
+// TokenIDByName retrieves the ID of the token with the given name in an account book
+// Exact match, deleted tokens are ignored
+func TokenIDByName(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID, name string) (uuid.UUID, error) {
+	q := `SELECT id FROM tokens WHERE account_book_id = $1 AND name = $2 AND deleted_at IS NULL LIMIT 2`
+	rows, err := conn.Query(ctx, q, accountBookID, name)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "name", name)
+		return uuid.Nil, terror.Error(err, "Could not fetch from database")
+	}
+	defer rows.Close()
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var tokenID uuid.UUID
+		err = rows.Scan(&tokenID)
 		if err != nil {
-			return uuid.Nil, terror.Error(err, "Could not insert new address")
+			logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "name", name)
+			return uuid.Nil, terror.Error(err, "Could not fetch from database")
 		}
-		return addressID, nil
+		ids = append(ids, tokenID)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error(), "accountBookID", accountBookID, "name", name)
+		return uuid.Nil, terror.Error(rows.Err(), "Could not fetch from database")
+	}
+	switch len(ids) {
+	case 0:
+		return uuid.Nil, terror.Error(ErrTokenNotFound, "Token not found")
+	case 1:
+		return ids[0], nil
+	default:
+		return uuid.Nil, terror.Error(ErrAmbiguousName, "Token name is ambiguous")
+	}
+}
+
+// GetToken retrieves a token by ID
+// Deleted tokens are not found
+func GetToken(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) (*Token, error) {
+	q := `SELECT ` + tokenColumns + ` FROM tokens WHERE id = $1 AND deleted_at IS NULL`
+	token, err := scanToken(conn.QueryRow(ctx, q, tokenID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return nil, terror.Error(err, "Could not get token")
+	}
+	return &token, nil
+}
+
+// ListTokens returns every token in an account book, ordered by symbol
+// Deleted tokens are excluded
+func ListTokens(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID) ([]Token, error) {
+	return listTokens(ctx, conn, accountBookID, false)
+}
+
+// ListTokensIncludingDeleted returns every token in an account book, deleted or not, ordered by symbol
+func ListTokensIncludingDeleted(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID) ([]Token, error) {
+	return listTokens(ctx, conn, accountBookID, true)
+}
+
+func listTokens(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID, includeDeleted bool) ([]Token, error) {
+	q := `SELECT ` + tokenColumns + ` FROM tokens WHERE account_book_id = $1 AND ($2 OR deleted_at IS NULL) ORDER BY symbol`
+	rows, err := conn.Query(ctx, q, accountBookID, includeDeleted)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID)
+		return nil, terror.Error(err, "Could not list tokens")
+	}
+	tokens, err := scanTokens(rows)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID)
+		return nil, terror.Error(err, "Could not list tokens")
 	}
+	return tokens, nil
+}
 
-	addressQ := `
-SELECT addresses.id FROM addresses
-JOIN tokens ON tokens.id = addresses.id
-JOIN account_books ON account_books.id = tokens.account_book_id
-WHERE tokens.symbol = $1 AND account_book_id = $2`
+// GetOrCreateAddress returns the address row ID of owner for a token
+// Creates a zero balance row if the owner has none yet. Safe to race, there is one row per (token, owner)
+func GetOrCreateAddress(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner Address) (uuid.UUID, error) {
+	insertQ := `INSERT INTO addresses (token_id, owner, balance) VALUES ($1, $2, 0) ON CONFLICT (token_id, owner) DO NOTHING`
+	_, err := conn.Exec(ctx, insertQ, tokenID, owner)
+	if isForeignKeyViolation(err, "addresses_token_id_fkey") {
+		return uuid.Nil, terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "tokenID", tokenID, "owner", owner)
+		return uuid.Nil, terror.Error(err, "Could not insert address")
+	}
+	q := `SELECT id FROM addresses WHERE token_id = $1 AND owner = $2`
 	var addressID uuid.UUID
-	row = conn.QueryRow(ctx, addressQ, symbol, accountBookID)
-	err = row.Scan(&addressID)
+	err = conn.QueryRow(ctx, q, tokenID, owner).Scan(&addressID)
 	if err != nil {
-		log.Errorw(err.Error(), "symbol", symbol, "accountBookID", accountBookID)
+		logger(ctx).Errorw(err.Error(), "tokenID", tokenID, "owner", owner)
 		return uuid.Nil, terror.Error(err, "Could not get address")
 	}
 	return addressID, nil
 }
 
+// CreateAddress inserts a zero balance address for owner and returns its row ID
+// Returns ErrAddressExists if owner already has an address for the token
+func CreateAddress(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner Address) (uuid.UUID, error) {
+	q := `INSERT INTO addresses (token_id, owner, balance) VALUES ($1, $2, 0) RETURNING id`
+	var addressID uuid.UUID
+	err := conn.QueryRow(ctx, q, tokenID, owner).Scan(&addressID)
+	if isUniqueViolation(err, "idx_addresses_token_owner") {
+		return uuid.Nil, terror.Error(ErrAddressExists, "Address already exists")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "tokenID", tokenID, "owner", owner)
+		return uuid.Nil, terror.Error(err, "Could not insert address")
+	}
+	return addressID, nil
+}
+
+// AddressByAccountBookIDSymbol retrieves the account book's own address for the token with the given symbol
+// The account book is the owner. It will create an address on the fly if not found
+func AddressByAccountBookIDSymbol(ctx context.Context, conn *pgxpool.Pool, symbol string, accountBookID uuid.UUID) (uuid.UUID, error) {
+	q := `SELECT id FROM tokens WHERE symbol = $1 AND account_book_id = $2 AND deleted_at IS NULL`
+	var tokenID uuid.UUID
+	row := conn.QueryRow(ctx, q, NormalizeSymbol(symbol), accountBookID)
+	err := row.Scan(&tokenID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "symbol", symbol, "accountBookID", accountBookID)
+		return uuid.Nil, terror.Error(err, "Could not get token")
+	}
+	return GetOrCreateAddress(ctx, conn, tokenID, Address(accountBookID))
+}
+
 // Name returns the name of the token.
 // Not unique.
-func Name(conn *pgxpool.Pool, tokenID uuid.UUID) (string, error) {
-	ctx := context.Background()
+func Name(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) (string, error) {
 	q := `SELECT name FROM tokens WHERE id = $1`
 	var name string
 	row := conn.QueryRow(ctx, q, tokenID)
 	err := row.Scan(&name)
 	if err != nil {
-		log.Errorw(err.Error(), "id", tokenID)
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
 		return "", terror.Error(err, "Could not get name")
 	}
 	return name, nil
@@ -149,14 +551,13 @@ func Name(conn *pgxpool.Pool, tokenID uuid.UUID) (string, error) {
 
 // Symbol returns the shorthand version of the token name
 // Unique. Indexed.
-func Symbol(conn *pgxpool.Pool, tokenID uuid.UUID) (string, error) {
-	ctx := context.Background()
+func Symbol(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) (string, error) {
 	q := `SELECT symbol FROM tokens WHERE id = $1`
 	var symbol string
 	row := conn.QueryRow(ctx, q, tokenID)
 	err := row.Scan(&symbol)
 	if err != nil {
-		log.Errorw(err.Error(), "id", tokenID)
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
 		return "", terror.Error(err, "Could not get symbol")
 	}
 	return symbol, nil
@@ -165,162 +566,261 @@ func Symbol(conn *pgxpool.Pool, tokenID uuid.UUID) (string, error) {
 // Decimals returns the numbers for user representation
 // Default is 18
 // Not changable
-func Decimals(conn *pgxpool.Pool, tokenID uuid.UUID) (int, error) {
-	ctx := context.Background()
+func Decimals(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) (int, error) {
 	q := `SELECT decimals FROM tokens WHERE id = $1`
 	var decimals int
 	row := conn.QueryRow(ctx, q, tokenID)
 	err := row.Scan(&decimals)
 	if err != nil {
-		log.Errorw(err.Error(), "id", tokenID)
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
 		return 0, terror.Error(err, "Could not get decimals")
 	}
 	return decimals, nil
 }
 
 // TotalSupply of the token
-func TotalSupply(conn *pgxpool.Pool, tokenID uuid.UUID) (int, error) {
-	ctx := context.Background()
+func TotalSupply(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) (Amount, error) {
 	q := `SELECT total_supply FROM tokens WHERE id = $1`
 	var totalSupply int
 	row := conn.QueryRow(ctx, q, tokenID)
 	err := row.Scan(&totalSupply)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, terror.Error(ErrTokenNotFound, "Token not found")
+	}
 	if err != nil {
-		log.Errorw(err.Error(), "id", tokenID)
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
 		return 0, terror.Error(err, "Could not get total supply")
 	}
-	return totalSupply, nil
+	return Amount(totalSupply), nil
 }
 
+// CirculatingSupply returns the total supply less the balances of the excluded addresses, such as a treasury
+// The zero address is always excluded, anything transferred to it is treated as burned.
+// So are escrow balances of holds, stakes, vesting schedules and scheduled transfers, they are not in circulation
+func CirculatingSupply(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, exclude []Address) (int, error) {
+	owners := []string{uuid.UUID(ZeroAddress).String()}
+	for _, owner := range exclude {
+		owners = append(owners, uuid.UUID(owner).String())
+	}
+	q := `
+SELECT total_supply - (
+	SELECT COALESCE(SUM(balance), 0) FROM addresses
+	WHERE token_id = $1 AND (owner = ANY($2::uuid[]) OR NOT (` + notEscrowQ + `))
+)
+FROM tokens WHERE id = $1`
+	var circulating int
+	err := conn.QueryRow(ctx, q, tokenID, owners).Scan(&circulating)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "exclude", exclude)
+		return 0, terror.Error(err, "Could not get circulating supply")
+	}
+	return circulating, nil
+}
+
+// balanceQ reads one balance, run by every balance read so it is one of the hot statements
+const balanceQ = `SELECT balance FROM addresses WHERE token_id = $1 AND owner = $2`
+
 // BalanceOf an address
 // Creates the address if it doesn't exist
-func BalanceOf(conn *pgxpool.Pool, tokenID uuid.UUID, owner Address) (int, error) {
-	ctx := context.Background()
-	q := `SELECT balance FROM addresses WHERE id = $1`
-	var totalSupply int
-	row := conn.QueryRow(ctx, q, owner)
-	err := row.Scan(&totalSupply)
-	if err != nil {
-		err := conn.BeginFunc(ctx, func(tx pgx.Tx) error {
-			q := `INSERT INTO addresses (token_id, balance) VALUES ($1, $2);`
-			_, err := tx.Exec(ctx, q, tokenID, 0)
-			if err != nil {
-				return err
-			}
-			return nil
-		})
+func BalanceOf(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner Address) (Amount, error) {
+	var balance int
+	row := conn.QueryRow(ctx, balanceQ, tokenID, owner)
+	err := row.Scan(&balance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		_, err = GetOrCreateAddress(ctx, conn, tokenID, owner)
 		if err != nil {
-			log.Errorw(err.Error(), "tokenID", tokenID, "owner", owner)
-			return 0, terror.Error(err, "Could not insert address")
+			return 0, err
 		}
 		return 0, nil
 	}
-	return totalSupply, nil
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "tokenID", tokenID, "owner", owner)
+		return 0, terror.Error(err, "Could not get balance")
+	}
+	return Amount(balance), nil
 }
 
-// TransferFrom moves balance between accounts
-func TransferFrom(conn *pgxpool.Pool, tokenID uuid.UUID, sender Address, recipient Address, amount int) (bool, error) {
-	ctx := context.Background()
-	senderBal, err := BalanceOf(conn, tokenID, sender)
-	if err != nil {
-		return false, terror.Error(err, "get balance")
+// balanceOf reads a balance without creating the address, a missing address holds nothing
+// Safe to run against a read replica
+func balanceOf(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner Address) (int, error) {
+	var balance int
+	err := conn.QueryRow(ctx, balanceQ, tokenID, owner).Scan(&balance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
 	}
-	recipientBal, err := BalanceOf(conn, tokenID, recipient)
 	if err != nil {
-		return false, terror.Error(err, "get balance")
+		logger(ctx).Errorw(err.Error(), "tokenID", tokenID, "owner", owner)
+		return 0, terror.Error(err, "Could not get balance")
+	}
+	return balance, nil
+}
+
+// strictBalanceOf reads a balance, returning ErrAddressNotFound instead of creating a missing address
+func strictBalanceOf(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner Address) (int, error) {
+	var balance int
+	err := conn.QueryRow(ctx, balanceQ, tokenID, owner).Scan(&balance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, terror.Error(ErrAddressNotFound, "Address not found")
 	}
-	err = conn.BeginFunc(ctx, func(tx pgx.Tx) error {
-		if senderBal < amount {
-			return errors.New("ERC20: transfer amount exceeds balance")
-		}
-		setBalanceQ := `UPDATE addresses SET balance = $1`
-		var err error
-		_, err = tx.Exec(ctx, setBalanceQ, recipientBal+amount)
-		if err != nil {
-			log.Errorw(err.Error(), "sender", sender, "recipient", recipient, "amount", amount)
-			return err
-		}
-		_, err = tx.Exec(ctx, setBalanceQ, senderBal-amount)
-		if err != nil {
-			log.Errorw(err.Error(), "sender", sender, "recipient", recipient, "amount", amount)
-			return err
-		}
-		return nil
-	})
 	if err != nil {
-		log.Errorw(err.Error(), "sender", sender, "recipient", recipient, "amount", amount)
-		return false, terror.Error(err, "Could not update balances")
+		logger(ctx).Errorw(err.Error(), "tokenID", tokenID, "owner", owner)
+		return 0, terror.Error(err, "Could not get balance")
 	}
-	return true, nil
+	return balance, nil
 }
 
-// Mint new tokens to an address
-func Mint(conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount int) error {
-	ctx := context.Background()
-	bal, err := BalanceOf(conn, tokenID, account)
+// Transfer moves balance between accounts
+// Tokens with a transfer burn destroy part of the amount on the way
+func Transfer(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, sender Address, recipient Address, amount Amount) (bool, error) {
+	_, err := TransferWithResult(ctx, conn, tokenID, sender, recipient, amount.Int())
 	if err != nil {
-		return terror.Error(err, "get balance")
+		return false, err
 	}
-	totalSupply, err := TotalSupply(conn, tokenID)
+	return true, nil
+}
+
+// TransferWithResult moves balance between accounts and returns both balances as they stand after the transfer
+func TransferWithResult(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, sender Address, recipient Address, amount int) (*TransferResult, error) {
+	return transferWith(ctx, conn, tokenID, sender, recipient, amount, transferOptions{})
+}
+
+// TransferWithEvent is Transfer returning the ID of the ledger event it recorded, for handing to downstream systems
+func TransferWithEvent(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, from, to Address, amount int) (uuid.UUID, error) {
+	result, err := transferWith(ctx, conn, tokenID, from, to, amount, transferOptions{})
 	if err != nil {
-		return terror.Error(err, "get total supply")
+		return uuid.Nil, err
 	}
-	err = conn.BeginFunc(ctx, func(tx pgx.Tx) error {
-		setBalanceQ := `UPDATE addresses SET balance = $1`
-		var err error
-		_, err = tx.Exec(ctx, setBalanceQ, bal+amount)
-		if err != nil {
-			log.Errorw(err.Error(), "account", account, "amount", amount)
-			return err
-		}
-		setTotalSupplyQ := `UPDATE tokens SET total_supply = $1`
-		_, err = tx.Exec(ctx, setTotalSupplyQ, totalSupply+amount)
+	return result.EventID, nil
+}
+
+// transferWith runs the checks in opts inside the transfer's transaction before any balance changes
+func transferWith(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, sender Address, recipient Address, amount int, opts transferOptions) (*TransferResult, error) {
+	var result *TransferResult
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := opts.check(ctx, tx, tokenID, sender, recipient, amount)
 		if err != nil {
-			log.Errorw(err.Error(), "account", account, "amount", amount)
 			return err
 		}
-		return nil
+		result, err = transfer(ctx, tx, tokenID, sender, recipient, amount, opts.rounding)
+		return err
 	})
 	if err != nil {
-		log.Errorw(err.Error(), "account", account, "amount", amount)
+		logger(ctx).Errorw(err.Error(), "sender", sender, "recipient", recipient, "amount", amount)
+		return nil, terror.Error(err, "Could not update balances")
+	}
+	return result, nil
+}
+
+// Mint new tokens to an address
+func Mint(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount Amount) error {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		return mint(ctx, tx, tokenID, account, amount.Int())
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "account", account, "amount", amount)
 		return terror.Error(err, "Could not update balances")
 	}
 	return nil
 }
 
 // Burn existing tokens from an address
-func Burn(conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount int) error {
-	ctx := context.Background()
-	bal, err := BalanceOf(conn, tokenID, account)
+func Burn(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount Amount) error {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		return burn(ctx, tx, tokenID, account, amount.Int())
+	})
 	if err != nil {
-		return terror.Error(err, "get balance")
+		logger(ctx).Errorw(err.Error(), "account", account, "amount", amount)
+		return terror.Error(err, "Could not update balances")
 	}
-	totalSupply, err := TotalSupply(conn, tokenID)
+	return nil
+}
+
+// SetTransferBurn sets the share of every transfer that is burned, in basis points
+// Zero disables the burn
+func SetTransferBurn(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, bps int) error {
+	if bps < 0 || bps > 10000 {
+		return terror.Error(ErrInvalidAmount, "Transfer burn must be between 0 and 10000 basis points")
+	}
+	q := `UPDATE tokens SET transfer_burn_bps = $1, updated_at = now() WHERE id = $2`
+	tag, err := conn.Exec(ctx, q, bps, tokenID)
 	if err != nil {
-		return terror.Error(err, "get total supply")
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "bps", bps)
+		return terror.Error(err, "Could not set transfer burn")
 	}
-	err = conn.BeginFunc(ctx, func(tx pgx.Tx) error {
-		if bal < amount {
-			return errors.New("ERC20: burn amount exceeds balance")
-		}
-		setBalanceQ := `UPDATE addresses SET balance = $1`
-		var err error
-		_, err = tx.Exec(ctx, setBalanceQ, bal-amount)
-		if err != nil {
-			log.Errorw(err.Error(), "account", account, "amount", amount)
-			return err
-		}
-		setTotalSupplyQ := `UPDATE tokens SET total_supply = $1`
-		_, err = tx.Exec(ctx, setTotalSupplyQ, totalSupply-amount)
-		if err != nil {
-			log.Errorw(err.Error(), "account", account, "amount", amount)
-			return err
-		}
-		return nil
-	})
+	if tag.RowsAffected() == 0 {
+		return terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	return nil
+}
+
+// SetMinSupply sets the floor burns can not take the total supply below
+// Zero removes the floor
+func SetMinSupply(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, minSupply int) error {
+	if minSupply < 0 {
+		return terror.Error(ErrInvalidAmount, "Minimum supply can not be negative")
+	}
+	q := `UPDATE tokens SET min_supply = $1, updated_at = now() WHERE id = $2`
+	tag, err := conn.Exec(ctx, q, minSupply, tokenID)
 	if err != nil {
-		log.Errorw(err.Error(), "account", account, "amount", amount)
-		return terror.Error(err, "Could not update balances")
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "minSupply", minSupply)
+		return terror.Error(err, "Could not set minimum supply")
+	}
+	if tag.RowsAffected() == 0 {
+		return terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	return nil
+}
+
+// SetMaxTransfer caps the amount of a single transfer or transferFrom, as a risk control
+// Zero removes the cap
+func SetMaxTransfer(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, maxTransfer int) error {
+	if maxTransfer < 0 {
+		return terror.Error(ErrInvalidAmount, "Maximum transfer can not be negative")
+	}
+	q := `UPDATE tokens SET max_transfer = NULLIF($1, 0), updated_at = now() WHERE id = $2`
+	tag, err := conn.Exec(ctx, q, maxTransfer, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "maxTransfer", maxTransfer)
+		return terror.Error(err, "Could not set maximum transfer")
+	}
+	if tag.RowsAffected() == 0 {
+		return terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	return nil
+}
+
+// SetTransferFee sends the given share of every transfer to collector, in basis points
+// Zero disables the fee
+func SetTransferFee(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, bps int, collector Address) error {
+	if bps < 0 || bps > 10000 {
+		return terror.Error(ErrInvalidAmount, "Transfer fee must be between 0 and 10000 basis points")
+	}
+	q := `UPDATE tokens SET fee_bps = $1, fee_collector = $2, updated_at = now() WHERE id = $3`
+	tag, err := conn.Exec(ctx, q, bps, collector, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "bps", bps, "collector", collector)
+		return terror.Error(err, "Could not set transfer fee")
+	}
+	if tag.RowsAffected() == 0 {
+		return terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	return nil
+}
+
+// DeleteToken retires a token without destroying its history
+// Deleted tokens are hidden from lookups and can no longer be minted or transferred
+func DeleteToken(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) error {
+	q := `UPDATE tokens SET deleted_at = now(), updated_at = now() WHERE id = $1 AND deleted_at IS NULL`
+	tag, err := conn.Exec(ctx, q, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return terror.Error(err, "Could not delete token")
+	}
+	if tag.RowsAffected() == 0 {
+		return terror.Error(ErrTokenNotFound, "Token not found")
 	}
 	return nil
 }
@@ -0,0 +1,361 @@
+package erc20_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+func TestOverBurn(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+
+	err := erc20.Burn(ctx, conn, tokenID, owner, 101)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("Burn over balance error = %v, want ErrInsufficientBalance", err)
+	}
+	if got := balanceOf(t, conn, tokenID, owner); got != 100 {
+		t.Errorf("balance after failed burn = %d, want 100", got)
+	}
+	if got := totalSupply(t, conn, tokenID); got != 100 {
+		t.Errorf("total supply after failed burn = %d, want 100", got)
+	}
+
+	err = erc20.WithRetry(ctx, conn, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `UPDATE addresses SET balance = balance - 101 WHERE token_id = $1 AND owner = $2`, tokenID, owner)
+		return err
+	})
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("negative balance error = %v, want the CHECK violation as ErrInsufficientBalance", err)
+	}
+	err = erc20.WithRetry(ctx, conn, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `UPDATE tokens SET total_supply = total_supply - 101 WHERE id = $1`, tokenID)
+		return err
+	})
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("negative supply error = %v, want the CHECK violation as ErrInsufficientBalance", err)
+	}
+	if got := balanceOf(t, conn, tokenID, owner); got != 100 {
+		t.Errorf("balance after rejected update = %d, want 100", got)
+	}
+	wantConserved(t, conn, tokenID)
+}
+
+func TestGetOrCreateAddressConcurrent(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	tokenID := newToken(t, conn, newAddress(t), 0)
+	owner := newAddress(t)
+
+	const workers = 20
+	ids := make([]uuid.UUID, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids[i], errs[i] = erc20.GetOrCreateAddress(ctx, conn, tokenID, owner)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range ids {
+		if errs[i] != nil {
+			t.Fatalf("GetOrCreateAddress: %v", errs[i])
+		}
+		if ids[i] != ids[0] {
+			t.Errorf("GetOrCreateAddress returned %s and %s for the same owner", ids[0], ids[i])
+		}
+	}
+	var rows int
+	err := conn.QueryRow(ctx, `SELECT count(*) FROM addresses WHERE token_id = $1 AND owner = $2`, tokenID, owner).Scan(&rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != 1 {
+		t.Errorf("%d address rows for one owner, want 1", rows)
+	}
+}
+
+func TestNormalizeSymbol(t *testing.T) {
+	for in, want := range map[string]string{"usdc": "USDC", " UsDc ": "USDC", "ETH": "ETH"} {
+		if got := erc20.NormalizeSymbol(in); got != want {
+			t.Errorf("NormalizeSymbol(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSymbolCaseInsensitive(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	bookID := erc20test.NewAccountBook(t, conn)
+	tokenID, err := erc20.Factory(ctx, conn, bookID, owner, "UsDc Coin", "UsDc", 6, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, symbol := range []string{"usdc", "USDC", "UsDc"} {
+		got, err := erc20.TokenIDBySymbol(ctx, conn, symbol)
+		if err != nil {
+			t.Fatalf("TokenIDBySymbol(%q): %v", symbol, err)
+		}
+		if got != tokenID {
+			t.Errorf("TokenIDBySymbol(%q) = %s, want %s", symbol, got, tokenID)
+		}
+	}
+	token, err := erc20.GetToken(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Name != "UsDc Coin" {
+		t.Errorf("name = %q, want the original casing", token.Name)
+	}
+
+	_, err = erc20.Factory(ctx, conn, bookID, owner, "Other", "usdc", 6, 0)
+	if err == nil {
+		t.Error("Factory accepted a symbol differing only in case")
+	}
+}
+
+func TestDeleteToken(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	bookID := erc20test.NewAccountBook(t, conn)
+	keptID, err := erc20.Factory(ctx, conn, bookID, owner, "Kept", "KEEP", 18, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goneID, err := erc20.Factory(ctx, conn, bookID, owner, "Gone", "GONE", 18, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.DeleteToken(ctx, conn, goneID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.GetToken(ctx, conn, goneID)
+	if !errors.Is(err, erc20.ErrTokenNotFound) {
+		t.Errorf("GetToken of a deleted token error = %v, want ErrTokenNotFound", err)
+	}
+	tokens, err := erc20.ListTokens(ctx, conn, bookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].ID != keptID {
+		t.Errorf("ListTokens = %v, want only the kept token", tokens)
+	}
+	tokens, err = erc20.ListTokensIncludingDeleted(ctx, conn, bookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 {
+		t.Errorf("ListTokensIncludingDeleted returned %d tokens, want 2", len(tokens))
+	}
+	for _, token := range tokens {
+		if token.ID == goneID && token.DeletedAt == nil {
+			t.Error("deleted token has no DeletedAt")
+		}
+	}
+
+	err = erc20.Mint(ctx, conn, goneID, owner, 1)
+	if !errors.Is(err, erc20.ErrTokenNotFound) {
+		t.Errorf("Mint of a deleted token error = %v, want ErrTokenNotFound", err)
+	}
+	if got := balanceOf(t, conn, goneID, owner); got != 100 {
+		t.Errorf("balance of a deleted token = %d, want its history kept at 100", got)
+	}
+}
+
+func TestMinSupply(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, spender := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	err := erc20.SetMinSupply(ctx, conn, tokenID, 900)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.Burn(ctx, conn, tokenID, owner, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.Approve(ctx, conn, tokenID, owner, spender, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.BurnFrom(ctx, conn, tokenID, spender, owner, 40)
+	if err != nil {
+		t.Fatalf("BurnFrom down to the floor: %v", err)
+	}
+	if got := totalSupply(t, conn, tokenID); got != 900 {
+		t.Fatalf("total supply = %d, want the floor of 900", got)
+	}
+
+	err = erc20.Burn(ctx, conn, tokenID, owner, 1)
+	if !errors.Is(err, erc20.ErrBelowMinSupply) {
+		t.Errorf("Burn past the floor error = %v, want ErrBelowMinSupply", err)
+	}
+	err = erc20.BurnFrom(ctx, conn, tokenID, spender, owner, 1)
+	if !errors.Is(err, erc20.ErrBelowMinSupply) {
+		t.Errorf("BurnFrom past the floor error = %v, want ErrBelowMinSupply", err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 900})
+	if got := allowance(t, conn, tokenID, owner, spender); got != 60 {
+		t.Errorf("allowance = %d, want 60, the rejected BurnFrom rolled back", got)
+	}
+	wantConserved(t, conn, tokenID)
+}
+
+func TestCirculatingSupply(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, treasury, holder := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	transfer(t, conn, tokenID, owner, treasury, 400)
+	transfer(t, conn, tokenID, owner, holder, 100)
+	// Sent to the zero address is treated as burned even though it is still in the total supply
+	transfer(t, conn, tokenID, owner, erc20.ZeroAddress, 50)
+
+	tests := []struct {
+		name    string
+		exclude []erc20.Address
+		want    int
+	}{
+		{"zero address only", nil, 950},
+		{"treasury", []erc20.Address{treasury}, 550},
+		{"treasury and owner", []erc20.Address{treasury, owner}, 100},
+	}
+	for _, tt := range tests {
+		got, err := erc20.CirculatingSupply(ctx, conn, tokenID, tt.exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("CirculatingSupply excluding %s = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+	if got := totalSupply(t, conn, tokenID); got != 1000 {
+		t.Errorf("total supply = %d, want 1000", got)
+	}
+
+	_, err := erc20.CirculatingSupply(ctx, conn, uuid.Must(uuid.NewV4()), nil)
+	if !errors.Is(err, erc20.ErrTokenNotFound) {
+		t.Errorf("CirculatingSupply of a missing token error = %v, want ErrTokenNotFound", err)
+	}
+}
+
+func TestTokenIDByName(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	bookID := erc20test.NewAccountBook(t, conn)
+	owner := newAddress(t)
+	gold, err := erc20.Factory(ctx, conn, bookID, owner, "Gold", "GLD", 18, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := erc20.TokenIDByName(ctx, conn, bookID, "Gold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != gold {
+		t.Errorf("TokenIDByName = %s, want %s", got, gold)
+	}
+	_, err = erc20.TokenIDByName(ctx, conn, bookID, "Silver")
+	if !errors.Is(err, erc20.ErrTokenNotFound) {
+		t.Errorf("TokenIDByName of a missing name error = %v, want ErrTokenNotFound", err)
+	}
+	_, err = erc20.Factory(ctx, conn, bookID, owner, "Gold", "GLD2", 18, 100)
+	if err == nil {
+		t.Error("Factory allowed a second token with the same name in the account book")
+	}
+
+	// Databases that have not run the unique names migration can still hold duplicates
+	_, err = conn.Exec(ctx, `DROP INDEX idx_tokens_book_name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.Factory(ctx, conn, bookID, owner, "Gold", "GLD2", 18, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.TokenIDByName(ctx, conn, bookID, "Gold")
+	if !errors.Is(err, erc20.ErrAmbiguousName) {
+		t.Errorf("TokenIDByName of a duplicated name error = %v, want ErrAmbiguousName", err)
+	}
+}
+
+func TestCreateAddress(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, account := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+
+	addressID, err := erc20.CreateAddress(ctx, conn, tokenID, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.CreateAddress(ctx, conn, tokenID, account)
+	if !errors.Is(err, erc20.ErrAddressExists) {
+		t.Errorf("second CreateAddress error = %v, want ErrAddressExists", err)
+	}
+	_, err = erc20.CreateAddress(ctx, conn, tokenID, owner)
+	if !errors.Is(err, erc20.ErrAddressExists) {
+		t.Errorf("CreateAddress for the funded owner error = %v, want ErrAddressExists", err)
+	}
+	got, err := erc20.GetOrCreateAddress(ctx, conn, tokenID, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != addressID {
+		t.Errorf("GetOrCreateAddress = %s, want the created %s", got, addressID)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 1000, account: 0})
+
+	// The same owner may have an address for another token
+	_, err = erc20.CreateAddress(ctx, conn, newToken(t, conn, owner, 0), account)
+	if err != nil {
+		t.Errorf("CreateAddress for another token: %v", err)
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	tests := []struct {
+		name, symbol string
+		decimals     int
+		err          error
+	}{
+		{"Gold", "GLD", 18, nil},
+		{"Gold", "GLD", 0, nil},
+		{"Gold", "GLD", erc20.MaxDecimals, nil},
+		{"Gold", "ABCDEFGHIJK", 18, nil},
+		{"Gold", "GLD", -1, erc20.ErrInvalidDecimals},
+		{"Gold", "GLD", erc20.MaxDecimals + 1, erc20.ErrInvalidDecimals},
+		{"", "GLD", 18, erc20.ErrInvalidName},
+		{"  ", "GLD", 18, erc20.ErrInvalidName},
+		{"Gold", "", 18, erc20.ErrInvalidSymbol},
+		{"Gold", " ", 18, erc20.ErrInvalidSymbol},
+		{"Gold", "ABCDEFGHIJKL", 18, erc20.ErrInvalidSymbol},
+	}
+	for _, tt := range tests {
+		err := erc20.ValidateToken(tt.name, tt.symbol, tt.decimals)
+		if tt.err == nil && err != nil {
+			t.Errorf("ValidateToken(%q, %q, %d): %v", tt.name, tt.symbol, tt.decimals, err)
+		}
+		if tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("ValidateToken(%q, %q, %d) error = %v, want %v", tt.name, tt.symbol, tt.decimals, err, tt.err)
+		}
+	}
+}
+
+func TestFactoryValidates(t *testing.T) {
+	_, err := erc20.Factory(ctx, nil, uuid.Nil, newAddress(t), "Gold", "GLD", -1, 100)
+	if !errors.Is(err, erc20.ErrInvalidDecimals) {
+		t.Errorf("Factory with negative decimals error = %v, want ErrInvalidDecimals", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Package erc20grpc serves the ledger over gRPC
+package erc20grpc
+
+//go:generate buf generate
+
+import (
+	"context"
+	"errors"
+
+	"erc20"
+	"erc20/erc20grpc/ledgerpb"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements ledgerpb.LedgerServer on top of the erc20 package
+type Server struct {
+	ledgerpb.UnimplementedLedgerServer
+	conn *pgxpool.Pool
+}
+
+// NewServer returns a ledger server backed by the given pool
+// Register it with ledgerpb.RegisterLedgerServer
+func NewServer(conn *pgxpool.Pool) *Server {
+	return &Server{conn: conn}
+}
+
+// CreateToken creates a new token in an account book
+func (s *Server) CreateToken(ctx context.Context, req *ledgerpb.CreateTokenRequest) (*ledgerpb.Token, error) {
+	accountBookID, err := parseID("account_book_id", req.AccountBookId)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := parseID("owner", req.Owner)
+	if err != nil {
+		return nil, err
+	}
+	tokenID, err := erc20.Factory(ctx, s.conn, accountBookID, erc20.Address(owner), req.Name, req.Symbol, int(req.Decimals), int(req.TotalSupply))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	token, err := erc20.GetToken(ctx, s.conn, tokenID)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return tokenMessage(token), nil
+}
+
+// GetToken retrieves a token by ID
+func (s *Server) GetToken(ctx context.Context, req *ledgerpb.GetTokenRequest) (*ledgerpb.Token, error) {
+	tokenID, err := parseID("id", req.Id)
+	if err != nil {
+		return nil, err
+	}
+	token, err := erc20.GetToken(ctx, s.conn, tokenID)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return tokenMessage(token), nil
+}
+
+// Transfer moves balance between addresses
+func (s *Server) Transfer(ctx context.Context, req *ledgerpb.TransferRequest) (*ledgerpb.TransferResponse, error) {
+	tokenID, err := parseID("token_id", req.TokenId)
+	if err != nil {
+		return nil, err
+	}
+	from, err := parseID("from", req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseID("to", req.To)
+	if err != nil {
+		return nil, err
+	}
+	_, err = erc20.Transfer(ctx, s.conn, tokenID, erc20.Address(from), erc20.Address(to), erc20.Amount(req.Amount))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &ledgerpb.TransferResponse{}, nil
+}
+
+// Mint creates new tokens on an address
+func (s *Server) Mint(ctx context.Context, req *ledgerpb.MintRequest) (*ledgerpb.MintResponse, error) {
+	tokenID, err := parseID("token_id", req.TokenId)
+	if err != nil {
+		return nil, err
+	}
+	account, err := parseID("account", req.Account)
+	if err != nil {
+		return nil, err
+	}
+	err = erc20.Mint(ctx, s.conn, tokenID, erc20.Address(account), erc20.Amount(req.Amount))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &ledgerpb.MintResponse{}, nil
+}
+
+// Burn destroys tokens held by an address
+func (s *Server) Burn(ctx context.Context, req *ledgerpb.BurnRequest) (*ledgerpb.BurnResponse, error) {
+	tokenID, err := parseID("token_id", req.TokenId)
+	if err != nil {
+		return nil, err
+	}
+	account, err := parseID("account", req.Account)
+	if err != nil {
+		return nil, err
+	}
+	err = erc20.Burn(ctx, s.conn, tokenID, erc20.Address(account), erc20.Amount(req.Amount))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &ledgerpb.BurnResponse{}, nil
+}
+
+// BalanceOf returns the balance of an address
+func (s *Server) BalanceOf(ctx context.Context, req *ledgerpb.BalanceOfRequest) (*ledgerpb.BalanceOfResponse, error) {
+	tokenID, err := parseID("token_id", req.TokenId)
+	if err != nil {
+		return nil, err
+	}
+	address, err := parseID("address", req.Address)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := erc20.BalanceOf(ctx, s.conn, tokenID, erc20.Address(address))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &ledgerpb.BalanceOfResponse{Balance: int64(balance)}, nil
+}
+
+func parseID(field string, id string) (uuid.UUID, error) {
+	parsed, err := uuid.FromString(id)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "invalid %s", field)
+	}
+	return parsed, nil
+}
+
+func tokenMessage(token *erc20.Token) *ledgerpb.Token {
+	return &ledgerpb.Token{
+		Id:            token.ID.String(),
+		AccountBookId: token.AccountBookID.String(),
+		Name:          token.Name,
+		Symbol:        token.Symbol,
+		Decimals:      int64(token.Decimals),
+		TotalSupply:   int64(token.TotalSupply),
+		Owner:         uuid.UUID(token.Owner).String(),
+	}
+}
+
+// statusError maps package errors onto gRPC status codes
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, erc20.ErrInsufficientBalance), errors.Is(err, erc20.ErrPaused),
+		errors.Is(err, erc20.ErrTransferTooLarge), errors.Is(err, erc20.ErrBelowMinSupply), errors.Is(err, erc20.ErrMintCapExceeded),
+		errors.Is(err, erc20.ErrSpendLimitExceeded), errors.Is(err, erc20.ErrAddressNotFound):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, erc20.ErrNotOwner), errors.Is(err, erc20.ErrUnauthorized):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, erc20.ErrTokenNotFound), errors.Is(err, erc20.ErrAccountBookNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, erc20.ErrInvalidAmount), errors.Is(err, erc20.ErrInvalidDecimals),
+		errors.Is(err, erc20.ErrInvalidName), errors.Is(err, erc20.ErrInvalidSymbol):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,115 @@
+package erc20grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"erc20/erc20grpc"
+	"erc20/erc20grpc/ledgerpb"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newClient serves a ledger on an in-process connection and returns a client for it with a fresh account book
+func newClient(t *testing.T) (ledgerpb.LedgerClient, string) {
+	t.Helper()
+	pool := erc20test.NewTestDB(t)
+	book := erc20test.NewAccountBook(t, pool)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	ledgerpb.RegisterLedgerServer(srv, erc20grpc.NewServer(pool))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return ledgerpb.NewLedgerClient(conn), book.String()
+}
+
+func newID(t *testing.T) string {
+	t.Helper()
+	id, err := uuid.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id.String()
+}
+
+func TestCreateThenTransfer(t *testing.T) {
+	client, book := newClient(t)
+	ctx := context.Background()
+	owner, alice, bob := newID(t), newID(t), newID(t)
+
+	token, err := client.CreateToken(ctx, &ledgerpb.CreateTokenRequest{
+		AccountBookId: book,
+		Owner:         owner,
+		Name:          "Test Dollar",
+		Symbol:        "tusd",
+		Decimals:      2,
+	})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	if token.AccountBookId != book || token.Symbol != "TUSD" || token.Owner != owner {
+		t.Fatalf("created token = %+v", token)
+	}
+
+	_, err = client.Mint(ctx, &ledgerpb.MintRequest{TokenId: token.Id, Account: alice, Amount: 100})
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	_, err = client.Transfer(ctx, &ledgerpb.TransferRequest{TokenId: token.Id, From: alice, To: bob, Amount: 30})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	for account, want := range map[string]int64{alice: 70, bob: 30} {
+		res, err := client.BalanceOf(ctx, &ledgerpb.BalanceOfRequest{TokenId: token.Id, Address: account})
+		if err != nil {
+			t.Fatalf("BalanceOf: %v", err)
+		}
+		if res.Balance != want {
+			t.Errorf("balance of %s = %d, want %d", account, res.Balance, want)
+		}
+	}
+
+	_, err = client.Transfer(ctx, &ledgerpb.TransferRequest{TokenId: token.Id, From: bob, To: alice, Amount: 31})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("over transfer = %v, want %s", err, codes.FailedPrecondition)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	client, book := newClient(t)
+	ctx := context.Background()
+
+	_, err := client.GetToken(ctx, &ledgerpb.GetTokenRequest{Id: newID(t)})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetToken of unknown token = %v, want %s", err, codes.NotFound)
+	}
+	_, err = client.CreateToken(ctx, &ledgerpb.CreateTokenRequest{AccountBookId: newID(t), Owner: newID(t), Name: "Orphan", Symbol: "ORP"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("CreateToken in unknown book = %v, want %s", err, codes.NotFound)
+	}
+	_, err = client.CreateToken(ctx, &ledgerpb.CreateTokenRequest{AccountBookId: book, Owner: newID(t), Name: "Bad", Symbol: "BAD", Decimals: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateToken with negative decimals = %v, want %s", err, codes.InvalidArgument)
+	}
+	_, err = client.CreateToken(ctx, &ledgerpb.CreateTokenRequest{AccountBookId: "nope", Owner: newID(t), Name: "Bad", Symbol: "BAD"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateToken with malformed book = %v, want %s", err, codes.InvalidArgument)
+	}
+}
@@ -0,0 +1,34 @@
+package erc20grpc
+
+import (
+	"testing"
+
+	"erc20"
+
+	"github.com/ninja-software/terror/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{erc20.ErrTransferTooLarge, codes.FailedPrecondition},
+		{erc20.ErrBelowMinSupply, codes.FailedPrecondition},
+		{erc20.ErrMintCapExceeded, codes.FailedPrecondition},
+		{erc20.ErrSpendLimitExceeded, codes.FailedPrecondition},
+		{erc20.ErrAddressNotFound, codes.FailedPrecondition},
+		{erc20.ErrInsufficientBalance, codes.FailedPrecondition},
+		{erc20.ErrPaused, codes.FailedPrecondition},
+		{erc20.ErrTokenNotFound, codes.NotFound},
+		{erc20.ErrNotOwner, codes.PermissionDenied},
+		{erc20.ErrUnauthorized, codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		if got := status.Code(statusError(terror.Error(tt.err, "wrapped"))); got != tt.want {
+			t.Errorf("statusError(%v) code = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
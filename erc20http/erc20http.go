@@ -0,0 +1,244 @@
+// Package erc20http exposes the ledger as a JSON REST API
+package erc20http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"erc20"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// CreateTokenRequest is the body of POST /tokens
+type CreateTokenRequest struct {
+	AccountBookID uuid.UUID `json:"account_book_id"`
+	Owner         uuid.UUID `json:"owner"`
+	Name          string    `json:"name"`
+	Symbol        string    `json:"symbol"`
+	Decimals      int       `json:"decimals"`
+	TotalSupply   int       `json:"total_supply"`
+}
+
+// TransferRequest is the body of POST /tokens/{id}/transfer
+type TransferRequest struct {
+	From   uuid.UUID `json:"from"`
+	To     uuid.UUID `json:"to"`
+	Amount int       `json:"amount"`
+}
+
+// MintRequest is the body of POST /tokens/{id}/mint
+type MintRequest struct {
+	Account uuid.UUID `json:"account"`
+	Amount  int       `json:"amount"`
+}
+
+// TokenResponse is a token as returned by the API
+type TokenResponse struct {
+	ID            uuid.UUID `json:"id"`
+	AccountBookID uuid.UUID `json:"account_book_id"`
+	Name          string    `json:"name"`
+	Symbol        string    `json:"symbol"`
+	Decimals      int       `json:"decimals"`
+	TotalSupply   int       `json:"total_supply"`
+	Owner         uuid.UUID `json:"owner"`
+}
+
+// BalanceResponse is the body returned by GET /addresses/{id}/balance
+type BalanceResponse struct {
+	Address uuid.UUID `json:"address"`
+	TokenID uuid.UUID `json:"token_id"`
+	Balance int       `json:"balance"`
+}
+
+// ErrorResponse is returned for every failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+var errNotFound = errors.New("not found")
+var errBadRequest = errors.New("bad request")
+
+// Handler serves the ledger routes
+type Handler struct {
+	conn *pgxpool.Pool
+}
+
+// New returns a handler serving the ledger on the given pool
+//
+//	POST /tokens
+//	GET  /tokens/{id}
+//	POST /tokens/{id}/transfer
+//	POST /tokens/{id}/mint
+//	GET  /addresses/{id}/balance?token_id={token_id}
+func New(conn *pgxpool.Pool) *Handler {
+	return &Handler{conn: conn}
+}
+
+// RequestIDHeader carries a correlation ID that is attached to every log line for the request
+const RequestIDHeader = "X-Request-ID"
+
+// ServeHTTP routes a request to its handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if id := r.Header.Get(RequestIDHeader); id != "" {
+		r = r.WithContext(erc20.WithRequestID(r.Context(), id))
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "tokens" && r.Method == http.MethodPost:
+		h.createToken(w, r)
+	case len(parts) == 2 && parts[0] == "tokens" && r.Method == http.MethodGet:
+		h.getToken(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "tokens" && parts[2] == "transfer" && r.Method == http.MethodPost:
+		h.transfer(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "tokens" && parts[2] == "mint" && r.Method == http.MethodPost:
+		h.mint(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "addresses" && parts[2] == "balance" && r.Method == http.MethodGet:
+		h.balance(w, r, parts[1])
+	default:
+		writeError(w, errNotFound)
+	}
+}
+
+func (h *Handler) createToken(w http.ResponseWriter, r *http.Request) {
+	req := &CreateTokenRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		writeError(w, errBadRequest)
+		return
+	}
+	tokenID, err := erc20.Factory(r.Context(), h.conn, req.AccountBookID, erc20.Address(req.Owner), req.Name, req.Symbol, req.Decimals, req.TotalSupply)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	token, err := erc20.GetToken(r.Context(), h.conn, tokenID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, tokenResponse(token))
+}
+
+func (h *Handler) getToken(w http.ResponseWriter, r *http.Request, id string) {
+	tokenID, err := uuid.FromString(id)
+	if err != nil {
+		writeError(w, errNotFound)
+		return
+	}
+	token, err := erc20.GetToken(r.Context(), h.conn, tokenID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tokenResponse(token))
+}
+
+func (h *Handler) transfer(w http.ResponseWriter, r *http.Request, id string) {
+	tokenID, err := uuid.FromString(id)
+	if err != nil {
+		writeError(w, errNotFound)
+		return
+	}
+	req := &TransferRequest{}
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		writeError(w, errBadRequest)
+		return
+	}
+	_, err = erc20.Transfer(r.Context(), h.conn, tokenID, erc20.Address(req.From), erc20.Address(req.To), erc20.Amount(req.Amount))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) mint(w http.ResponseWriter, r *http.Request, id string) {
+	tokenID, err := uuid.FromString(id)
+	if err != nil {
+		writeError(w, errNotFound)
+		return
+	}
+	req := &MintRequest{}
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		writeError(w, errBadRequest)
+		return
+	}
+	err = erc20.Mint(r.Context(), h.conn, tokenID, erc20.Address(req.Account), erc20.Amount(req.Amount))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) balance(w http.ResponseWriter, r *http.Request, id string) {
+	address, err := uuid.FromString(id)
+	if err != nil {
+		writeError(w, errNotFound)
+		return
+	}
+	tokenID, err := uuid.FromString(r.URL.Query().Get("token_id"))
+	if err != nil {
+		writeError(w, errBadRequest)
+		return
+	}
+	balance, err := erc20.BalanceOf(r.Context(), h.conn, tokenID, erc20.Address(address))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &BalanceResponse{Address: address, TokenID: tokenID, Balance: balance.Int()})
+}
+
+func tokenResponse(token *erc20.Token) *TokenResponse {
+	return &TokenResponse{
+		ID:            token.ID,
+		AccountBookID: token.AccountBookID,
+		Name:          token.Name,
+		Symbol:        token.Symbol,
+		Decimals:      token.Decimals,
+		TotalSupply:   token.TotalSupply,
+		Owner:         uuid.UUID(token.Owner),
+	}
+}
+
+// statusCode maps package errors onto HTTP status codes
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, errNotFound), errors.Is(err, erc20.ErrTokenNotFound), errors.Is(err, erc20.ErrAccountBookNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errBadRequest), errors.Is(err, erc20.ErrInsufficientBalance), errors.Is(err, erc20.ErrInvalidAmount),
+		errors.Is(err, erc20.ErrInvalidDecimals), errors.Is(err, erc20.ErrInvalidName), errors.Is(err, erc20.ErrInvalidSymbol):
+		return http.StatusBadRequest
+	case errors.Is(err, erc20.ErrNotOwner), errors.Is(err, erc20.ErrUnauthorized):
+		return http.StatusForbidden
+	case errors.Is(err, erc20.ErrPaused):
+		return http.StatusConflict
+	case errors.Is(err, erc20.ErrTransferTooLarge), errors.Is(err, erc20.ErrBelowMinSupply), errors.Is(err, erc20.ErrMintCapExceeded),
+		errors.Is(err, erc20.ErrSpendLimitExceeded), errors.Is(err, erc20.ErrAddressNotFound):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := statusCode(err)
+	msg := err.Error()
+	if code == http.StatusInternalServerError {
+		msg = http.StatusText(code)
+	}
+	writeJSON(w, code, &ErrorResponse{Error: msg})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,160 @@
+package erc20http_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"erc20/erc20http"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func newServer(t *testing.T) (*httptest.Server, uuid.UUID) {
+	t.Helper()
+	pool := erc20test.NewTestDB(t)
+	srv := httptest.NewServer(erc20http.New(pool))
+	t.Cleanup(srv.Close)
+	return srv, erc20test.NewAccountBook(t, pool)
+}
+
+// do sends body as JSON and decodes the response into out when it is not nil, returning the status code
+func do(t *testing.T, method string, url string, body interface{}, out interface{}) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&buf).Encode(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if out != nil {
+		err = json.NewDecoder(res.Body).Decode(out)
+		if err != nil {
+			t.Fatalf("%s %s: decode response: %v", method, url, err)
+		}
+	}
+	return res.StatusCode
+}
+
+func newID(t *testing.T) uuid.UUID {
+	t.Helper()
+	id, err := uuid.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestCreateTransferRoundTrip(t *testing.T) {
+	srv, book := newServer(t)
+	owner, alice, bob := newID(t), newID(t), newID(t)
+
+	token := &erc20http.TokenResponse{}
+	code := do(t, http.MethodPost, srv.URL+"/tokens", &erc20http.CreateTokenRequest{
+		AccountBookID: book,
+		Owner:         owner,
+		Name:          "Test Dollar",
+		Symbol:        "tusd",
+		Decimals:      2,
+		TotalSupply:   0,
+	}, token)
+	if code != http.StatusCreated {
+		t.Fatalf("POST /tokens = %d, want %d", code, http.StatusCreated)
+	}
+	if token.ID == uuid.Nil || token.AccountBookID != book || token.Symbol != "TUSD" || token.Owner != owner {
+		t.Fatalf("created token = %+v", token)
+	}
+
+	got := &erc20http.TokenResponse{}
+	code = do(t, http.MethodGet, srv.URL+"/tokens/"+token.ID.String(), nil, got)
+	if code != http.StatusOK || got.ID != token.ID {
+		t.Fatalf("GET /tokens/{id} = %d %+v", code, got)
+	}
+
+	code = do(t, http.MethodPost, srv.URL+"/tokens/"+token.ID.String()+"/mint", &erc20http.MintRequest{Account: alice, Amount: 100}, nil)
+	if code != http.StatusNoContent {
+		t.Fatalf("mint = %d, want %d", code, http.StatusNoContent)
+	}
+	code = do(t, http.MethodPost, srv.URL+"/tokens/"+token.ID.String()+"/transfer", &erc20http.TransferRequest{From: alice, To: bob, Amount: 40}, nil)
+	if code != http.StatusNoContent {
+		t.Fatalf("transfer = %d, want %d", code, http.StatusNoContent)
+	}
+
+	for owner, want := range map[uuid.UUID]int{alice: 60, bob: 40} {
+		balance := &erc20http.BalanceResponse{}
+		code = do(t, http.MethodGet, fmt.Sprintf("%s/addresses/%s/balance?token_id=%s", srv.URL, owner, token.ID), nil, balance)
+		if code != http.StatusOK || balance.Balance != want {
+			t.Errorf("balance of %s = %d %d, want %d", owner, code, balance.Balance, want)
+		}
+	}
+}
+
+func TestOverTransferIsBadRequest(t *testing.T) {
+	srv, book := newServer(t)
+	owner, alice, bob := newID(t), newID(t), newID(t)
+
+	token := &erc20http.TokenResponse{}
+	do(t, http.MethodPost, srv.URL+"/tokens", &erc20http.CreateTokenRequest{AccountBookID: book, Owner: owner, Name: "Test", Symbol: "TST", Decimals: 0}, token)
+	do(t, http.MethodPost, srv.URL+"/tokens/"+token.ID.String()+"/mint", &erc20http.MintRequest{Account: alice, Amount: 10}, nil)
+
+	res := &erc20http.ErrorResponse{}
+	code := do(t, http.MethodPost, srv.URL+"/tokens/"+token.ID.String()+"/transfer", &erc20http.TransferRequest{From: alice, To: bob, Amount: 11}, res)
+	if code != http.StatusBadRequest {
+		t.Fatalf("over transfer = %d, want %d", code, http.StatusBadRequest)
+	}
+	if res.Error == "" {
+		t.Error("over transfer returned no error message")
+	}
+}
+
+func TestCreateSameSymbolInTwoBooks(t *testing.T) {
+	pool := erc20test.NewTestDB(t)
+	srv := httptest.NewServer(erc20http.New(pool))
+	defer srv.Close()
+	owner := newID(t)
+
+	ids := map[uuid.UUID]bool{}
+	for i := 0; i < 2; i++ {
+		token := &erc20http.TokenResponse{}
+		code := do(t, http.MethodPost, srv.URL+"/tokens", &erc20http.CreateTokenRequest{
+			AccountBookID: erc20test.NewAccountBook(t, pool),
+			Owner:         owner,
+			Name:          "Shared",
+			Symbol:        "SHR",
+		}, token)
+		if code != http.StatusCreated {
+			t.Fatalf("POST /tokens in book %d = %d, want %d", i, code, http.StatusCreated)
+		}
+		ids[token.ID] = true
+	}
+	if len(ids) != 2 {
+		t.Errorf("created tokens %v, want two distinct IDs", ids)
+	}
+}
+
+func TestCreateInMissingBookIsNotFound(t *testing.T) {
+	srv, _ := newServer(t)
+	code := do(t, http.MethodPost, srv.URL+"/tokens", &erc20http.CreateTokenRequest{
+		AccountBookID: newID(t),
+		Owner:         newID(t),
+		Name:          "Orphan",
+		Symbol:        "ORP",
+	}, nil)
+	if code != http.StatusNotFound {
+		t.Errorf("POST /tokens with unknown book = %d, want %d", code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,34 @@
+package erc20http
+
+import (
+	"net/http"
+	"testing"
+
+	"erc20"
+
+	"github.com/ninja-software/terror/v2"
+)
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{erc20.ErrTransferTooLarge, http.StatusUnprocessableEntity},
+		{erc20.ErrBelowMinSupply, http.StatusUnprocessableEntity},
+		{erc20.ErrMintCapExceeded, http.StatusUnprocessableEntity},
+		{erc20.ErrSpendLimitExceeded, http.StatusUnprocessableEntity},
+		{erc20.ErrAddressNotFound, http.StatusUnprocessableEntity},
+		{erc20.ErrInsufficientBalance, http.StatusBadRequest},
+		{erc20.ErrTokenNotFound, http.StatusNotFound},
+		{erc20.ErrNotOwner, http.StatusForbidden},
+		{erc20.ErrUnauthorized, http.StatusForbidden},
+		{erc20.ErrPaused, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		// Package functions wrap their errors, the mapping must see through that
+		if got := statusCode(terror.Error(tt.err, "wrapped")); got != tt.want {
+			t.Errorf("statusCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
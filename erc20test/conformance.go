@@ -0,0 +1,295 @@
+package erc20test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"erc20"
+
+	"github.com/gofrs/uuid"
+)
+
+// Backend returns a fresh, empty erc20.DB and an account book to create tokens in
+type Backend func(t *testing.T) (erc20.DB, uuid.UUID)
+
+// RunConformance runs the same scenarios against a backend, so every erc20.DB behaves alike
+// memstore runs it on every test, the postgres Client behind the integration build tag
+func RunConformance(t *testing.T, newDB Backend) {
+	for _, scenario := range []struct {
+		name string
+		run  func(t *testing.T, c *conformance)
+	}{
+		{"FactoryMintsInitialSupply", testFactory},
+		{"SymbolLookup", testSymbolLookup},
+		{"Transfer", testTransfer},
+		{"MintAndBurn", testMintAndBurn},
+		{"Allowances", testAllowances},
+		{"TransferManyAllOrNothing", testTransferMany},
+		{"Paused", testPaused},
+		{"Deleted", testDeleted},
+		{"UnknownToken", testUnknownToken},
+	} {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			db, book := newDB(t)
+			scenario.run(t, &conformance{t: t, db: db, book: book, ctx: context.Background()})
+		})
+	}
+}
+
+// conformance wraps a backend with helpers that fail the test on unexpected errors
+type conformance struct {
+	t    *testing.T
+	db   erc20.DB
+	book uuid.UUID
+	ctx  context.Context
+}
+
+func (c *conformance) address() erc20.Address {
+	c.t.Helper()
+	id, err := uuid.NewV4()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return erc20.Address(id)
+}
+
+func (c *conformance) token(owner erc20.Address, symbol string, supply int) uuid.UUID {
+	c.t.Helper()
+	tokenID, err := c.db.Factory(c.ctx, c.book, owner, "Conformance "+symbol, symbol, 18, supply)
+	if err != nil {
+		c.t.Fatalf("Factory %s: %v", symbol, err)
+	}
+	return tokenID
+}
+
+func (c *conformance) wantBalance(tokenID uuid.UUID, owner erc20.Address, want int) {
+	c.t.Helper()
+	got, err := c.db.BalanceOf(c.ctx, tokenID, owner)
+	if err != nil {
+		c.t.Fatalf("BalanceOf: %v", err)
+	}
+	if got.Int() != want {
+		c.t.Errorf("balance = %d, want %d", got, want)
+	}
+}
+
+func (c *conformance) wantSupply(tokenID uuid.UUID, want int) {
+	c.t.Helper()
+	got, err := c.db.TotalSupply(c.ctx, tokenID)
+	if err != nil {
+		c.t.Fatalf("TotalSupply: %v", err)
+	}
+	if got.Int() != want {
+		c.t.Errorf("total supply = %d, want %d", got, want)
+	}
+}
+
+func (c *conformance) wantErr(op string, err error, want error) {
+	c.t.Helper()
+	if !errors.Is(err, want) {
+		c.t.Errorf("%s = %v, want %v", op, err, want)
+	}
+}
+
+func testFactory(t *testing.T, c *conformance) {
+	owner := c.address()
+	tokenID := c.token(owner, "INIT", 1000)
+	c.wantBalance(tokenID, owner, 1000)
+	c.wantSupply(tokenID, 1000)
+
+	_, err := c.db.Factory(c.ctx, c.book, owner, "Bad", "BAD", -1, 0)
+	c.wantErr("Factory with negative decimals", err, erc20.ErrInvalidDecimals)
+	_, err = c.db.Factory(c.ctx, c.book, owner, "Bad", "", 18, 0)
+	c.wantErr("Factory with empty symbol", err, erc20.ErrInvalidSymbol)
+	_, err = c.db.Factory(c.ctx, c.book, owner, "Again", "init", 18, 0)
+	if err == nil {
+		t.Error("Factory with a symbol taken in the account book succeeded")
+	}
+}
+
+func testSymbolLookup(t *testing.T, c *conformance) {
+	tokenID := c.token(c.address(), "UsDc", 0)
+	for _, symbol := range []string{"usdc", "USDC", " UsDc "} {
+		found, err := c.db.TokenIDBySymbol(c.ctx, symbol)
+		if err != nil {
+			t.Fatalf("TokenIDBySymbol(%q): %v", symbol, err)
+		}
+		if found != tokenID {
+			t.Errorf("TokenIDBySymbol(%q) = %s, want %s", symbol, found, tokenID)
+		}
+	}
+	_, err := c.db.TokenIDBySymbol(c.ctx, "NONE")
+	c.wantErr("TokenIDBySymbol of unknown symbol", err, erc20.ErrTokenNotFound)
+}
+
+func testTransfer(t *testing.T, c *conformance) {
+	alice, bob := c.address(), c.address()
+	tokenID := c.token(alice, "XFER", 100)
+
+	ok, err := c.db.Transfer(c.ctx, tokenID, alice, bob, 40)
+	if err != nil || !ok {
+		t.Fatalf("Transfer = %v, %v", ok, err)
+	}
+	c.wantBalance(tokenID, alice, 60)
+	c.wantBalance(tokenID, bob, 40)
+
+	_, err = c.db.Transfer(c.ctx, tokenID, bob, alice, 41)
+	c.wantErr("over transfer", err, erc20.ErrInsufficientBalance)
+	_, err = c.db.Transfer(c.ctx, tokenID, alice, bob, -1)
+	c.wantErr("negative transfer", err, erc20.ErrInvalidAmount)
+	c.wantBalance(tokenID, alice, 60)
+	c.wantBalance(tokenID, bob, 40)
+	c.wantSupply(tokenID, 100)
+}
+
+func testMintAndBurn(t *testing.T, c *conformance) {
+	alice := c.address()
+	tokenID := c.token(c.address(), "MINT", 0)
+
+	err := c.db.Mint(c.ctx, tokenID, alice, 50)
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	err = c.db.Burn(c.ctx, tokenID, alice, 20)
+	if err != nil {
+		t.Fatalf("Burn: %v", err)
+	}
+	c.wantBalance(tokenID, alice, 30)
+	c.wantSupply(tokenID, 30)
+
+	c.wantErr("negative mint", c.db.Mint(c.ctx, tokenID, alice, -1), erc20.ErrInvalidAmount)
+	c.wantErr("negative burn", c.db.Burn(c.ctx, tokenID, alice, -1), erc20.ErrInvalidAmount)
+	c.wantErr("over burn", c.db.Burn(c.ctx, tokenID, alice, 31), erc20.ErrInsufficientBalance)
+	c.wantBalance(tokenID, alice, 30)
+	c.wantSupply(tokenID, 30)
+}
+
+func testAllowances(t *testing.T, c *conformance) {
+	owner, spender, recipient := c.address(), c.address(), c.address()
+	tokenID := c.token(owner, "ALLOW", 100)
+
+	err := c.db.Approve(c.ctx, tokenID, owner, spender, 50)
+	if err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	_, err = c.db.TransferFrom(c.ctx, tokenID, spender, owner, recipient, 30)
+	if err != nil {
+		t.Fatalf("TransferFrom: %v", err)
+	}
+	allowance, err := c.db.Allowance(c.ctx, tokenID, owner, spender)
+	if err != nil {
+		t.Fatalf("Allowance: %v", err)
+	}
+	if allowance != 20 {
+		t.Errorf("allowance = %d, want 20", allowance)
+	}
+	c.wantBalance(tokenID, recipient, 30)
+
+	_, err = c.db.TransferFrom(c.ctx, tokenID, spender, owner, recipient, 21)
+	c.wantErr("TransferFrom over allowance", err, erc20.ErrInsufficientAllowance)
+	c.wantErr("negative Approve", c.db.Approve(c.ctx, tokenID, owner, spender, -1), erc20.ErrInvalidAmount)
+
+	err = c.db.Approve(c.ctx, tokenID, owner, spender, 1000)
+	if err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	_, err = c.db.TransferFrom(c.ctx, tokenID, spender, owner, recipient, 71)
+	c.wantErr("TransferFrom over balance", err, erc20.ErrInsufficientBalance)
+	allowance, err = c.db.Allowance(c.ctx, tokenID, owner, spender)
+	if err != nil {
+		t.Fatalf("Allowance: %v", err)
+	}
+	if allowance != 1000 {
+		t.Errorf("allowance after failed TransferFrom = %d, want 1000", allowance)
+	}
+	c.wantBalance(tokenID, owner, 70)
+}
+
+func testTransferMany(t *testing.T, c *conformance) {
+	alice, bob, carol := c.address(), c.address(), c.address()
+	tokenID := c.token(alice, "MANY", 100)
+
+	err := c.db.TransferMany(c.ctx, tokenID, []erc20.TransferLeg{
+		{From: alice, To: bob, Amount: 30},
+		{From: bob, To: carol, Amount: 10},
+		{From: carol, To: alice, Amount: 11},
+	})
+	c.wantErr("TransferMany with an overdrawn leg", err, erc20.ErrInsufficientBalance)
+	c.wantBalance(tokenID, alice, 100)
+	c.wantBalance(tokenID, bob, 0)
+	c.wantBalance(tokenID, carol, 0)
+
+	err = c.db.TransferMany(c.ctx, tokenID, []erc20.TransferLeg{
+		{From: alice, To: bob, Amount: 30},
+		{From: bob, To: carol, Amount: 10},
+		{From: carol, To: alice, Amount: 5},
+	})
+	if err != nil {
+		t.Fatalf("TransferMany: %v", err)
+	}
+	c.wantBalance(tokenID, alice, 75)
+	c.wantBalance(tokenID, bob, 20)
+	c.wantBalance(tokenID, carol, 5)
+}
+
+func testPaused(t *testing.T, c *conformance) {
+	alice, bob := c.address(), c.address()
+	tokenID := c.token(alice, "PAUSE", 100)
+
+	err := c.db.Pause(c.ctx, tokenID)
+	if err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	_, err = c.db.Transfer(c.ctx, tokenID, alice, bob, 1)
+	c.wantErr("Transfer while paused", err, erc20.ErrPaused)
+	c.wantErr("TransferMany while paused", c.db.TransferMany(c.ctx, tokenID, []erc20.TransferLeg{{From: alice, To: bob, Amount: 1}}), erc20.ErrPaused)
+	c.wantErr("Mint while paused", c.db.Mint(c.ctx, tokenID, alice, 1), erc20.ErrPaused)
+	c.wantErr("Burn while paused", c.db.Burn(c.ctx, tokenID, alice, 1), erc20.ErrPaused)
+	c.wantBalance(tokenID, alice, 100)
+
+	err = c.db.Unpause(c.ctx, tokenID)
+	if err != nil {
+		t.Fatalf("Unpause: %v", err)
+	}
+	_, err = c.db.Transfer(c.ctx, tokenID, alice, bob, 1)
+	if err != nil {
+		t.Errorf("Transfer after Unpause: %v", err)
+	}
+}
+
+func testDeleted(t *testing.T, c *conformance) {
+	alice, bob := c.address(), c.address()
+	tokenID := c.token(alice, "GONE", 100)
+
+	err := c.db.DeleteToken(c.ctx, tokenID)
+	if err != nil {
+		t.Fatalf("DeleteToken: %v", err)
+	}
+	_, err = c.db.Transfer(c.ctx, tokenID, alice, bob, 1)
+	c.wantErr("Transfer of a deleted token", err, erc20.ErrTokenNotFound)
+	c.wantErr("Mint of a deleted token", c.db.Mint(c.ctx, tokenID, alice, 1), erc20.ErrTokenNotFound)
+	c.wantErr("Burn of a deleted token", c.db.Burn(c.ctx, tokenID, alice, 1), erc20.ErrTokenNotFound)
+	c.wantErr("Pause of a deleted token", c.db.Pause(c.ctx, tokenID), erc20.ErrTokenNotFound)
+	c.wantErr("DeleteToken twice", c.db.DeleteToken(c.ctx, tokenID), erc20.ErrTokenNotFound)
+	_, err = c.db.TokenIDBySymbol(c.ctx, "GONE")
+	c.wantErr("TokenIDBySymbol of a deleted token", err, erc20.ErrTokenNotFound)
+	c.wantBalance(tokenID, alice, 100)
+}
+
+func testUnknownToken(t *testing.T, c *conformance) {
+	alice := c.address()
+	tokenID, err := uuid.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.db.Transfer(c.ctx, tokenID, alice, c.address(), 0)
+	c.wantErr("Transfer of an unknown token", err, erc20.ErrTokenNotFound)
+	c.wantErr("Mint of an unknown token", c.db.Mint(c.ctx, tokenID, alice, 1), erc20.ErrTokenNotFound)
+	c.wantErr("Burn of an unknown token", c.db.Burn(c.ctx, tokenID, alice, 0), erc20.ErrTokenNotFound)
+	_, err = c.db.TotalSupply(c.ctx, tokenID)
+	c.wantErr("TotalSupply of an unknown token", err, erc20.ErrTokenNotFound)
+	_, err = c.db.BalanceOf(c.ctx, tokenID, alice)
+	c.wantErr("BalanceOf an unknown token", err, erc20.ErrTokenNotFound)
+}
@@ -0,0 +1,93 @@
+// Package erc20test provides a throwaway, migrated Postgres database for tests of code built on erc20
+package erc20test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"erc20"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// DatabaseURLEnv names the environment variable holding a connection string for a Postgres server
+// The role must be allowed to create databases. Tests are skipped when it is unset
+const DatabaseURLEnv = "ERC20_TEST_DATABASE_URL"
+
+// NewTestDB creates a fresh database on the server in DatabaseURLEnv, runs Migrate on it and returns a pool
+// Each call gets its own database, named with a _test suffix, so tests can run in parallel.
+// The pool is closed and the database dropped when the test ends
+func NewTestDB(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv(DatabaseURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", DatabaseURLEnv)
+	}
+	ctx := context.Background()
+	id, err := uuid.NewV4()
+	if err != nil {
+		t.Fatalf("generate database name: %v", err)
+	}
+	name := pgx.Identifier{"erc20_" + strings.ReplaceAll(id.String(), "-", "") + "_test"}.Sanitize()
+
+	admin, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatalf("connect to %s: %v", DatabaseURLEnv, err)
+	}
+	_, err = admin.Exec(ctx, "CREATE DATABASE "+name)
+	admin.Close(ctx)
+	if err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("parse %s: %v", DatabaseURLEnv, err)
+	}
+	cfg.ConnConfig.Database = strings.Trim(name, `"`)
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	if err != nil {
+		dropDatabase(t, url, name)
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+		dropDatabase(t, url, name)
+	})
+	err = erc20.Migrate(ctx, pool)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return pool
+}
+
+// NewAccountBook inserts an empty account book into a database from NewTestDB and returns its ID
+func NewAccountBook(t testing.TB, conn *pgxpool.Pool) uuid.UUID {
+	t.Helper()
+	var id uuid.UUID
+	err := conn.QueryRow(context.Background(), `INSERT INTO account_books DEFAULT VALUES RETURNING id`).Scan(&id)
+	if err != nil {
+		t.Fatalf("create account book: %v", err)
+	}
+	return id
+}
+
+// dropDatabase removes a database created by NewTestDB, reporting rather than failing on error
+func dropDatabase(t testing.TB, url string, name string) {
+	ctx := context.Background()
+	admin, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Logf("drop %s: %v", name, err)
+		return
+	}
+	defer admin.Close(ctx)
+	_, err = admin.Exec(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", name))
+	if err != nil {
+		t.Logf("drop %s: %v", name, err)
+	}
+}
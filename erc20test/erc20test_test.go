@@ -0,0 +1,58 @@
+package erc20test_test
+
+import (
+	"context"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestNewTestDB(t *testing.T) {
+	ctx := context.Background()
+	conn := erc20test.NewTestDB(t)
+	version, err := erc20.CurrentVersion(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := erc20.Migrations[len(erc20.Migrations)-1].Version; version != want {
+		t.Errorf("CurrentVersion = %d, want %d", version, want)
+	}
+	owner, err := uuid.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenID, err := erc20.Factory(ctx, conn, erc20test.NewAccountBook(t, conn), erc20.Address(owner), "Smoke", "SMK", 18, 1000)
+	if err != nil {
+		t.Fatalf("Factory: %v", err)
+	}
+	supply, err := erc20.TotalSupply(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if supply != 1000 {
+		t.Errorf("TotalSupply = %d, want 1000", supply)
+	}
+}
+
+func TestNewTestDBIsolated(t *testing.T) {
+	ctx := context.Background()
+	first := erc20test.NewTestDB(t)
+	second := erc20test.NewTestDB(t)
+	owner, err := uuid.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = erc20.Factory(ctx, first, erc20test.NewAccountBook(t, first), erc20.Address(owner), "Smoke", "SMK", 18, 0)
+	if err != nil {
+		t.Fatalf("Factory: %v", err)
+	}
+	_, err = erc20.TokenIDBySymbol(ctx, second, "SMK")
+	if err == nil {
+		t.Error("a token created in one test database is visible in another")
+	}
+}
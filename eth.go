@@ -0,0 +1,69 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrInvalidEthAddress is returned when a string is not a 0x prefixed 40 hex digit Ethereum address
+var ErrInvalidEthAddress = errors.New("ERC20: invalid ethereum address")
+
+// ErrAddressNotFound is returned when no address matches a lookup
+var ErrAddressNotFound = errors.New("ERC20: address not found")
+
+var ethAddressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// normalizeEthAddress validates an Ethereum address and lower cases it
+// Checksummed and plain forms of the same address map to the same row
+func normalizeEthAddress(ethAddr string) (string, error) {
+	ethAddr = strings.TrimSpace(ethAddr)
+	if !ethAddressPattern.MatchString(ethAddr) {
+		return "", ErrInvalidEthAddress
+	}
+	return strings.ToLower(ethAddr), nil
+}
+
+// LinkEthAddress associates a ledger address with an Ethereum address for bridging
+// An Ethereum address can be linked to at most one address per token
+func LinkEthAddress(ctx context.Context, conn *pgxpool.Pool, addressID uuid.UUID, ethAddr string) error {
+	normalized, err := normalizeEthAddress(ethAddr)
+	if err != nil {
+		return terror.Error(err, "Invalid Ethereum address")
+	}
+	q := `UPDATE addresses SET eth_address = $1, updated_at = now() WHERE id = $2`
+	tag, err := conn.Exec(ctx, q, normalized, addressID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", addressID, "eth", normalized)
+		return terror.Error(err, "Could not link Ethereum address")
+	}
+	if tag.RowsAffected() == 0 {
+		return terror.Error(ErrAddressNotFound, "Address not found")
+	}
+	return nil
+}
+
+// AddressByEth returns the ID of the address linked to an Ethereum address for the token
+func AddressByEth(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, ethAddr string) (uuid.UUID, error) {
+	normalized, err := normalizeEthAddress(ethAddr)
+	if err != nil {
+		return uuid.Nil, terror.Error(err, "Invalid Ethereum address")
+	}
+	q := `SELECT id FROM addresses WHERE token_id = $1 AND eth_address = $2`
+	var id uuid.UUID
+	err = conn.QueryRow(ctx, q, tokenID, normalized).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, terror.Error(ErrAddressNotFound, "Address not found")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "eth", normalized)
+		return uuid.Nil, terror.Error(err, "Could not get address")
+	}
+	return id, nil
+}
@@ -0,0 +1,74 @@
+package erc20_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+const ethAddr = "0x52908400098527886E0F7030069857D2E4169EE7"
+
+func TestLinkEthAddress(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+	addressID, err := erc20.GetOrCreateAddress(ctx, conn, tokenID, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.LinkEthAddress(ctx, conn, addressID, ethAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lookup := range []string{ethAddr, strings.ToLower(ethAddr)} {
+		got, err := erc20.AddressByEth(ctx, conn, tokenID, lookup)
+		if err != nil {
+			t.Fatalf("AddressByEth(%q): %v", lookup, err)
+		}
+		if got != addressID {
+			t.Errorf("AddressByEth(%q) = %s, want %s", lookup, got, addressID)
+		}
+	}
+
+	otherID, err := erc20.GetOrCreateAddress(ctx, conn, tokenID, newAddress(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.LinkEthAddress(ctx, conn, otherID, strings.ToLower(ethAddr))
+	if err == nil {
+		t.Error("linked one Ethereum address to two addresses of a token")
+	}
+	_, err = erc20.AddressByEth(ctx, conn, tokenID, "0x0000000000000000000000000000000000000001")
+	if !errors.Is(err, erc20.ErrAddressNotFound) {
+		t.Errorf("AddressByEth of an unlinked address error = %v, want ErrAddressNotFound", err)
+	}
+	err = erc20.LinkEthAddress(ctx, conn, uuid.Must(uuid.NewV4()), ethAddr)
+	if !errors.Is(err, erc20.ErrAddressNotFound) {
+		t.Errorf("LinkEthAddress of a missing address error = %v, want ErrAddressNotFound", err)
+	}
+}
+
+func TestLinkEthAddressRejectsMalformed(t *testing.T) {
+	for _, bad := range []string{
+		"",
+		"52908400098527886E0F7030069857D2E4169EE7",
+		"0x52908400098527886E0F7030069857D2E4169EE",
+		"0x52908400098527886E0F7030069857D2E4169EE77",
+		"0xZ2908400098527886E0F7030069857D2E4169EE7",
+	} {
+		err := erc20.LinkEthAddress(ctx, nil, uuid.Nil, bad)
+		if !errors.Is(err, erc20.ErrInvalidEthAddress) {
+			t.Errorf("LinkEthAddress(%q) error = %v, want ErrInvalidEthAddress", bad, err)
+		}
+		_, err = erc20.AddressByEth(ctx, nil, uuid.Nil, bad)
+		if !errors.Is(err, erc20.ErrInvalidEthAddress) {
+			t.Errorf("AddressByEth(%q) error = %v, want ErrInvalidEthAddress", bad, err)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// EventType is the kind of balance change recorded in ledger_events
+type EventType string
+
+// Event types recorded in ledger_events
+const (
+	EventTransfer  EventType = "transfer"
+	EventMint      EventType = "mint"
+	EventBurn      EventType = "burn"
+	EventClawback  EventType = "clawback"
+	EventAdminBurn EventType = "admin_burn"
+	// EventAdjustment is a balance set directly rather than moved, an increase is sent from and a
+	// decrease to ZeroAddress, changing the total supply like a mint or burn
+	EventAdjustment EventType = "adjustment"
+)
+
+// Event is a single balance change in the ledger
+// Mints are sent from and burns are sent to ZeroAddress
+type Event struct {
+	ID        uuid.UUID
+	TokenID   uuid.UUID
+	Type      EventType
+	From      Address
+	To        Address
+	Amount    int
+	CreatedAt time.Time
+}
+
+// recordEvent appends an event to the ledger inside tx and returns its ID
+func recordEvent(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, eventType EventType, from Address, to Address, amount int) (uuid.UUID, error) {
+	q := `INSERT INTO ledger_events (token_id, event_type, sender, recipient, amount) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	var eventID uuid.UUID
+	err := tx.QueryRow(ctx, q, tokenID, string(eventType), from, to, amount).Scan(&eventID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return eventID, nil
+}
+
+// eventColumns is the column list scanned by scanEvents
+const eventColumns = `ledger_events.id, ledger_events.token_id, ledger_events.event_type, ledger_events.sender, ledger_events.recipient, ledger_events.amount, ledger_events.created_at`
+
+// scanEvents collects every row selected with eventColumns
+func scanEvents(rows pgx.Rows) ([]Event, error) {
+	defer rows.Close()
+	events := []Event{}
+	for rows.Next() {
+		var event Event
+		var eventType string
+		err := rows.Scan(&event.ID, &event.TokenID, &eventType, &event.From, &event.To, &event.Amount, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		event.Type = EventType(eventType)
+		events = append(events, event)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return events, nil
+}
+
+// optionalTime maps the zero time to NULL for optional query bounds
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// TransfersForAddress returns the events where addr was the sender or recipient, newest first
+// Mints and burns are included as transfers from and to ZeroAddress.
+// The window is [from, to), a zero time leaves that side unbounded
+func TransfersForAddress(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, addr Address, from, to time.Time, limit, offset int) ([]Event, error) {
+	q := `
+SELECT ` + eventColumns + ` FROM ledger_events
+WHERE token_id = $1 AND (sender = $2 OR recipient = $2)
+AND ($3::TIMESTAMPTZ IS NULL OR created_at >= $3)
+AND ($4::TIMESTAMPTZ IS NULL OR created_at < $4)
+ORDER BY created_at DESC, id
+LIMIT $5 OFFSET $6`
+	rows, err := conn.Query(ctx, q, tokenID, addr, optionalTime(from), optionalTime(to), limit, offset)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "addr", addr)
+		return nil, terror.Error(err, "Could not get transfers")
+	}
+	events, err := scanEvents(rows)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "addr", addr)
+		return nil, terror.Error(err, "Could not get transfers")
+	}
+	return events, nil
+}
+
+// FundingEntry totals what one source credited to an address
+// Mints appear with Source ZeroAddress and Type EventMint
+type FundingEntry struct {
+	Source Address
+	Type   EventType
+	Total  int
+	Count  int
+}
+
+// FundingSources returns every source that credited addr, largest total first
+// Sources are grouped by sender and event type, so a clawback from an address is listed apart from its transfers
+func FundingSources(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, addr Address) ([]FundingEntry, error) {
+	q := `
+SELECT sender, event_type, SUM(amount), COUNT(*) FROM ledger_events
+WHERE token_id = $1 AND recipient = $2 AND event_type NOT IN ($3, $4)
+GROUP BY sender, event_type
+ORDER BY SUM(amount) DESC, sender, event_type`
+	rows, err := conn.Query(ctx, q, tokenID, addr, string(EventBurn), string(EventAdminBurn))
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "addr", addr)
+		return nil, terror.Error(err, "Could not get funding sources")
+	}
+	defer rows.Close()
+	entries := []FundingEntry{}
+	for rows.Next() {
+		var entry FundingEntry
+		var eventType string
+		err = rows.Scan(&entry.Source, &eventType, &entry.Total, &entry.Count)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "id", tokenID, "addr", addr)
+			return nil, terror.Error(err, "Could not get funding sources")
+		}
+		entry.Type = EventType(eventType)
+		entries = append(entries, entry)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error(), "id", tokenID, "addr", addr)
+		return nil, terror.Error(rows.Err(), "Could not get funding sources")
+	}
+	return entries, nil
+}
+
+// ErrEventNotFound is returned when a ledger event does not exist
+var ErrEventNotFound = errors.New("ERC20: event not found")
+
+// GetEvent retrieves a single ledger event by ID
+func GetEvent(ctx context.Context, conn *pgxpool.Pool, eventID uuid.UUID) (*Event, error) {
+	q := `SELECT ` + eventColumns + ` FROM ledger_events WHERE id = $1`
+	rows, err := conn.Query(ctx, q, eventID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "eventID", eventID)
+		return nil, terror.Error(err, "Could not get event")
+	}
+	events, err := scanEvents(rows)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "eventID", eventID)
+		return nil, terror.Error(err, "Could not get event")
+	}
+	if len(events) == 0 {
+		return nil, terror.Error(ErrEventNotFound, "Event not found")
+	}
+	return &events[0], nil
+}
+
+// ActivityEvent is a ledger event annotated with the symbol of its token
+type ActivityEvent struct {
+	Event
+	Symbol string
+}
+
+// ActivityFeed returns the events where addr was the sender or recipient on any token in the account book, newest first
+func ActivityFeed(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID, addr Address, limit, offset int) ([]ActivityEvent, error) {
+	q := `
+SELECT ` + eventColumns + `, tokens.symbol FROM ledger_events
+JOIN tokens ON tokens.id = ledger_events.token_id
+WHERE tokens.account_book_id = $1 AND (ledger_events.sender = $2 OR ledger_events.recipient = $2)
+ORDER BY ledger_events.created_at DESC, ledger_events.seq DESC
+LIMIT $3 OFFSET $4`
+	rows, err := conn.Query(ctx, q, accountBookID, addr, limit, offset)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "addr", addr)
+		return nil, terror.Error(err, "Could not get activity")
+	}
+	defer rows.Close()
+	events := []ActivityEvent{}
+	for rows.Next() {
+		var event ActivityEvent
+		var eventType string
+		err = rows.Scan(&event.ID, &event.TokenID, &eventType, &event.From, &event.To, &event.Amount, &event.CreatedAt, &event.Symbol)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "addr", addr)
+			return nil, terror.Error(err, "Could not get activity")
+		}
+		event.Type = EventType(eventType)
+		events = append(events, event)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error(), "accountBookID", accountBookID, "addr", addr)
+		return nil, terror.Error(rows.Err(), "Could not get activity")
+	}
+	return events, nil
+}
+
+// BalanceOfAtTime returns the balance addr held at a past moment, replayed from the ledger events up to and including at
+// Unlike BalanceOfAt it needs no snapshot. Zero if the address had no events by then
+func BalanceOfAtTime(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, addr Address, at time.Time) (int, error) {
+	q := `
+SELECT COALESCE(SUM(CASE WHEN recipient = $2 THEN amount ELSE 0 END) - SUM(CASE WHEN sender = $2 THEN amount ELSE 0 END), 0)
+FROM ledger_events
+WHERE token_id = $1 AND (sender = $2 OR recipient = $2) AND created_at <= $3`
+	var balance int
+	err := conn.QueryRow(ctx, q, tokenID, addr, at).Scan(&balance)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "addr", addr, "at", at)
+		return 0, terror.Error(err, "Could not get historical balance")
+	}
+	return balance, nil
+}
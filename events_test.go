@@ -0,0 +1,182 @@
+package erc20_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestTransfersForAddress(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, alice, bob := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	for _, tr := range []struct {
+		from, to erc20.Address
+		amount   int
+	}{{owner, alice, 10}, {alice, bob, 3}, {owner, bob, 7}, {bob, alice, 1}} {
+		time.Sleep(5 * time.Millisecond)
+		transfer(t, conn, tokenID, tr.from, tr.to, tr.amount)
+	}
+
+	events, err := erc20.TransfersForAddress(ctx, conn, tokenID, alice, time.Time{}, time.Time{}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAmounts := []int{1, 3, 10}
+	if len(events) != len(wantAmounts) {
+		t.Fatalf("TransfersForAddress returned %d events, want %d", len(events), len(wantAmounts))
+	}
+	for i, event := range events {
+		if event.Amount != wantAmounts[i] {
+			t.Errorf("event %d amount = %d, want %d, newest first", i, event.Amount, wantAmounts[i])
+		}
+		if event.From != alice && event.To != alice {
+			t.Errorf("event %d does not involve the address", i)
+		}
+	}
+
+	windowed, err := erc20.TransfersForAddress(ctx, conn, tokenID, alice, events[1].CreatedAt, events[0].CreatedAt, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windowed) != 1 || windowed[0].ID != events[1].ID {
+		t.Errorf("windowed TransfersForAddress = %+v, want only the transfer to bob", windowed)
+	}
+
+	paged, err := erc20.TransfersForAddress(ctx, conn, tokenID, alice, time.Time{}, time.Time{}, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paged) != 1 || paged[0].ID != events[1].ID {
+		t.Errorf("second page = %+v, want the second newest event", paged)
+	}
+}
+
+func TestFundingSources(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, alice, target := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	transfer(t, conn, tokenID, owner, alice, 100)
+	transfer(t, conn, tokenID, owner, target, 120)
+	transfer(t, conn, tokenID, owner, target, 80)
+	transfer(t, conn, tokenID, alice, target, 50)
+	mint(t, conn, tokenID, target, 70)
+	// Outgoing transfers are not funding
+	transfer(t, conn, tokenID, target, alice, 5)
+
+	got, err := erc20.FundingSources(ctx, conn, tokenID, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []erc20.FundingEntry{
+		{Source: owner, Type: erc20.EventTransfer, Total: 200, Count: 2},
+		{Source: erc20.ZeroAddress, Type: erc20.EventMint, Total: 70, Count: 1},
+		{Source: alice, Type: erc20.EventTransfer, Total: 50, Count: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FundingSources = %+v, want %+v", got, want)
+	}
+}
+
+func TestActivityFeed(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	bookID := erc20test.NewAccountBook(t, conn)
+	owner, alice, bob := newAddress(t), newAddress(t), newAddress(t)
+	gold, err := erc20.Factory(ctx, conn, bookID, owner, "Gold", "GLD", 18, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	silver, err := erc20.Factory(ctx, conn, bookID, owner, "Silver", "SLV", 18, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Activity in another account book is not part of the feed
+	other := newToken(t, conn, owner, 1000)
+	steps := []func(){
+		func() { transfer(t, conn, gold, owner, alice, 10) },
+		func() { transfer(t, conn, silver, owner, alice, 20) },
+		func() { transfer(t, conn, other, owner, alice, 99) },
+		func() { transfer(t, conn, gold, alice, bob, 3) },
+		func() { mint(t, conn, silver, alice, 5) },
+	}
+	for _, step := range steps {
+		time.Sleep(5 * time.Millisecond)
+		step()
+	}
+
+	feed, err := erc20.ActivityFeed(ctx, conn, bookID, alice, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type entry struct {
+		symbol    string
+		eventType erc20.EventType
+		amount    int
+	}
+	want := []entry{
+		{"SLV", erc20.EventMint, 5},
+		{"GLD", erc20.EventTransfer, 3},
+		{"SLV", erc20.EventTransfer, 20},
+		{"GLD", erc20.EventTransfer, 10},
+	}
+	got := []entry{}
+	for _, event := range feed {
+		got = append(got, entry{event.Symbol, event.Type, event.Amount})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ActivityFeed = %+v, want %+v", got, want)
+	}
+
+	page, err := erc20.ActivityFeed(ctx, conn, bookID, alice, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != feed[1].ID || page[1].ID != feed[2].ID {
+		t.Errorf("ActivityFeed limit 2 offset 1 did not return the second and third events")
+	}
+}
+
+func TestBalanceOfAtTime(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, holder := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	checkpoint := func() time.Time {
+		time.Sleep(10 * time.Millisecond)
+		at := time.Now()
+		time.Sleep(10 * time.Millisecond)
+		return at
+	}
+
+	beforeAny := checkpoint()
+	transfer(t, conn, tokenID, owner, holder, 100)
+	mint(t, conn, tokenID, holder, 50)
+	afterCredits := checkpoint()
+	transfer(t, conn, tokenID, holder, owner, 30)
+	afterDebit := checkpoint()
+	err := erc20.Burn(ctx, conn, tokenID, holder, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{"before any event", beforeAny, 0},
+		{"after the credits", afterCredits, 150},
+		{"after the debit", afterDebit, 120},
+		{"now", time.Now(), balanceOf(t, conn, tokenID, holder)},
+	} {
+		got, err := erc20.BalanceOfAtTime(ctx, conn, tokenID, holder, tt.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("BalanceOfAtTime %s = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
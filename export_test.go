@@ -0,0 +1,50 @@
+package erc20
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Move runs move in a transaction of its own
+func Move(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, sender, recipient Address, amount int) (senderBalance, recipientBalance int, err error) {
+	err = beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		senderBalance, recipientBalance, err = move(ctx, tx, tokenID, sender, recipient, amount, amount)
+		return err
+	})
+	return senderBalance, recipientBalance, err
+}
+
+// MoveSplit is Move written as a locking read and separate debit and credit statements
+func MoveSplit(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, sender, recipient Address, amount int) (senderBalance, recipientBalance int, err error) {
+	err = beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		senderBalance, err = debit(ctx, tx, tokenID, sender, amount)
+		if err != nil {
+			return err
+		}
+		recipientBalance, err = credit(ctx, tx, tokenID, recipient, amount)
+		return err
+	})
+	return senderBalance, recipientBalance, err
+}
+
+// WithRetry is withRetry, for injecting retryable failures
+var WithRetry = withRetry
+
+// Begin is begin, returning the context an operation would run with
+func (c *Client) Begin(ctx context.Context) (context.Context, func(), error) {
+	return c.begin(ctx)
+}
+
+// Deliver posts a single event to url, as DispatchPending does for each pending delivery
+func (d *WebhookDispatcher) Deliver(ctx context.Context, url, secret string, event Event) error {
+	return d.deliver(ctx, pendingDelivery{url: url, secret: secret, event: event})
+}
+
+// Logger returns the package logger, so tests replacing it can put it back
+func Logger() *zap.Logger {
+	return log.Desugar()
+}
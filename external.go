@@ -0,0 +1,105 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrExternalIDExists is returned when an external ID is already in use
+// Token external IDs are unique across the schema, address external IDs within their token
+var ErrExternalIDExists = errors.New("ERC20: external id already exists")
+
+// isUniqueViolation reports whether err is a unique violation (23505) of the named constraint or index
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
+// isForeignKeyViolation reports whether err is a foreign key violation (23503) of the named constraint
+func isForeignKeyViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503" && pgErr.ConstraintName == constraint
+}
+
+// FactoryWithExternalID creates a token like Factory, tagged with the caller's own ID for it, and returns the new token's ID
+// Returns ErrExternalIDExists if another token already has externalID
+func FactoryWithExternalID(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID, owner Address, name string, symbol string, decimals int, totalSupply int, externalID string) (uuid.UUID, error) {
+	err := ValidateToken(name, symbol, decimals)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	var tokenID uuid.UUID
+	err = beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		tokenID, err = createToken(ctx, tx, accountBookID, owner, name, symbol, decimals, totalSupply)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `UPDATE tokens SET external_id = $1 WHERE id = $2`, externalID, tokenID)
+		return err
+	})
+	if isUniqueViolation(err, "idx_tokens_external_id") {
+		return uuid.Nil, terror.Error(ErrExternalIDExists, "External ID already exists")
+	}
+	if isForeignKeyViolation(err, "tokens_account_book_id_fkey") {
+		return uuid.Nil, terror.Error(ErrAccountBookNotFound, "Account book not found")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "name", name, "symbol", symbol, "externalID", externalID)
+		return uuid.Nil, terror.Error(err, "Could not create token")
+	}
+	return tokenID, nil
+}
+
+// TokenIDByExternalID retrieves the ID of the token tagged with externalID
+// Deleted tokens are not found
+func TokenIDByExternalID(ctx context.Context, conn *pgxpool.Pool, externalID string) (uuid.UUID, error) {
+	q := `SELECT id FROM tokens WHERE external_id = $1 AND deleted_at IS NULL`
+	var tokenID uuid.UUID
+	err := conn.QueryRow(ctx, q, externalID).Scan(&tokenID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "externalID", externalID)
+		return uuid.Nil, terror.Error(err, "Could not get token")
+	}
+	return tokenID, nil
+}
+
+// SetAddressExternalID tags a ledger address with the caller's own ID for it
+func SetAddressExternalID(ctx context.Context, conn *pgxpool.Pool, addressID uuid.UUID, externalID string) error {
+	q := `UPDATE addresses SET external_id = $1, updated_at = now() WHERE id = $2`
+	tag, err := conn.Exec(ctx, q, externalID, addressID)
+	if isUniqueViolation(err, "idx_addresses_token_external_id") {
+		return terror.Error(ErrExternalIDExists, "External ID already exists")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", addressID, "externalID", externalID)
+		return terror.Error(err, "Could not set external ID")
+	}
+	if tag.RowsAffected() == 0 {
+		return terror.Error(ErrAddressNotFound, "Address not found")
+	}
+	return nil
+}
+
+// AddressByExternalID returns the ID of the address tagged with externalID for the token
+func AddressByExternalID(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, externalID string) (uuid.UUID, error) {
+	q := `SELECT id FROM addresses WHERE token_id = $1 AND external_id = $2`
+	var id uuid.UUID
+	err := conn.QueryRow(ctx, q, tokenID, externalID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, terror.Error(ErrAddressNotFound, "Address not found")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "externalID", externalID)
+		return uuid.Nil, terror.Error(err, "Could not get address")
+	}
+	return id, nil
+}
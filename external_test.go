@@ -0,0 +1,86 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestFactoryWithExternalID(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	book := erc20test.NewAccountBook(t, conn)
+	owner := newAddress(t)
+
+	tokenID, err := erc20.FactoryWithExternalID(ctx, conn, book, owner, "Points", "PTS", 0, 500, "crm-token-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := erc20.GetToken(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccountBookID != book {
+		t.Errorf("token account book = %s, want %s", token.AccountBookID, book)
+	}
+	if got := balanceOf(t, conn, tokenID, owner); got != 500 {
+		t.Errorf("owner balance = %d, want 500", got)
+	}
+
+	found, err := erc20.TokenIDByExternalID(ctx, conn, "crm-token-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != tokenID {
+		t.Errorf("TokenIDByExternalID = %s, want %s", found, tokenID)
+	}
+	_, err = erc20.TokenIDByExternalID(ctx, conn, "crm-token-2")
+	if !errors.Is(err, erc20.ErrTokenNotFound) {
+		t.Errorf("TokenIDByExternalID of unknown id = %v, want %v", err, erc20.ErrTokenNotFound)
+	}
+
+	_, err = erc20.FactoryWithExternalID(ctx, conn, book, owner, "Other", "OTH", 0, 0, "crm-token-1")
+	if !errors.Is(err, erc20.ErrExternalIDExists) {
+		t.Errorf("duplicate token external id = %v, want %v", err, erc20.ErrExternalIDExists)
+	}
+	_, err = erc20.TokenIDBySymbol(ctx, conn, "OTH")
+	if !errors.Is(err, erc20.ErrTokenNotFound) {
+		t.Errorf("token with duplicate external id was created: %v", err)
+	}
+}
+
+func TestAddressExternalID(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	tokenID := newToken(t, conn, newAddress(t), 0)
+	alice, bob := newAddress(t), newAddress(t)
+
+	aliceID, err := erc20.CreateAddress(ctx, conn, tokenID, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobID, err := erc20.CreateAddress(ctx, conn, tokenID, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.SetAddressExternalID(ctx, conn, aliceID, "customer-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := erc20.AddressByExternalID(ctx, conn, tokenID, "customer-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != aliceID {
+		t.Errorf("AddressByExternalID = %s, want %s", found, aliceID)
+	}
+
+	err = erc20.SetAddressExternalID(ctx, conn, bobID, "customer-42")
+	if !errors.Is(err, erc20.ErrExternalIDExists) {
+		t.Errorf("duplicate address external id = %v, want %v", err, erc20.ErrExternalIDExists)
+	}
+	_, err = erc20.AddressByExternalID(ctx, conn, tokenID, "customer-43")
+	if !errors.Is(err, erc20.ErrAddressNotFound) {
+		t.Errorf("AddressByExternalID of unknown id = %v, want %v", err, erc20.ErrAddressNotFound)
+	}
+}
@@ -3,8 +3,16 @@ module erc20
 go 1.16
 
 require (
+	github.com/go-redis/redis/v8 v8.11.4
 	github.com/gofrs/uuid v3.2.0+incompatible
+	github.com/graph-gophers/graphql-go v1.3.0
+	github.com/jackc/pgconn v1.8.1
 	github.com/jackc/pgx/v4 v4.11.0
 	github.com/ninja-software/terror/v2 v2.0.5
+	github.com/prometheus/client_golang v1.11.1
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
 	go.uber.org/zap v1.13.0
+	google.golang.org/grpc v1.40.0
+	google.golang.org/protobuf v1.27.1
 )
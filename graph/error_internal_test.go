@@ -0,0 +1,34 @@
+package graph
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+
+	"github.com/ninja-software/terror/v2"
+)
+
+func TestResolverError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{erc20.ErrTransferTooLarge, CodeFailedPrecondition},
+		{erc20.ErrBelowMinSupply, CodeFailedPrecondition},
+		{erc20.ErrMintCapExceeded, CodeFailedPrecondition},
+		{erc20.ErrSpendLimitExceeded, CodeFailedPrecondition},
+		{erc20.ErrAddressNotFound, CodeFailedPrecondition},
+		{erc20.ErrPaused, CodeFailedPrecondition},
+		{erc20.ErrNotOwner, CodePermissionDenied},
+		{erc20.ErrUnauthorized, CodePermissionDenied},
+		{erc20.ErrInsufficientBalance, CodeInsufficientBalance},
+		{erc20.ErrTokenNotFound, CodeTokenNotFound},
+	}
+	for _, tt := range tests {
+		var resolved *Error
+		if !errors.As(resolverError(terror.Error(tt.err, "wrapped")), &resolved) || resolved.Code != tt.want {
+			t.Errorf("resolverError(%v) = %v, want code %s", tt.err, resolved, tt.want)
+		}
+	}
+}
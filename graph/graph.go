@@ -0,0 +1,244 @@
+// Package graph exposes the ledger as a GraphQL endpoint
+package graph
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"erc20"
+
+	"github.com/gofrs/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Error codes set in the "code" extension of every resolver error
+const (
+	CodeInvalidArgument     = "INVALID_ARGUMENT"
+	CodeTokenNotFound       = "TOKEN_NOT_FOUND"
+	CodeInsufficientBalance = "INSUFFICIENT_BALANCE"
+	CodeFailedPrecondition  = "FAILED_PRECONDITION"
+	CodePermissionDenied    = "PERMISSION_DENIED"
+	CodeInternal            = "INTERNAL"
+)
+
+// NewSchema parses the schema against a resolver backed by the given pool
+func NewSchema(conn *pgxpool.Pool) (*graphql.Schema, error) {
+	return graphql.ParseSchema(Schema, &Resolver{conn: conn})
+}
+
+// NewHandler serves the schema over HTTP
+func NewHandler(conn *pgxpool.Pool) (http.Handler, error) {
+	schema, err := NewSchema(conn)
+	if err != nil {
+		return nil, err
+	}
+	return &relay.Handler{Schema: schema}, nil
+}
+
+// Resolver is the root query and mutation resolver
+type Resolver struct {
+	conn *pgxpool.Pool
+}
+
+// Token resolves token(id)
+func (r *Resolver) Token(ctx context.Context, args struct{ ID graphql.ID }) (*TokenResolver, error) {
+	tokenID, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	token, err := erc20.GetToken(ctx, r.conn, tokenID)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	return &TokenResolver{token: *token}, nil
+}
+
+// Tokens resolves tokens(accountBookId)
+func (r *Resolver) Tokens(ctx context.Context, args struct{ AccountBookID graphql.ID }) ([]*TokenResolver, error) {
+	accountBookID, err := parseID(args.AccountBookID)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := erc20.ListTokens(ctx, r.conn, accountBookID)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	resolvers := []*TokenResolver{}
+	for _, token := range tokens {
+		resolvers = append(resolvers, &TokenResolver{token: token})
+	}
+	return resolvers, nil
+}
+
+// BalanceOf resolves balanceOf(tokenId, address)
+func (r *Resolver) BalanceOf(ctx context.Context, args struct {
+	TokenID graphql.ID
+	Address graphql.ID
+}) (int32, error) {
+	tokenID, err := parseID(args.TokenID)
+	if err != nil {
+		return 0, err
+	}
+	address, err := parseID(args.Address)
+	if err != nil {
+		return 0, err
+	}
+	balance, err := erc20.BalanceOf(ctx, r.conn, tokenID, erc20.Address(address))
+	if err != nil {
+		return 0, resolverError(err)
+	}
+	return int32(balance), nil
+}
+
+// Transfer resolves the transfer mutation
+func (r *Resolver) Transfer(ctx context.Context, args struct {
+	TokenID graphql.ID
+	From    graphql.ID
+	To      graphql.ID
+	Amount  int32
+}) (bool, error) {
+	tokenID, err := parseID(args.TokenID)
+	if err != nil {
+		return false, err
+	}
+	from, err := parseID(args.From)
+	if err != nil {
+		return false, err
+	}
+	to, err := parseID(args.To)
+	if err != nil {
+		return false, err
+	}
+	ok, err := erc20.Transfer(ctx, r.conn, tokenID, erc20.Address(from), erc20.Address(to), erc20.Amount(args.Amount))
+	if err != nil {
+		return false, resolverError(err)
+	}
+	return ok, nil
+}
+
+// Mint resolves the mint mutation
+func (r *Resolver) Mint(ctx context.Context, args struct {
+	TokenID graphql.ID
+	Account graphql.ID
+	Amount  int32
+}) (bool, error) {
+	tokenID, err := parseID(args.TokenID)
+	if err != nil {
+		return false, err
+	}
+	account, err := parseID(args.Account)
+	if err != nil {
+		return false, err
+	}
+	err = erc20.Mint(ctx, r.conn, tokenID, erc20.Address(account), erc20.Amount(args.Amount))
+	if err != nil {
+		return false, resolverError(err)
+	}
+	return true, nil
+}
+
+// Burn resolves the burn mutation
+func (r *Resolver) Burn(ctx context.Context, args struct {
+	TokenID graphql.ID
+	Account graphql.ID
+	Amount  int32
+}) (bool, error) {
+	tokenID, err := parseID(args.TokenID)
+	if err != nil {
+		return false, err
+	}
+	account, err := parseID(args.Account)
+	if err != nil {
+		return false, err
+	}
+	err = erc20.Burn(ctx, r.conn, tokenID, erc20.Address(account), erc20.Amount(args.Amount))
+	if err != nil {
+		return false, resolverError(err)
+	}
+	return true, nil
+}
+
+// TokenResolver resolves the fields of a Token
+type TokenResolver struct {
+	token erc20.Token
+}
+
+// ID of the token
+func (t *TokenResolver) ID() graphql.ID {
+	return graphql.ID(t.token.ID.String())
+}
+
+// AccountBookID the token belongs to
+func (t *TokenResolver) AccountBookID() graphql.ID {
+	return graphql.ID(t.token.AccountBookID.String())
+}
+
+// Name of the token
+func (t *TokenResolver) Name() string {
+	return t.token.Name
+}
+
+// Symbol of the token
+func (t *TokenResolver) Symbol() string {
+	return t.token.Symbol
+}
+
+// Decimals of the token
+func (t *TokenResolver) Decimals() int32 {
+	return int32(t.token.Decimals)
+}
+
+// TotalSupply of the token
+func (t *TokenResolver) TotalSupply() int32 {
+	return int32(t.token.TotalSupply)
+}
+
+// Error is a resolver error carrying a machine readable code in its extensions
+type Error struct {
+	Code string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Extensions adds the code to the GraphQL error response
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+func parseID(id graphql.ID) (uuid.UUID, error) {
+	parsed, err := uuid.FromString(string(id))
+	if err != nil {
+		return uuid.Nil, &Error{Code: CodeInvalidArgument, Err: err}
+	}
+	return parsed, nil
+}
+
+// resolverError maps package errors onto error codes
+func resolverError(err error) error {
+	switch {
+	case errors.Is(err, erc20.ErrTokenNotFound):
+		return &Error{Code: CodeTokenNotFound, Err: err}
+	case errors.Is(err, erc20.ErrInsufficientBalance):
+		return &Error{Code: CodeInsufficientBalance, Err: err}
+	case errors.Is(err, erc20.ErrInvalidAmount):
+		return &Error{Code: CodeInvalidArgument, Err: err}
+	case errors.Is(err, erc20.ErrPaused), errors.Is(err, erc20.ErrTransferTooLarge), errors.Is(err, erc20.ErrBelowMinSupply),
+		errors.Is(err, erc20.ErrMintCapExceeded), errors.Is(err, erc20.ErrSpendLimitExceeded), errors.Is(err, erc20.ErrAddressNotFound):
+		return &Error{Code: CodeFailedPrecondition, Err: err}
+	case errors.Is(err, erc20.ErrNotOwner), errors.Is(err, erc20.ErrUnauthorized):
+		return &Error{Code: CodePermissionDenied, Err: err}
+	default:
+		return &Error{Code: CodeInternal, Err: err}
+	}
+}
@@ -0,0 +1,69 @@
+package graph_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+	"erc20/graph"
+
+	"github.com/gofrs/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+var ctx = context.Background()
+
+func exec(t *testing.T, schema *graphql.Schema, query string, vars map[string]interface{}) *graphql.Response {
+	t.Helper()
+	return schema.Exec(ctx, query, "", vars)
+}
+
+func TestMintThenBalanceOf(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := erc20.Address(uuid.Must(uuid.NewV4()))
+	account := uuid.Must(uuid.NewV4())
+	tokenID, err := erc20.Factory(ctx, conn, erc20test.NewAccountBook(t, conn), owner, "Test", "TST", 18, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graph.NewSchema(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]interface{}{"token": tokenID.String(), "account": account.String()}
+
+	resp := exec(t, schema, `mutation($token: ID!, $account: ID!) { mint(tokenId: $token, account: $account, amount: 250) }`, vars)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("mint: %v", resp.Errors)
+	}
+	resp = exec(t, schema, `query($token: ID!, $account: ID!) { balanceOf(tokenId: $token, address: $account) }`, vars)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("balanceOf: %v", resp.Errors)
+	}
+	var data struct{ BalanceOf int }
+	err = json.Unmarshal(resp.Data, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.BalanceOf != 250 {
+		t.Errorf("balanceOf = %d, want 250", data.BalanceOf)
+	}
+
+	resp = exec(t, schema, `mutation($token: ID!, $account: ID!) { burn(tokenId: $token, account: $account, amount: 251) }`, vars)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != graph.CodeInsufficientBalance {
+		t.Errorf("over-burn errors = %v, want code %s", resp.Errors, graph.CodeInsufficientBalance)
+	}
+}
+
+func TestInvalidID(t *testing.T) {
+	schema, err := graph.NewSchema(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := exec(t, schema, `{ balanceOf(tokenId: "not-a-uuid", address: "also-not") }`, nil)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != graph.CodeInvalidArgument {
+		t.Errorf("errors = %v, want code %s", resp.Errors, graph.CodeInvalidArgument)
+	}
+}
@@ -0,0 +1,30 @@
+package graph
+
+// Schema is the GraphQL schema served by the resolvers in this package
+const Schema = `
+schema {
+	query: Query
+	mutation: Mutation
+}
+
+type Query {
+	token(id: ID!): Token!
+	tokens(accountBookId: ID!): [Token!]!
+	balanceOf(tokenId: ID!, address: ID!): Int!
+}
+
+type Mutation {
+	transfer(tokenId: ID!, from: ID!, to: ID!, amount: Int!): Boolean!
+	mint(tokenId: ID!, account: ID!, amount: Int!): Boolean!
+	burn(tokenId: ID!, account: ID!, amount: Int!): Boolean!
+}
+
+type Token {
+	id: ID!
+	accountBookId: ID!
+	name: String!
+	symbol: String!
+	decimals: Int!
+	totalSupply: Int!
+}
+`
@@ -0,0 +1,39 @@
+package erc20
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// PoolStats is a snapshot of connection pool usage
+type PoolStats struct {
+	AcquiredConns int32
+	IdleConns     int32
+	TotalConns    int32
+	MaxConns      int32
+}
+
+// Ping checks the database is reachable, for readiness probes
+// Respects the context deadline
+func Ping(ctx context.Context, conn *pgxpool.Pool) error {
+	var one int
+	err := conn.QueryRow(ctx, `SELECT 1`).Scan(&one)
+	if err != nil {
+		logger(ctx).Errorw(err.Error())
+		return terror.Error(err, "Could not reach database")
+	}
+	return nil
+}
+
+// Stats returns the current connection pool usage
+func Stats(conn *pgxpool.Pool) PoolStats {
+	stat := conn.Stat()
+	return PoolStats{
+		AcquiredConns: stat.AcquiredConns(),
+		IdleConns:     stat.IdleConns(),
+		TotalConns:    stat.TotalConns(),
+		MaxConns:      stat.MaxConns(),
+	}
+}
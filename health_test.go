@@ -0,0 +1,52 @@
+package erc20_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func TestPingAndStats(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+
+	err := erc20.Ping(ctx, conn)
+	if err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	stats := erc20.Stats(conn)
+	if stats.MaxConns < 1 {
+		t.Errorf("MaxConns = %d, want at least 1", stats.MaxConns)
+	}
+	if stats.TotalConns > stats.MaxConns || stats.AcquiredConns+stats.IdleConns > stats.TotalConns {
+		t.Errorf("inconsistent pool stats %+v", stats)
+	}
+}
+
+func TestPingUnreachable(t *testing.T) {
+	config, err := pgxpool.ParseConfig("postgres://erc20@127.0.0.1:1/erc20?connect_timeout=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.LazyConnect = true
+	config.MaxConns = 3
+	conn, err := pgxpool.ConnectConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	pingCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	err = erc20.Ping(pingCtx, conn)
+	if err == nil {
+		t.Error("Ping of an unreachable database succeeded")
+	}
+	if got := erc20.Stats(conn).MaxConns; got != 3 {
+		t.Errorf("MaxConns = %d, want 3", got)
+	}
+}
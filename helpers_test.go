@@ -0,0 +1,87 @@
+package erc20_test
+
+import (
+	"context"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var ctx = context.Background()
+
+func newAddress(t testing.TB) erc20.Address {
+	t.Helper()
+	id, err := uuid.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return erc20.Address(id)
+}
+
+// newToken creates a token in a fresh account book with its supply minted to owner
+func newToken(t testing.TB, conn *pgxpool.Pool, owner erc20.Address, supply int) uuid.UUID {
+	t.Helper()
+	tokenID, err := erc20.Factory(ctx, conn, erc20test.NewAccountBook(t, conn), owner, "Test", "TST", 18, supply)
+	if err != nil {
+		t.Fatalf("Factory: %v", err)
+	}
+	return tokenID
+}
+
+func mint(t testing.TB, conn *pgxpool.Pool, tokenID uuid.UUID, account erc20.Address, amount int) {
+	t.Helper()
+	err := erc20.Mint(ctx, conn, tokenID, account, erc20.Amount(amount))
+	if err != nil {
+		t.Fatalf("Mint %d: %v", amount, err)
+	}
+}
+
+func transfer(t testing.TB, conn *pgxpool.Pool, tokenID uuid.UUID, from, to erc20.Address, amount int) {
+	t.Helper()
+	_, err := erc20.Transfer(ctx, conn, tokenID, from, to, erc20.Amount(amount))
+	if err != nil {
+		t.Fatalf("Transfer %d: %v", amount, err)
+	}
+}
+
+func balanceOf(t testing.TB, conn *pgxpool.Pool, tokenID uuid.UUID, owner erc20.Address) int {
+	t.Helper()
+	balance, err := erc20.BalanceOf(ctx, conn, tokenID, owner)
+	if err != nil {
+		t.Fatalf("BalanceOf: %v", err)
+	}
+	return balance.Int()
+}
+
+func totalSupply(t testing.TB, conn *pgxpool.Pool, tokenID uuid.UUID) int {
+	t.Helper()
+	supply, err := erc20.TotalSupply(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatalf("TotalSupply: %v", err)
+	}
+	return supply.Int()
+}
+
+// wantBalances fails the test unless every owner holds the given balance
+func wantBalances(t testing.TB, conn *pgxpool.Pool, tokenID uuid.UUID, want map[erc20.Address]int) {
+	t.Helper()
+	for owner, balance := range want {
+		got := balanceOf(t, conn, tokenID, owner)
+		if got != balance {
+			t.Errorf("balance of %s = %d, want %d", uuid.UUID(owner), got, balance)
+		}
+	}
+}
+
+// wantConserved fails the test unless the token's balances add up to its total supply
+func wantConserved(t testing.TB, conn *pgxpool.Pool, tokenID uuid.UUID) {
+	t.Helper()
+	err := erc20.AssertConserved(ctx, conn, tokenID)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,139 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrHoldNotFound is returned when a hold does not exist or was already captured or released
+var ErrHoldNotFound = errors.New("ERC20: hold not found")
+
+// HoldFunds reserves amount of the account's balance for a later CaptureHold or ReleaseHold
+// The held amount is moved to an escrow address owned by the hold, so BalanceOf only reports what is still spendable
+func HoldFunds(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount int) (uuid.UUID, error) {
+	if amount < 0 {
+		return uuid.Nil, terror.Error(ErrInvalidAmount, "Amount can not be negative")
+	}
+	var holdID uuid.UUID
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := activeToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		q := `INSERT INTO holds (token_id, account, amount) VALUES ($1, $2, $3) RETURNING id`
+		err = tx.QueryRow(ctx, q, tokenID, account, amount).Scan(&holdID)
+		if err != nil {
+			return err
+		}
+		_, err = debit(ctx, tx, tokenID, account, amount)
+		if err != nil {
+			return err
+		}
+		_, err = credit(ctx, tx, tokenID, Address(holdID), amount)
+		if err != nil {
+			return err
+		}
+		_, err = recordEvent(ctx, tx, tokenID, EventTransfer, account, Address(holdID), amount)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "account", account, "amount", amount)
+		return uuid.Nil, terror.Error(err, "Could not hold funds")
+	}
+	return holdID, nil
+}
+
+// CaptureHold settles a hold by paying the held amount to the recipient
+func CaptureHold(ctx context.Context, conn *pgxpool.Pool, holdID uuid.UUID, to Address) error {
+	err := settleHold(ctx, conn, holdID, &to)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "holdID", holdID, "to", to)
+		return terror.Error(err, "Could not capture hold")
+	}
+	return nil
+}
+
+// ReleaseHold settles a hold by returning the held amount to the account it was taken from
+func ReleaseHold(ctx context.Context, conn *pgxpool.Pool, holdID uuid.UUID) error {
+	err := settleHold(ctx, conn, holdID, nil)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "holdID", holdID)
+		return terror.Error(err, "Could not release hold")
+	}
+	return nil
+}
+
+// settleHold moves the held amount out of escrow to recipient, or back to the account when recipient is nil
+func settleHold(ctx context.Context, conn *pgxpool.Pool, holdID uuid.UUID, recipient *Address) error {
+	return withRetry(ctx, conn, func(tx pgx.Tx) error {
+		q := `SELECT token_id, account, amount FROM holds WHERE id = $1 AND settled_at IS NULL FOR UPDATE`
+		var tokenID uuid.UUID
+		var account Address
+		var amount int
+		err := tx.QueryRow(ctx, q, holdID).Scan(&tokenID, &account, &amount)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrHoldNotFound
+		}
+		if err != nil {
+			return err
+		}
+		err = shareTokenLock(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		to := account
+		if recipient != nil {
+			to = *recipient
+		}
+		_, err = debit(ctx, tx, tokenID, Address(holdID), amount)
+		if err != nil {
+			return err
+		}
+		_, err = credit(ctx, tx, tokenID, to, amount)
+		if err != nil {
+			return err
+		}
+		_, err = recordEvent(ctx, tx, tokenID, EventTransfer, Address(holdID), to, amount)
+		if err != nil {
+			return err
+		}
+		settleQ := `UPDATE holds SET settled_at = now() WHERE id = $1`
+		_, err = tx.Exec(ctx, settleQ, holdID)
+		return err
+	})
+}
+
+// AvailableBalance returns how much of the account's balance it can transfer right now
+// Held and staked funds sit in escrow until released or unstaked, so they are never part of it.
+// Unlike BalanceOf a missing address is not created
+func AvailableBalance(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address) (int, error) {
+	q := `SELECT balance FROM addresses WHERE token_id = $1 AND owner = $2 AND ` + notEscrowQ
+	var available int
+	err := conn.QueryRow(ctx, q, tokenID, account).Scan(&available)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "account", account)
+		return 0, terror.Error(err, "Could not get available balance")
+	}
+	return available, nil
+}
+
+// HeldBalance returns the total of an account's open holds
+// These are not part of BalanceOf until released
+func HeldBalance(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address) (int, error) {
+	q := `SELECT COALESCE(SUM(amount), 0) FROM holds WHERE token_id = $1 AND account = $2 AND settled_at IS NULL`
+	var held int
+	err := conn.QueryRow(ctx, q, tokenID, account).Scan(&held)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "account", account)
+		return 0, terror.Error(err, "Could not get held balance")
+	}
+	return held, nil
+}
@@ -0,0 +1,120 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func availableBalance(t *testing.T, conn *pgxpool.Pool, tokenID uuid.UUID, account erc20.Address) int {
+	t.Helper()
+	available, err := erc20.AvailableBalance(ctx, conn, tokenID, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return available
+}
+
+func TestHoldFunds(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	payer, merchant := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, payer, 100)
+
+	holdID, err := erc20.HoldFunds(ctx, conn, tokenID, payer, 70)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := availableBalance(t, conn, tokenID, payer); got != 30 {
+		t.Errorf("AvailableBalance while held = %d, want 30", got)
+	}
+	held, err := erc20.HeldBalance(ctx, conn, tokenID, payer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if held != 70 {
+		t.Errorf("HeldBalance = %d, want 70", held)
+	}
+	_, err = erc20.Transfer(ctx, conn, tokenID, payer, merchant, 31)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("spending held funds = %v, want %v", err, erc20.ErrInsufficientBalance)
+	}
+
+	err = erc20.CaptureHold(ctx, conn, holdID, merchant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{payer: 30, merchant: 70})
+	err = erc20.ReleaseHold(ctx, conn, holdID)
+	if !errors.Is(err, erc20.ErrHoldNotFound) {
+		t.Errorf("releasing a captured hold = %v, want %v", err, erc20.ErrHoldNotFound)
+	}
+	wantConserved(t, conn, tokenID)
+}
+
+func TestReleaseHold(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	payer := newAddress(t)
+	tokenID := newToken(t, conn, payer, 100)
+
+	holdID, err := erc20.HoldFunds(ctx, conn, tokenID, payer, 70)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.ReleaseHold(ctx, conn, holdID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := availableBalance(t, conn, tokenID, payer); got != 100 {
+		t.Errorf("AvailableBalance after release = %d, want 100", got)
+	}
+}
+
+func TestEscrowIsNotAHolder(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	payer, other := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, payer, 100)
+	transfer(t, conn, tokenID, payer, other, 50)
+	holdID, err := erc20.HoldFunds(ctx, conn, tokenID, payer, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := erc20.HolderCount(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("HolderCount = %d, want 2", count)
+	}
+	top, err := erc20.TopHolders(ctx, conn, tokenID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, holder := range top {
+		if holder.Address == erc20.Address(holdID) {
+			t.Errorf("TopHolders includes the hold escrow")
+		}
+	}
+	circulating, err := erc20.CirculatingSupply(ctx, conn, tokenID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if circulating != 70 {
+		t.Errorf("CirculatingSupply = %d, want 70", circulating)
+	}
+
+	err = erc20.SetBalances(ctx, conn, tokenID, map[erc20.Address]int{erc20.Address(holdID): 0, payer: 50}, false)
+	if !errors.Is(err, erc20.ErrEscrowAddress) {
+		t.Errorf("SetBalances on the hold escrow = %v, want %v", err, erc20.ErrEscrowAddress)
+	}
+	err = erc20.ReleaseHold(ctx, conn, holdID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{payer: 50, other: 50})
+}
@@ -0,0 +1,210 @@
+package erc20
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("ERC20: invalid cursor")
+
+// HolderBalance is an address and the balance it holds
+type HolderBalance struct {
+	Address Address
+	Balance int
+}
+
+// HolderCount returns the number of addresses holding a non-zero balance of the token
+// Escrow addresses of holds, stakes, vesting schedules and scheduled transfers are not holders
+func HolderCount(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) (int, error) {
+	q := `SELECT count(id) FROM addresses WHERE token_id = $1 AND balance > 0 AND ` + notEscrowQ
+	var count int
+	row := conn.QueryRow(ctx, q, tokenID)
+	err := row.Scan(&count)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return 0, terror.Error(err, "Could not count holders")
+	}
+	return count, nil
+}
+
+// TopHolders returns the n largest holders of the token, largest first
+// Zero balance and escrow addresses are excluded
+func TopHolders(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, n int) ([]HolderBalance, error) {
+	q := `
+SELECT owner, balance FROM addresses
+WHERE token_id = $1 AND balance > 0 AND ` + notEscrowQ + `
+ORDER BY balance DESC, owner
+LIMIT $2`
+	rows, err := conn.Query(ctx, q, tokenID, n)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "n", n)
+		return nil, terror.Error(err, "Could not get top holders")
+	}
+	defer rows.Close()
+	holders := []HolderBalance{}
+	for rows.Next() {
+		var holder HolderBalance
+		err = rows.Scan(&holder.Address, &holder.Balance)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "id", tokenID, "n", n)
+			return nil, terror.Error(err, "Could not scan holder")
+		}
+		holders = append(holders, holder)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error(), "id", tokenID, "n", n)
+		return nil, terror.Error(rows.Err(), "Could not get top holders")
+	}
+	return holders, nil
+}
+
+// ListHolders pages through the holders of the token, largest first
+// Pass an empty cursor for the first page. nextCursor is empty once there are no more holders.
+// Paging is keyset based on (balance, id) so rows are not skipped or repeated as the table grows.
+// Escrow addresses are excluded
+func ListHolders(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, afterCursor string, limit int) ([]HolderBalance, string, error) {
+	first := afterCursor == ""
+	var afterBalance int
+	var afterID uuid.UUID
+	if !first {
+		var err error
+		afterBalance, afterID, err = decodeHolderCursor(afterCursor)
+		if err != nil {
+			return nil, "", terror.Error(err, "Invalid cursor")
+		}
+	}
+	q := `
+SELECT id, owner, balance FROM addresses
+WHERE token_id = $1 AND balance > 0 AND ($2 OR (balance, id) < ($3, $4)) AND ` + notEscrowQ + `
+ORDER BY balance DESC, id DESC
+LIMIT $5`
+	rows, err := conn.Query(ctx, q, tokenID, first, afterBalance, afterID, limit)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "cursor", afterCursor)
+		return nil, "", terror.Error(err, "Could not list holders")
+	}
+	defer rows.Close()
+	holders := []HolderBalance{}
+	var lastID uuid.UUID
+	for rows.Next() {
+		var holder HolderBalance
+		err = rows.Scan(&lastID, &holder.Address, &holder.Balance)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "id", tokenID, "cursor", afterCursor)
+			return nil, "", terror.Error(err, "Could not scan holder")
+		}
+		holders = append(holders, holder)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error(), "id", tokenID, "cursor", afterCursor)
+		return nil, "", terror.Error(rows.Err(), "Could not list holders")
+	}
+	if len(holders) < limit || len(holders) == 0 {
+		return holders, "", nil
+	}
+	return holders, encodeHolderCursor(holders[len(holders)-1].Balance, lastID), nil
+}
+
+func encodeHolderCursor(balance int, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", balance, id)))
+}
+
+func decodeHolderCursor(cursor string) (int, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, uuid.Nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return 0, uuid.Nil, ErrInvalidCursor
+	}
+	balance, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.FromString(parts[1])
+	if err != nil {
+		return 0, uuid.Nil, ErrInvalidCursor
+	}
+	return balance, id, nil
+}
+
+// TokenBalance is a token and an owner's balance of it
+type TokenBalance struct {
+	TokenID  uuid.UUID
+	Symbol   string
+	Decimals int
+	Balance  int
+}
+
+// PortfolioOf returns every live token of an account book with the owner's balance of it, ordered by symbol
+// Tokens the owner has never held are included with a zero balance
+func PortfolioOf(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID, owner Address) ([]TokenBalance, error) {
+	q := `
+SELECT tokens.id, tokens.symbol, tokens.decimals, COALESCE(addresses.balance, 0)
+FROM tokens
+LEFT JOIN addresses ON addresses.token_id = tokens.id AND addresses.owner = $2
+WHERE tokens.account_book_id = $1 AND tokens.deleted_at IS NULL
+ORDER BY tokens.symbol`
+	rows, err := conn.Query(ctx, q, accountBookID, owner)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "owner", owner)
+		return nil, terror.Error(err, "Could not get portfolio")
+	}
+	defer rows.Close()
+	balances := []TokenBalance{}
+	for rows.Next() {
+		var balance TokenBalance
+		err = rows.Scan(&balance.TokenID, &balance.Symbol, &balance.Decimals, &balance.Balance)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "owner", owner)
+			return nil, terror.Error(err, "Could not scan balance")
+		}
+		balances = append(balances, balance)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error(), "accountBookID", accountBookID, "owner", owner)
+		return nil, terror.Error(rows.Err(), "Could not get portfolio")
+	}
+	return balances, nil
+}
+
+// IterateBalances calls fn with every address balance of a token, zero balances included, ordered by address
+// Escrow addresses are skipped, their balances belong to holds, stakes, vesting schedules and scheduled transfers.
+// Rows are streamed rather than loaded at once. Iteration stops at the first error fn returns, which is passed back as is
+func IterateBalances(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, fn func(addr Address, balance int) error) error {
+	q := `SELECT owner, balance FROM addresses WHERE token_id = $1 AND ` + notEscrowQ + ` ORDER BY owner`
+	rows, err := conn.Query(ctx, q, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return terror.Error(err, "Could not get balances")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var addr Address
+		var balance int
+		err = rows.Scan(&addr, &balance)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "id", tokenID)
+			return terror.Error(err, "Could not scan balance")
+		}
+		err = fn(addr, balance)
+		if err != nil {
+			return err
+		}
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error(), "id", tokenID)
+		return terror.Error(rows.Err(), "Could not get balances")
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package erc20_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestHolderCountAndTopHolders(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	small, large, emptied := newAddress(t), newAddress(t), newAddress(t)
+	transfer(t, conn, tokenID, owner, small, 100)
+	transfer(t, conn, tokenID, owner, large, 500)
+	transfer(t, conn, tokenID, owner, emptied, 50)
+	transfer(t, conn, tokenID, emptied, owner, 50)
+
+	count, err := erc20.HolderCount(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("HolderCount = %d, want 3", count)
+	}
+
+	holders, err := erc20.TopHolders(ctx, conn, tokenID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []erc20.HolderBalance{{Address: large, Balance: 500}, {Address: owner, Balance: 400}, {Address: small, Balance: 100}}
+	if len(holders) != len(want) {
+		t.Fatalf("TopHolders returned %d holders, want %d", len(holders), len(want))
+	}
+	for i := range want {
+		if holders[i] != want[i] {
+			t.Errorf("TopHolders[%d] = %+v, want %+v", i, holders[i], want[i])
+		}
+	}
+
+	holders, err = erc20.TopHolders(ctx, conn, tokenID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holders) != 1 || holders[0].Address != large {
+		t.Errorf("TopHolders(1) = %+v, want only the largest holder", holders)
+	}
+}
+
+func TestListHolders(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	want := map[erc20.Address]int{owner: 900}
+	for _, amount := range []int{10, 10, 10, 20, 20, 30} {
+		holder := newAddress(t)
+		transfer(t, conn, tokenID, owner, holder, amount)
+		want[holder] = amount
+	}
+
+	seen := map[erc20.Address]int{}
+	last := -1
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatal("ListHolders never ran out of pages")
+		}
+		holders, next, err := erc20.ListHolders(ctx, conn, tokenID, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, holder := range holders {
+			if _, ok := seen[holder.Address]; ok {
+				t.Errorf("holder %s seen twice", uuid.UUID(holder.Address))
+			}
+			if last >= 0 && holder.Balance > last {
+				t.Errorf("balance %d listed after %d, want largest first", holder.Balance, last)
+			}
+			seen[holder.Address] = holder.Balance
+			last = holder.Balance
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != len(want) {
+		t.Errorf("ListHolders listed %d holders, want %d", len(seen), len(want))
+	}
+	for addr, balance := range want {
+		if seen[addr] != balance {
+			t.Errorf("holder %s listed with %d, want %d", uuid.UUID(addr), seen[addr], balance)
+		}
+	}
+}
+
+func TestListHoldersInvalidCursor(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "bm8tY29sb24", "eDpub3QtYS11dWlk"} {
+		_, _, err := erc20.ListHolders(ctx, nil, uuid.Nil, cursor, 10)
+		if !errors.Is(err, erc20.ErrInvalidCursor) {
+			t.Errorf("ListHolders(%q) error = %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
+
+func TestPortfolioOf(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	bookID := erc20test.NewAccountBook(t, conn)
+	issuer, holder := newAddress(t), newAddress(t)
+	factory := func(name, symbol string, decimals int) uuid.UUID {
+		tokenID, err := erc20.Factory(ctx, conn, bookID, issuer, name, symbol, decimals, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tokenID
+	}
+	bronze, gold, silver := factory("Bronze", "BRZ", 2), factory("Gold", "GLD", 18), factory("Silver", "SLV", 6)
+	transfer(t, conn, gold, issuer, holder, 250)
+	transfer(t, conn, silver, issuer, holder, 40)
+
+	got, err := erc20.PortfolioOf(ctx, conn, bookID, holder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []erc20.TokenBalance{
+		{TokenID: bronze, Symbol: "BRZ", Decimals: 2, Balance: 0},
+		{TokenID: gold, Symbol: "GLD", Decimals: 18, Balance: 250},
+		{TokenID: silver, Symbol: "SLV", Decimals: 6, Balance: 40},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PortfolioOf = %+v, want %+v", got, want)
+	}
+}
+
+func TestIterateBalances(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 0)
+	items := mintItems(t, 3000)
+	err := erc20.MintBatch(ctx, conn, tokenID, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[erc20.Address]int{}
+	for _, item := range items {
+		want[item.Account] += item.Amount
+	}
+
+	visited := map[erc20.Address]int{}
+	var last string
+	err = erc20.IterateBalances(ctx, conn, tokenID, func(addr erc20.Address, balance int) error {
+		if _, seen := visited[addr]; seen {
+			t.Errorf("%s visited twice", uuid.UUID(addr))
+		}
+		if uuid.UUID(addr).String() < last {
+			t.Errorf("%s visited out of order", uuid.UUID(addr))
+		}
+		last = uuid.UUID(addr).String()
+		visited[addr] = balance
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The owner of a token created without supply may hold an empty address
+	if balance, ok := visited[owner]; ok && balance == 0 {
+		delete(visited, owner)
+	}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("IterateBalances visited %d holders, want %d with matching balances", len(visited), len(want))
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = erc20.IterateBalances(ctx, conn, tokenID, func(addr erc20.Address, balance int) error {
+		calls++
+		if calls == 10 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("IterateBalances error = %v, want the callback's error as is", err)
+	}
+	if calls != 10 {
+		t.Errorf("callback ran %d times, want iteration to stop at 10", calls)
+	}
+}
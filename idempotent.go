@@ -0,0 +1,39 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrDuplicateRequest is returned when a request key has already been processed
+var ErrDuplicateRequest = errors.New("ERC20: duplicate request")
+
+// MintIdempotent mints like Mint, at most once per requestKey
+// A replay with a key that already minted changes nothing and returns ErrDuplicateRequest,
+// callers retrying a delivery can treat that as success
+func MintIdempotent(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount int, requestKey string) error {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		q := `INSERT INTO mint_requests (request_key, token_id) VALUES ($1, $2) ON CONFLICT (request_key) DO NOTHING`
+		tag, err := tx.Exec(ctx, q, requestKey, tokenID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrDuplicateRequest
+		}
+		return mint(ctx, tx, tokenID, account, amount)
+	})
+	if errors.Is(err, ErrDuplicateRequest) {
+		return terror.Error(err, "Mint already processed")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "account", account, "amount", amount, "requestKey", requestKey)
+		return terror.Error(err, "Could not update balances")
+	}
+	return nil
+}
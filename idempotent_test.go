@@ -0,0 +1,34 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestMintIdempotent(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, account := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 0)
+
+	err := erc20.MintIdempotent(ctx, conn, tokenID, account, 100, "deposit-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.MintIdempotent(ctx, conn, tokenID, account, 100, "deposit-1")
+	if !errors.Is(err, erc20.ErrDuplicateRequest) {
+		t.Errorf("replayed MintIdempotent error = %v, want ErrDuplicateRequest", err)
+	}
+	if got := totalSupply(t, conn, tokenID); got != 100 {
+		t.Errorf("total supply = %d, want 100 after a replay", got)
+	}
+
+	err = erc20.MintIdempotent(ctx, conn, tokenID, account, 50, "deposit-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{account: 150})
+	wantConserved(t, conn, tokenID)
+}
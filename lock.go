@@ -0,0 +1,45 @@
+package erc20
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// WithTokenLock runs fn while holding an advisory lock on the token, so across every process
+// only one WithTokenLock caller for that token runs at a time. Other callers block until it is free.
+// The lock is transaction scoped and is released when fn returns, errors or panics.
+// Every balance change of the token takes the same lock in shared mode, so while fn runs no transfer,
+// mint or burn of the token is in progress and any that start wait for fn to return.
+// fn must not change the token's balances itself, its calls would wait on the lock held for it and never finish.
+// RebuildBalances takes the lock on its own and must not be called from fn
+func WithTokenLock(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, fn func() error) error {
+	err := beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		err := lockToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		return fn()
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return terror.Error(err, "Could not run with token lock")
+	}
+	return nil
+}
+
+// lockToken takes the token's advisory lock exclusively inside tx, waiting for every balance change in progress
+func lockToken(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1::text, 0))`, tokenID)
+	return err
+}
+
+// shareTokenLock takes the token's advisory lock in shared mode inside tx, waiting while it is held exclusively
+// Balance changes take it before touching any row, so they run alongside each other but not alongside lockToken
+func shareTokenLock(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock_shared(hashtextextended($1::text, 0))`, tokenID)
+	return err
+}
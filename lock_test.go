@@ -0,0 +1,69 @@
+package erc20_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestWithTokenLockSerializes(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	tokenID := newToken(t, conn, newAddress(t), 100)
+
+	var inside, overlapped int32
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := erc20.WithTokenLock(ctx, conn, tokenID, func() error {
+				if atomic.AddInt32(&inside, 1) > 1 {
+					atomic.StoreInt32(&overlapped, 1)
+				}
+				time.Sleep(50 * time.Millisecond)
+				atomic.AddInt32(&inside, -1)
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if overlapped != 0 {
+		t.Error("two WithTokenLock callers ran at the same time")
+	}
+}
+
+func TestWithTokenLockHoldsOffTransfers(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, recipient := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+
+	done := make(chan error, 1)
+	err := erc20.WithTokenLock(ctx, conn, tokenID, func() error {
+		go func() {
+			_, err := erc20.Transfer(ctx, conn, tokenID, owner, recipient, 10)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			t.Errorf("transfer finished while the token was locked: %v", err)
+			done <- err
+		case <-time.After(100 * time.Millisecond):
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 90, recipient: 10})
+}
@@ -0,0 +1,60 @@
+package erc20
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"go.uber.org/zap"
+)
+
+// ctxKey namespaces values this package stores in a context
+type ctxKey int
+
+const requestIDKey ctxKey = iota
+
+// WithRequestID returns a copy of ctx carrying a request or correlation ID
+// Every log line written for an operation run with the returned context includes it as request_id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID carried by ctx, if any
+func RequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok && id != ""
+}
+
+// SetLogger replaces the package logger, which defaults to a zap development logger
+func SetLogger(l *zap.Logger) {
+	log = l.Sugar()
+}
+
+// logger returns the package logger with the request ID from ctx attached
+func logger(ctx context.Context) *zap.SugaredLogger {
+	if id, ok := RequestID(ctx); ok {
+		return log.With("request_id", id)
+	}
+	return log
+}
+
+// logOperation writes one line per client operation with a consistent set of fields
+// Failures log at warn, successes at info
+func logOperation(ctx context.Context, op string, tokenID uuid.UUID, amount int, started time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = errorType(err)
+	}
+	fields := []interface{}{
+		"op", op,
+		"token_id", tokenID,
+		"amount", amount,
+		"duration_ms", time.Since(started).Milliseconds(),
+		"outcome", outcome,
+	}
+	if err != nil {
+		logger(ctx).Warnw(err.Error(), fields...)
+		return
+	}
+	logger(ctx).Infow(op, fields...)
+}
@@ -0,0 +1,97 @@
+package erc20_test
+
+import (
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observeLogs captures everything the package logs until the test ends
+func observeLogs(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zapcore.DebugLevel)
+	previous := erc20.Logger()
+	erc20.SetLogger(zap.New(core))
+	t.Cleanup(func() { erc20.SetLogger(previous) })
+	return logs
+}
+
+// wantFields fails the test unless entry has every field in want
+func wantFields(t *testing.T, entry observer.LoggedEntry, want map[string]interface{}) {
+	t.Helper()
+	fields := entry.ContextMap()
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("log field %s = %v (%T), want %v (%T)", key, fields[key], fields[key], value, value)
+		}
+	}
+	if _, ok := fields["duration_ms"]; !ok {
+		t.Error("log line has no duration_ms")
+	}
+}
+
+func TestTransferLogsOperation(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, recipient := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	logs := observeLogs(t)
+	client := erc20.NewClient(conn)
+
+	_, err := client.Transfer(erc20.WithRequestID(ctx, "req-123"), tokenID, owner, recipient, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := logs.FilterField(zap.String("op", "transfer")).AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("transfer logged %d operation lines, want 1", len(entries))
+	}
+	if entries[0].Level != zapcore.InfoLevel {
+		t.Errorf("level = %s, want info", entries[0].Level)
+	}
+	wantFields(t, entries[0], map[string]interface{}{
+		"request_id": "req-123",
+		"op":         "transfer",
+		"token_id":   tokenID.String(),
+		"amount":     int64(25),
+		"outcome":    "success",
+	})
+}
+
+func TestFailedTransferLogsOperation(t *testing.T) {
+	config, err := pgxpool.ParseConfig("postgres://erc20@127.0.0.1:1/erc20?connect_timeout=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.LazyConnect = true
+	conn, err := pgxpool.ConnectConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	logs := observeLogs(t)
+	client := erc20.NewClient(conn)
+	tokenID := uuid.Must(uuid.NewV4())
+
+	_, err = client.Transfer(erc20.WithRequestID(ctx, "req-456"), tokenID, newAddress(t), newAddress(t), 25)
+	if err == nil {
+		t.Fatal("Transfer against an unreachable database succeeded")
+	}
+	entries := logs.FilterField(zap.String("op", "transfer")).AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("transfer logged %d operation lines, want 1", len(entries))
+	}
+	if entries[0].Level != zapcore.WarnLevel {
+		t.Errorf("level = %s, want warn", entries[0].Level)
+	}
+	wantFields(t, entries[0], map[string]interface{}{
+		"request_id": "req-456",
+		"outcome":    "error",
+	})
+}
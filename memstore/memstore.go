@@ -0,0 +1,329 @@
+// Package memstore is an in-memory implementation of erc20.DB
+//
+// It models tokens, balances and allowances in Go maps with the same semantics as the
+// postgres backed erc20.Client, for unit tests and local development. erc20test.RunConformance
+// checks the two agree.
+// It is not for production: nothing is persisted and it does not scale past one process.
+// Fees, caps, roles and the other per token policies of the postgres ledger are not modelled.
+package memstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"erc20"
+
+	"github.com/gofrs/uuid"
+	"github.com/ninja-software/terror/v2"
+)
+
+var _ erc20.DB = (*Store)(nil)
+
+// ErrSymbolExists is returned when creating a token with a symbol already taken in its account book
+var ErrSymbolExists = errors.New("memstore: symbol already exists")
+
+type allowanceKey struct {
+	owner   erc20.Address
+	spender erc20.Address
+}
+
+type token struct {
+	accountBookID uuid.UUID
+	owner         erc20.Address
+	name          string
+	symbol        string
+	decimals      int
+	totalSupply   int
+	paused        bool
+	deleted       bool
+	balances      map[erc20.Address]int
+	allowances    map[allowanceKey]int
+}
+
+// Store holds the ledger in memory
+// Safe for concurrent use, every operation is all or nothing
+type Store struct {
+	mu     sync.Mutex
+	tokens map[uuid.UUID]*token
+}
+
+// New creates an empty store
+func New() *Store {
+	return &Store{
+		tokens: map[uuid.UUID]*token{},
+	}
+}
+
+// token returns the token, deleted or not
+func (s *Store) token(tokenID uuid.UUID) (*token, error) {
+	t, ok := s.tokens[tokenID]
+	if !ok {
+		return nil, terror.Error(erc20.ErrTokenNotFound, "Token not found")
+	}
+	return t, nil
+}
+
+// activeToken returns the token if its balances may change
+// ErrTokenNotFound once deleted and ErrPaused while paused
+func (s *Store) activeToken(tokenID uuid.UUID) (*token, error) {
+	t, ok := s.tokens[tokenID]
+	if !ok || t.deleted {
+		return nil, terror.Error(erc20.ErrTokenNotFound, "Token not found")
+	}
+	if t.paused {
+		return nil, terror.Error(erc20.ErrPaused, "Token is paused")
+	}
+	return t, nil
+}
+
+// move debits amount from sender and credits it to recipient in balances
+func move(balances map[erc20.Address]int, sender erc20.Address, recipient erc20.Address, amount int) error {
+	if amount < 0 {
+		return terror.Error(erc20.ErrInvalidAmount, "Amount must not be negative")
+	}
+	if balances[sender] < amount {
+		return terror.Error(erc20.ErrInsufficientBalance, "Could not update balances")
+	}
+	balances[sender] -= amount
+	balances[recipient] += amount
+	return nil
+}
+
+// Factory creates a new token administered by owner in an account book and returns its ID
+// The initial supply is minted to owner
+func (s *Store) Factory(ctx context.Context, accountBookID uuid.UUID, owner erc20.Address, name string, symbol string, decimals int, totalSupply int) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := erc20.ValidateToken(name, symbol, decimals)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if totalSupply < 0 {
+		return uuid.Nil, terror.Error(erc20.ErrInvalidAmount, "Could not create token")
+	}
+	symbol = erc20.NormalizeSymbol(symbol)
+	for _, t := range s.tokens {
+		if t.accountBookID == accountBookID && t.symbol == symbol {
+			return uuid.Nil, terror.Error(ErrSymbolExists, "Could not create token")
+		}
+	}
+	tokenID, err := uuid.NewV4()
+	if err != nil {
+		return uuid.Nil, terror.Error(err, "Could not create token")
+	}
+	s.tokens[tokenID] = &token{
+		accountBookID: accountBookID,
+		owner:         owner,
+		name:          name,
+		symbol:        symbol,
+		decimals:      decimals,
+		totalSupply:   totalSupply,
+		balances:      map[erc20.Address]int{owner: totalSupply},
+		allowances:    map[allowanceKey]int{},
+	}
+	return tokenID, nil
+}
+
+// TokenIDBySymbol retrieves the token ID given its symbol
+// Case insensitive, deleted tokens are ignored. Returns ErrAmbiguousSymbol if more than one account book has a token with the symbol
+func (s *Store) TokenIDBySymbol(ctx context.Context, symbol string) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	symbol = erc20.NormalizeSymbol(symbol)
+	found := uuid.Nil
+	for tokenID, t := range s.tokens {
+		if t.deleted || t.symbol != symbol {
+			continue
+		}
+		if found != uuid.Nil {
+			return uuid.Nil, terror.Error(erc20.ErrAmbiguousSymbol, "Token symbol is ambiguous")
+		}
+		found = tokenID
+	}
+	if found == uuid.Nil {
+		return uuid.Nil, terror.Error(erc20.ErrTokenNotFound, "Token not found")
+	}
+	return found, nil
+}
+
+// TotalSupply of the token
+func (s *Store) TotalSupply(ctx context.Context, tokenID uuid.UUID) (erc20.Amount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.token(tokenID)
+	if err != nil {
+		return 0, err
+	}
+	return erc20.Amount(t.totalSupply), nil
+}
+
+// BalanceOf an address
+// Unknown addresses hold zero
+func (s *Store) BalanceOf(ctx context.Context, tokenID uuid.UUID, owner erc20.Address) (erc20.Amount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.token(tokenID)
+	if err != nil {
+		return 0, err
+	}
+	return erc20.Amount(t.balances[owner]), nil
+}
+
+// Transfer moves balance between accounts
+func (s *Store) Transfer(ctx context.Context, tokenID uuid.UUID, sender erc20.Address, recipient erc20.Address, amount erc20.Amount) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.activeToken(tokenID)
+	if err != nil {
+		return false, err
+	}
+	err = move(t.balances, sender, recipient, amount.Int())
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TransferMany applies every leg or none of them
+// If any leg would overdraw its sender the whole batch is rejected
+func (s *Store) TransferMany(ctx context.Context, tokenID uuid.UUID, legs []erc20.TransferLeg) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.activeToken(tokenID)
+	if err != nil {
+		return err
+	}
+	balances := make(map[erc20.Address]int, len(t.balances))
+	for owner, balance := range t.balances {
+		balances[owner] = balance
+	}
+	for _, leg := range legs {
+		err = move(balances, leg.From, leg.To, leg.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	t.balances = balances
+	return nil
+}
+
+// Approve lets spender move up to amount of the owner's balance
+// Replaces any existing allowance
+func (s *Store) Approve(ctx context.Context, tokenID uuid.UUID, owner, spender erc20.Address, amount int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if amount < 0 {
+		return terror.Error(erc20.ErrInvalidAmount, "Allowance can not be negative")
+	}
+	t, err := s.token(tokenID)
+	if err != nil {
+		return err
+	}
+	t.allowances[allowanceKey{owner: owner, spender: spender}] = amount
+	return nil
+}
+
+// Allowance returns how much spender may still move of the owner's balance
+func (s *Store) Allowance(ctx context.Context, tokenID uuid.UUID, owner, spender erc20.Address) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.token(tokenID)
+	if err != nil {
+		return 0, err
+	}
+	return t.allowances[allowanceKey{owner: owner, spender: spender}], nil
+}
+
+// TransferFrom moves balance from sender to recipient on behalf of spender
+// The spender's allowance is reduced by amount
+func (s *Store) TransferFrom(ctx context.Context, tokenID uuid.UUID, spender, sender, recipient erc20.Address, amount int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.token(tokenID)
+	if err != nil {
+		return false, err
+	}
+	key := allowanceKey{owner: sender, spender: spender}
+	if t.allowances[key] < amount {
+		return false, terror.Error(erc20.ErrInsufficientAllowance, "Could not transfer")
+	}
+	t, err = s.activeToken(tokenID)
+	if err != nil {
+		return false, err
+	}
+	err = move(t.balances, sender, recipient, amount)
+	if err != nil {
+		return false, err
+	}
+	t.allowances[key] -= amount
+	return true, nil
+}
+
+// Mint new tokens to an address
+func (s *Store) Mint(ctx context.Context, tokenID uuid.UUID, account erc20.Address, amount erc20.Amount) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if amount < 0 {
+		return terror.Error(erc20.ErrInvalidAmount, "Amount must not be negative")
+	}
+	t, err := s.activeToken(tokenID)
+	if err != nil {
+		return err
+	}
+	t.balances[account] += amount.Int()
+	t.totalSupply += amount.Int()
+	return nil
+}
+
+// Burn existing tokens from an address
+func (s *Store) Burn(ctx context.Context, tokenID uuid.UUID, account erc20.Address, amount erc20.Amount) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.activeToken(tokenID)
+	if err != nil {
+		return err
+	}
+	if amount < 0 {
+		return terror.Error(erc20.ErrInvalidAmount, "Amount must not be negative")
+	}
+	if t.balances[account] < amount.Int() {
+		return terror.Error(erc20.ErrInsufficientBalance, "Could not update balances")
+	}
+	t.balances[account] -= amount.Int()
+	t.totalSupply -= amount.Int()
+	return nil
+}
+
+// Pause stops every balance change of a token until Unpause
+func (s *Store) Pause(ctx context.Context, tokenID uuid.UUID) error {
+	return s.setPaused(tokenID, true)
+}
+
+// Unpause lets balances of a paused token change again
+func (s *Store) Unpause(ctx context.Context, tokenID uuid.UUID) error {
+	return s.setPaused(tokenID, false)
+}
+
+func (s *Store) setPaused(tokenID uuid.UUID, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[tokenID]
+	if !ok || t.deleted {
+		return terror.Error(erc20.ErrTokenNotFound, "Token not found")
+	}
+	t.paused = paused
+	return nil
+}
+
+// DeleteToken retires a token, its balances stay readable but can no longer change
+func (s *Store) DeleteToken(ctx context.Context, tokenID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[tokenID]
+	if !ok || t.deleted {
+		return terror.Error(erc20.ErrTokenNotFound, "Token not found")
+	}
+	t.deleted = true
+	return nil
+}
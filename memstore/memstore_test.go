@@ -0,0 +1,17 @@
+package memstore_test
+
+import (
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+	"erc20/memstore"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestConformance(t *testing.T) {
+	erc20test.RunConformance(t, func(t *testing.T) (erc20.DB, uuid.UUID) {
+		return memstore.New(), uuid.Must(uuid.NewV4())
+	})
+}
@@ -0,0 +1,140 @@
+package erc20
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// MerkleLeaf returns the leaf hash committing to a single holder balance
+// Leaves and inner nodes are domain separated so a node can not pass as a leaf
+func MerkleLeaf(owner Address, balance int) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(uuid.UUID(owner).Bytes())
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(balance))
+	h.Write(buf[:])
+	return h.Sum(nil)
+}
+
+// merkleNode hashes a pair of children in sorted order, so proofs need no left or right flags
+func merkleNode(a, b []byte) []byte {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(a)
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// merkleLevels builds every level of the tree from the leaves up, the last level holds the root
+// An odd node out is carried up to the next level unchanged
+func merkleLevels(leaves [][]byte) [][][]byte {
+	levels := [][][]byte{leaves}
+	for level := leaves; len(level) > 1; {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, merkleNode(level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// holderLeaves returns the non-zero balances of a token in address order with their leaf hashes
+// Escrow addresses are left out
+func holderLeaves(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) ([]HolderBalance, [][]byte, error) {
+	q := `SELECT owner, balance FROM addresses WHERE token_id = $1 AND balance > 0 AND ` + notEscrowQ + ` ORDER BY owner`
+	rows, err := conn.Query(ctx, q, tokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	holders := []HolderBalance{}
+	leaves := [][]byte{}
+	for rows.Next() {
+		var holder HolderBalance
+		err = rows.Scan(&holder.Address, &holder.Balance)
+		if err != nil {
+			return nil, nil, err
+		}
+		holders = append(holders, holder)
+		leaves = append(leaves, MerkleLeaf(holder.Address, holder.Balance))
+	}
+	if rows.Err() != nil {
+		return nil, nil, rows.Err()
+	}
+	return holders, leaves, nil
+}
+
+// BalancesMerkleRoot returns the root of a Merkle tree over every non-zero balance of a token
+// Leaves are MerkleLeaf in address order. A token with no holders has a nil root
+func BalancesMerkleRoot(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) ([]byte, error) {
+	_, leaves, err := holderLeaves(ctx, conn, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return nil, terror.Error(err, "Could not get balances")
+	}
+	if len(leaves) == 0 {
+		return nil, nil
+	}
+	levels := merkleLevels(leaves)
+	return levels[len(levels)-1][0], nil
+}
+
+// BalanceProof returns the balance of owner and the sibling hashes proving it is included in BalancesMerkleRoot
+func BalanceProof(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner Address) (int, [][]byte, error) {
+	holders, leaves, err := holderLeaves(ctx, conn, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "owner", owner)
+		return 0, nil, terror.Error(err, "Could not get balances")
+	}
+	index := -1
+	for i, holder := range holders {
+		if holder.Address == owner {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return 0, nil, terror.Error(ErrAddressNotFound, "Address holds no balance")
+	}
+	return holders[index].Balance, merkleProof(leaves, index), nil
+}
+
+// merkleProof returns the sibling hashes linking leaves[index] to the root
+// A level where the node is the odd one out contributes no sibling
+func merkleProof(leaves [][]byte, index int) [][]byte {
+	proof := [][]byte{}
+	levels := merkleLevels(leaves)
+	for _, level := range levels[:len(levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof = append(proof, level[sibling])
+		}
+		index /= 2
+	}
+	return proof
+}
+
+// VerifyProof reports whether proof links leaf to root
+func VerifyProof(root, leaf []byte, proof [][]byte) bool {
+	node := leaf
+	for _, sibling := range proof {
+		node = merkleNode(node, sibling)
+	}
+	return bytes.Equal(node, root)
+}
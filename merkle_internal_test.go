@@ -0,0 +1,36 @@
+package erc20
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestMerkleProof(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		leaves := make([][]byte, n)
+		for i := range leaves {
+			leaves[i] = MerkleLeaf(Address(uuid.Must(uuid.NewV4())), i+1)
+		}
+		levels := merkleLevels(leaves)
+		root := levels[len(levels)-1][0]
+		for i, leaf := range leaves {
+			proof := merkleProof(leaves, i)
+			if !VerifyProof(root, leaf, proof) {
+				t.Errorf("proof of leaf %d of %d does not verify", i, n)
+			}
+			tampered := MerkleLeaf(Address(uuid.Must(uuid.NewV4())), i+1)
+			if VerifyProof(root, tampered, proof) {
+				t.Errorf("tampered leaf %d of %d verifies", i, n)
+			}
+		}
+	}
+}
+
+func TestMerkleNodeOrder(t *testing.T) {
+	a := MerkleLeaf(Address(uuid.Must(uuid.NewV4())), 1)
+	b := MerkleLeaf(Address(uuid.Must(uuid.NewV4())), 2)
+	if string(merkleNode(a, b)) != string(merkleNode(b, a)) {
+		t.Error("merkleNode depends on the order of its children")
+	}
+}
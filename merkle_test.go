@@ -0,0 +1,53 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestBalanceProof(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	holders := []erc20.Address{owner}
+	for i := 1; i <= 4; i++ {
+		holder := newAddress(t)
+		transfer(t, conn, tokenID, owner, holder, i*10)
+		holders = append(holders, holder)
+	}
+
+	root, err := erc20.BalancesMerkleRoot(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, holder := range holders {
+		balance, proof, err := erc20.BalanceProof(ctx, conn, tokenID, holder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if balance != balanceOf(t, conn, tokenID, holder) {
+			t.Errorf("BalanceProof balance = %d, want the holder's balance", balance)
+		}
+		if !erc20.VerifyProof(root, erc20.MerkleLeaf(holder, balance), proof) {
+			t.Error("proof does not verify against the root")
+		}
+		if erc20.VerifyProof(root, erc20.MerkleLeaf(holder, balance+1), proof) {
+			t.Error("proof verifies a tampered balance")
+		}
+	}
+
+	_, _, err = erc20.BalanceProof(ctx, conn, tokenID, newAddress(t))
+	if !errors.Is(err, erc20.ErrAddressNotFound) {
+		t.Errorf("BalanceProof of a non-holder error = %v, want ErrAddressNotFound", err)
+	}
+	again, err := erc20.BalancesMerkleRoot(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(root) {
+		t.Error("BalancesMerkleRoot is not deterministic")
+	}
+}
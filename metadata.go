@@ -0,0 +1,60 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// SetMetadata replaces the arbitrary attributes stored on a token
+// e.g. icon URL, website, category or chain id
+func SetMetadata(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, md map[string]interface{}) error {
+	if md == nil {
+		md = map[string]interface{}{}
+	}
+	q := `UPDATE tokens SET metadata = $1, updated_at = now() WHERE id = $2`
+	tag, err := conn.Exec(ctx, q, md, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return terror.Error(err, "Could not set metadata")
+	}
+	if tag.RowsAffected() == 0 {
+		return terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	return nil
+}
+
+// GetMetadata returns the arbitrary attributes stored on a token
+func GetMetadata(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) (map[string]interface{}, error) {
+	q := `SELECT metadata FROM tokens WHERE id = $1`
+	md := map[string]interface{}{}
+	err := conn.QueryRow(ctx, q, tokenID).Scan(&md)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return nil, terror.Error(err, "Could not get metadata")
+	}
+	return md, nil
+}
+
+// TokensByMetadata returns the tokens in an account book whose metadata has key set to value
+func TokensByMetadata(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID, key string, value string) ([]Token, error) {
+	q := `SELECT ` + tokenColumns + ` FROM tokens WHERE account_book_id = $1 AND metadata @> $2 AND deleted_at IS NULL ORDER BY symbol`
+	rows, err := conn.Query(ctx, q, accountBookID, map[string]string{key: value})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "key", key)
+		return nil, terror.Error(err, "Could not get tokens")
+	}
+	tokens, err := scanTokens(rows)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "key", key)
+		return nil, terror.Error(err, "Could not get tokens")
+	}
+	return tokens, nil
+}
@@ -0,0 +1,57 @@
+package erc20_test
+
+import (
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestMetadata(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	bookID := erc20test.NewAccountBook(t, conn)
+	stableID, err := erc20.Factory(ctx, conn, bookID, owner, "Stable", "STB", 6, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gameID, err := erc20.Factory(ctx, conn, bookID, owner, "Game", "GME", 18, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.SetMetadata(ctx, conn, stableID, map[string]interface{}{"category": "stablecoin", "website": "https://stable.example", "chain_id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.SetMetadata(ctx, conn, gameID, map[string]interface{}{"category": "gaming"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	md, err := erc20.GetMetadata(ctx, conn, stableID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md["category"] != "stablecoin" || md["website"] != "https://stable.example" {
+		t.Errorf("GetMetadata = %v", md)
+	}
+	if md["chain_id"] != float64(1) {
+		t.Errorf("chain_id = %v, want 1", md["chain_id"])
+	}
+
+	tokens, err := erc20.TokensByMetadata(ctx, conn, bookID, "category", "stablecoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].ID != stableID {
+		t.Errorf("TokensByMetadata(category=stablecoin) = %v, want only the stable token", tokens)
+	}
+	tokens, err = erc20.TokensByMetadata(ctx, conn, bookID, "category", "defi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 0 {
+		t.Errorf("TokensByMetadata(category=defi) returned %d tokens, want none", len(tokens))
+	}
+}
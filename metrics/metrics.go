@@ -0,0 +1,70 @@
+// Package metrics exposes Prometheus instrumentation for ledger operations
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics holds the counters and histograms recorded for each ledger operation
+type Metrics struct {
+	operations *prometheus.CounterVec
+	failures   *prometheus.CounterVec
+	latency    *prometheus.HistogramVec
+}
+
+// New creates an unregistered set of ledger metrics
+// Register them with Collector()
+func New() *Metrics {
+	return &Metrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "erc20",
+			Name:      "operations_total",
+			Help:      "Number of successful ledger operations.",
+		}, []string{"op"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "erc20",
+			Name:      "operation_failures_total",
+			Help:      "Number of failed ledger operations by error type.",
+		}, []string{"op", "error"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "erc20",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of ledger operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"op"}),
+	}
+}
+
+// Observe records the outcome and duration of a single operation
+// errType is empty on success
+func (m *Metrics) Observe(op string, started time.Time, errType string) {
+	m.latency.WithLabelValues(op).Observe(time.Since(started).Seconds())
+	if errType != "" {
+		m.failures.WithLabelValues(op, errType).Inc()
+		return
+	}
+	m.operations.WithLabelValues(op).Inc()
+}
+
+// Collector returns a prometheus.Collector covering every ledger metric
+func (m *Metrics) Collector() prometheus.Collector {
+	return collector{m}
+}
+
+type collector struct {
+	m *Metrics
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	c.m.operations.Describe(ch)
+	c.m.failures.Describe(ch)
+	c.m.latency.Describe(ch)
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	c.m.operations.Collect(ch)
+	c.m.failures.Collect(ch)
+	c.m.latency.Collect(ch)
+}
@@ -0,0 +1,53 @@
+package metrics_test
+
+import (
+	"testing"
+	"time"
+
+	"erc20/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// counter returns the summed value of every series of the named counter with the given op label
+func counter(t *testing.T, reg *prometheus.Registry, name, op string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "op" && label.GetValue() == op {
+					total += metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return total
+}
+
+func TestObserve(t *testing.T) {
+	m := metrics.New()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m.Collector())
+
+	m.Observe("transfer", time.Now(), "")
+	m.Observe("transfer", time.Now(), "")
+	m.Observe("transfer", time.Now(), "insufficient_balance")
+
+	if got := counter(t, reg, "erc20_operations_total", "transfer"); got != 2 {
+		t.Errorf("erc20_operations_total{op=transfer} = %v, want 2", got)
+	}
+	if got := counter(t, reg, "erc20_operation_failures_total", "transfer"); got != 1 {
+		t.Errorf("erc20_operation_failures_total{op=transfer} = %v, want 1", got)
+	}
+	if got := counter(t, reg, "erc20_operations_total", "mint"); got != 0 {
+		t.Errorf("erc20_operations_total{op=mint} = %v, want 0", got)
+	}
+}
@@ -0,0 +1,278 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrIrreversibleMigration is returned when rolling back a step that has no Down SQL
+var ErrIrreversibleMigration = errors.New("ERC20: migration can not be rolled back")
+
+// ErrUnsafeDrop is returned by DropSchema on a database not named as a test or development one
+var ErrUnsafeDrop = errors.New("ERC20: refusing to drop schema")
+
+// MigrationStep is a single versioned schema change
+// Down reverses SQL for Rollback
+type MigrationStep struct {
+	Version int
+	Name    string
+	SQL     string
+	Down    string
+}
+
+// Migrations are applied in order by Migrate
+// Append new steps with the next version, never edit a step that has shipped
+var Migrations = []MigrationStep{
+	{Version: 1, Name: "initial schema", SQL: Migration, Down: initialSchemaDown},
+	{Version: 2, Name: "stakes", SQL: `
+CREATE TABLE stakes (
+	id UUID NOT NULL PRIMARY KEY DEFAULT gen_random_uuid (),
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	account UUID NOT NULL,
+	amount INTEGER NOT NULL DEFAULT 0 CHECK (amount >= 0),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX idx_stakes_token_account ON stakes (token_id, account);
+`, Down: `DROP TABLE stakes;`},
+	{Version: 3, Name: "spend limits", SQL: `
+CREATE TABLE spend_limits (
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	owner UUID NOT NULL,
+	spender UUID NOT NULL,
+	amount INTEGER NOT NULL CHECK (amount >= 0),
+	window_length INTERVAL NOT NULL,
+	window_start TIMESTAMPTZ NOT NULL DEFAULT now(),
+	spent INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (token_id, owner, spender)
+);
+`, Down: `DROP TABLE spend_limits;`},
+	{Version: 4, Name: "address versions", SQL: `
+ALTER TABLE addresses ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
+CREATE FUNCTION addresses_bump_version() RETURNS trigger AS $$
+BEGIN
+	NEW.version := OLD.version + 1;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER addresses_bump_version BEFORE UPDATE OF balance ON addresses
+	FOR EACH ROW EXECUTE FUNCTION addresses_bump_version();
+`, Down: `
+DROP TRIGGER addresses_bump_version ON addresses;
+DROP FUNCTION addresses_bump_version();
+ALTER TABLE addresses DROP COLUMN version;
+`},
+	// Fails while an account book holds two live tokens of the same name, rename one before migrating
+	{Version: 5, Name: "unique token names", SQL: `
+CREATE UNIQUE INDEX idx_tokens_book_name ON tokens (account_book_id, name) WHERE deleted_at IS NULL;
+`, Down: `DROP INDEX idx_tokens_book_name;`},
+	{Version: 6, Name: "token tags", SQL: `
+CREATE TABLE token_tags (
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	tag TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (token_id, tag)
+);
+CREATE INDEX idx_token_tags_tag ON token_tags (tag);
+`, Down: `DROP TABLE token_tags;`},
+	{Version: 7, Name: "mint caps", SQL: `
+ALTER TABLE tokens ADD COLUMN max_mint_per_address INTEGER CHECK (max_mint_per_address >= 0);
+CREATE TABLE mint_totals (
+	token_id UUID NOT NULL REFERENCES tokens(id),
+	account UUID NOT NULL,
+	minted INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (token_id, account)
+);
+INSERT INTO mint_totals (token_id, account, minted)
+SELECT token_id, recipient, SUM(amount) FROM ledger_events WHERE event_type = 'mint' GROUP BY token_id, recipient;
+`, Down: `
+DROP TABLE mint_totals;
+ALTER TABLE tokens DROP COLUMN max_mint_per_address;
+`},
+	// Events of one transaction share created_at, seq keeps them in the order they were recorded
+	{Version: 8, Name: "ledger event sequence", SQL: `
+ALTER TABLE ledger_events ADD COLUMN seq BIGSERIAL;
+CREATE INDEX idx_ledger_events_token_seq ON ledger_events (token_id, seq);
+`, Down: `ALTER TABLE ledger_events DROP COLUMN seq;`},
+	// Lets each account book issue its own token of a symbol, for transfers between books
+	{Version: 9, Name: "symbols unique per book", SQL: `
+ALTER TABLE tokens DROP CONSTRAINT tokens_symbol_key;
+CREATE UNIQUE INDEX idx_tokens_book_symbol ON tokens (account_book_id, symbol);
+`, Down: `
+DROP INDEX idx_tokens_book_symbol;
+ALTER TABLE tokens ADD CONSTRAINT tokens_symbol_key UNIQUE (symbol);
+`},
+	{Version: 10, Name: "external ids", SQL: `
+ALTER TABLE tokens ADD COLUMN external_id TEXT;
+CREATE UNIQUE INDEX idx_tokens_external_id ON tokens (external_id);
+ALTER TABLE addresses ADD COLUMN external_id TEXT;
+CREATE UNIQUE INDEX idx_addresses_token_external_id ON addresses (token_id, external_id);
+`, Down: `
+ALTER TABLE addresses DROP COLUMN external_id;
+ALTER TABLE tokens DROP COLUMN external_id;
+`},
+	{Version: 11, Name: "max transfer", SQL: `
+ALTER TABLE tokens ADD COLUMN max_transfer INTEGER CHECK (max_transfer >= 0);
+`, Down: `ALTER TABLE tokens DROP COLUMN max_transfer;`},
+	{Version: 12, Name: "permit key versions", SQL: `
+ALTER TABLE permit_keys ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+`, Down: `ALTER TABLE permit_keys DROP COLUMN version;`},
+}
+
+// initialSchemaDown drops everything Migration creates except the extensions, which other schemas may share
+const initialSchemaDown = `
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
+DROP TABLE mint_approvals;
+DROP TABLE mint_proposals;
+DROP TABLE token_roles;
+DROP TABLE permit_nonces;
+DROP TABLE permit_keys;
+DROP TABLE holds;
+DROP TABLE scheduled_transfers;
+DROP TABLE vesting_schedules;
+DROP TABLE snapshot_balances;
+DROP FUNCTION snapshot_balances_immutable();
+DROP TABLE snapshots;
+DROP TABLE mint_requests;
+DROP TABLE allowances;
+DROP TABLE ledger_events;
+DROP TABLE addresses;
+DROP TABLE tokens;
+DROP TABLE account_books;
+`
+
+// migrationLockID keys the advisory lock that stops concurrent Migrate calls applying the same step twice
+const migrationLockID = 0x65726332
+
+// Migrate applies every step of Migrations newer than the current version, each in its own transaction
+// Already applied steps are skipped so it is safe to run on every start
+func Migrate(ctx context.Context, conn *pgxpool.Pool) error {
+	q := `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER NOT NULL PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+	_, err := conn.Exec(ctx, q)
+	if err != nil {
+		logger(ctx).Errorw(err.Error())
+		return terror.Error(err, "Could not create schema_migrations")
+	}
+	for _, step := range Migrations {
+		step := step
+		err = beginFunc(ctx, conn, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID)
+			if err != nil {
+				return err
+			}
+			var applied bool
+			err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, step.Version).Scan(&applied)
+			if err != nil || applied {
+				return err
+			}
+			_, err = tx.Exec(ctx, step.SQL)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, step.Version, step.Name)
+			return err
+		})
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "version", step.Version, "name", step.Name)
+			return terror.Error(err, fmt.Sprintf("Could not apply migration %d", step.Version))
+		}
+	}
+	return nil
+}
+
+// Rollback reverts every applied step newer than toVersion, newest first, each in its own transaction
+// Rolling back to zero leaves an empty schema
+func Rollback(ctx context.Context, conn *pgxpool.Pool, toVersion int) error {
+	if toVersion < 0 {
+		return terror.Error(fmt.Errorf("invalid target version %d", toVersion), "Can not roll back below version 0")
+	}
+	current, err := CurrentVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	for i := len(Migrations) - 1; i >= 0; i-- {
+		step := Migrations[i]
+		if step.Version <= toVersion || step.Version > current {
+			continue
+		}
+		err = beginFunc(ctx, conn, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID)
+			if err != nil {
+				return err
+			}
+			tag, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, step.Version)
+			if err != nil || tag.RowsAffected() == 0 {
+				return err
+			}
+			if step.Down == "" {
+				return ErrIrreversibleMigration
+			}
+			_, err = tx.Exec(ctx, step.Down)
+			return err
+		})
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "version", step.Version, "name", step.Name)
+			return terror.Error(err, fmt.Sprintf("Could not roll back migration %d", step.Version))
+		}
+	}
+	return nil
+}
+
+// DropSchema rolls back every migration and removes schema_migrations, for test teardown and local development
+// Refuses with ErrUnsafeDrop unless the current database name ends in _test or _dev
+func DropSchema(ctx context.Context, conn *pgxpool.Pool) error {
+	var name string
+	err := conn.QueryRow(ctx, `SELECT current_database()`).Scan(&name)
+	if err != nil {
+		logger(ctx).Errorw(err.Error())
+		return terror.Error(err, "Could not get database name")
+	}
+	if !strings.HasSuffix(name, "_test") && !strings.HasSuffix(name, "_dev") {
+		return terror.Error(ErrUnsafeDrop, fmt.Sprintf("Database %s is not a test or development database", name))
+	}
+	err = Rollback(ctx, conn, 0)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx, `DROP TABLE IF EXISTS schema_migrations`)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "database", name)
+		return terror.Error(err, "Could not drop schema_migrations")
+	}
+	return nil
+}
+
+// CurrentVersion returns the latest applied migration version, zero for a database Migrate has never run on
+func CurrentVersion(ctx context.Context, conn *pgxpool.Pool) (int, error) {
+	q := `SELECT to_regclass('schema_migrations') IS NOT NULL`
+	var exists bool
+	err := conn.QueryRow(ctx, q).Scan(&exists)
+	if err != nil {
+		logger(ctx).Errorw(err.Error())
+		return 0, terror.Error(err, "Could not get schema version")
+	}
+	if !exists {
+		return 0, nil
+	}
+	var version int
+	err = conn.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		logger(ctx).Errorw(err.Error())
+		return 0, terror.Error(err, "Could not get schema version")
+	}
+	return version, nil
+}
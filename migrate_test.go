@@ -0,0 +1,176 @@
+package erc20_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func latestVersion() int {
+	return erc20.Migrations[len(erc20.Migrations)-1].Version
+}
+
+func currentVersion(t *testing.T, conn *pgxpool.Pool) int {
+	t.Helper()
+	version, err := erc20.CurrentVersion(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func hasColumn(t *testing.T, conn *pgxpool.Pool, table, column string) bool {
+	t.Helper()
+	q := `SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)`
+	var exists bool
+	err := conn.QueryRow(ctx, q, table, column).Scan(&exists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exists
+}
+
+func hasTable(t *testing.T, conn *pgxpool.Pool, table string) bool {
+	t.Helper()
+	var exists bool
+	err := conn.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exists
+}
+
+func TestMigrate(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	err := erc20.DropSchema(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := currentVersion(t, conn); got != 0 {
+		t.Fatalf("CurrentVersion of an empty database = %d, want 0", got)
+	}
+	if hasTable(t, conn, "tokens") {
+		t.Fatal("DropSchema left the tokens table")
+	}
+
+	err = erc20.Migrate(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := currentVersion(t, conn); got != latestVersion() {
+		t.Errorf("CurrentVersion = %d, want %d", got, latestVersion())
+	}
+	var applied time.Time
+	err = conn.QueryRow(ctx, `SELECT max(applied_at) FROM schema_migrations`).Scan(&applied)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.Migrate(ctx, conn)
+	if err != nil {
+		t.Fatalf("re-running Migrate: %v", err)
+	}
+	var steps int
+	var reapplied time.Time
+	err = conn.QueryRow(ctx, `SELECT count(*), max(applied_at) FROM schema_migrations`).Scan(&steps, &reapplied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps != len(erc20.Migrations) || !reapplied.Equal(applied) {
+		t.Errorf("re-running Migrate changed schema_migrations, %d steps applied at %v", steps, reapplied)
+	}
+	newToken(t, conn, newAddress(t), 100)
+}
+
+func TestRollback(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	if !hasColumn(t, conn, "tokens", "max_transfer") || !hasColumn(t, conn, "permit_keys", "version") {
+		t.Fatal("migrated schema is missing the columns of versions 11 and 12")
+	}
+
+	err := erc20.Rollback(ctx, conn, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := currentVersion(t, conn); got != 10 {
+		t.Errorf("CurrentVersion after rollback = %d, want 10", got)
+	}
+	if hasColumn(t, conn, "tokens", "max_transfer") {
+		t.Error("rollback to 10 left tokens.max_transfer from version 11")
+	}
+	if hasColumn(t, conn, "permit_keys", "version") {
+		t.Error("rollback to 10 left permit_keys.version from version 12")
+	}
+	if !hasTable(t, conn, "stakes") {
+		t.Error("rollback to 10 dropped the stakes table from version 2")
+	}
+
+	err = erc20.Rollback(ctx, conn, -1)
+	if err == nil {
+		t.Error("Rollback below version 0 succeeded")
+	}
+	if got := currentVersion(t, conn); got != 10 {
+		t.Errorf("CurrentVersion after a rejected rollback = %d, want 10", got)
+	}
+
+	err = erc20.Migrate(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasColumn(t, conn, "tokens", "max_transfer") {
+		t.Error("Migrate after rollback did not restore tokens.max_transfer")
+	}
+}
+
+func TestDropSchema(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	transfer(t, conn, tokenID, owner, newAddress(t), 100)
+
+	err := erc20.DropSchema(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"account_books", "tokens", "addresses", "ledger_events", "allowances", "schema_migrations"} {
+		if hasTable(t, conn, table) {
+			t.Errorf("DropSchema left the %s table", table)
+		}
+	}
+	err = erc20.Migrate(ctx, conn)
+	if err != nil {
+		t.Fatalf("Migrate after DropSchema: %v", err)
+	}
+	newToken(t, conn, owner, 100)
+}
+
+func TestDropSchemaRefusesOtherDatabases(t *testing.T) {
+	url := os.Getenv(erc20test.DatabaseURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", erc20test.DatabaseURLEnv)
+	}
+	conn, err := pgxpool.Connect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var name string
+	err = conn.QueryRow(ctx, `SELECT current_database()`).Scan(&name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasSuffix(name, "_test") || strings.HasSuffix(name, "_dev") {
+		t.Skipf("%s names a database DropSchema would accept", erc20test.DatabaseURLEnv)
+	}
+	err = erc20.DropSchema(ctx, conn)
+	if !errors.Is(err, erc20.ErrUnsafeDrop) {
+		t.Errorf("DropSchema on %s error = %v, want ErrUnsafeDrop", name, err)
+	}
+}
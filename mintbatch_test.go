@@ -0,0 +1,120 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// mintItems returns n mints to fresh accounts, the first account listed twice
+func mintItems(t testing.TB, n int) []erc20.MintItem {
+	t.Helper()
+	items := make([]erc20.MintItem, 0, n+1)
+	for i := 0; i < n; i++ {
+		items = append(items, erc20.MintItem{Account: newAddress(t), Amount: i%7 + 1})
+	}
+	return append(items, erc20.MintItem{Account: items[0].Account, Amount: 5})
+}
+
+// mintEach mints every item with its own Mint call
+func mintEach(t testing.TB, conn *pgxpool.Pool, tokenID uuid.UUID, items []erc20.MintItem) {
+	t.Helper()
+	for _, item := range items {
+		mint(t, conn, tokenID, item.Account, item.Amount)
+	}
+}
+
+func TestMintBatch(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, alice, bob := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+
+	err := erc20.MintBatch(ctx, conn, tokenID, []erc20.MintItem{
+		{Account: alice, Amount: 250},
+		{Account: bob, Amount: 40},
+		{Account: owner, Amount: 5},
+		{Account: alice, Amount: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 1005, alice: 251, bob: 40})
+	if got := totalSupply(t, conn, tokenID); got != 1296 {
+		t.Errorf("total supply = %d, want 1000 plus the batch sum of 296", got)
+	}
+	wantConserved(t, conn, tokenID)
+}
+
+func TestMintBatchMatchesMint(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	items := mintItems(t, 50)
+	batched := newToken(t, conn, newAddress(t), 0)
+	looped := newToken(t, conn, newAddress(t), 0)
+
+	err := erc20.MintBatch(ctx, conn, batched, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mintEach(t, conn, looped, items)
+
+	for _, item := range items {
+		got, want := balanceOf(t, conn, batched, item.Account), balanceOf(t, conn, looped, item.Account)
+		if got != want {
+			t.Errorf("MintBatch balance = %d, Mint balance = %d", got, want)
+		}
+	}
+	if got, want := totalSupply(t, conn, batched), totalSupply(t, conn, looped); got != want {
+		t.Errorf("MintBatch supply = %d, Mint supply = %d", got, want)
+	}
+	wantConserved(t, conn, batched)
+}
+
+func TestMintBatchAllOrNothing(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	tokenID := newToken(t, conn, newAddress(t), 0)
+	items := mintItems(t, 10)
+	err := erc20.SetMaxMintPerAddress(ctx, conn, tokenID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.MintBatch(ctx, conn, tokenID, append(items, erc20.MintItem{Account: items[1].Account, Amount: 10}))
+	if !errors.Is(err, erc20.ErrMintCapExceeded) {
+		t.Errorf("MintBatch over the mint cap = %v, want %v", err, erc20.ErrMintCapExceeded)
+	}
+	err = erc20.MintBatch(ctx, conn, tokenID, append(items, erc20.MintItem{Account: newAddress(t), Amount: -1}))
+	if !errors.Is(err, erc20.ErrInvalidAmount) {
+		t.Errorf("MintBatch with a negative amount = %v, want %v", err, erc20.ErrInvalidAmount)
+	}
+	if got := totalSupply(t, conn, tokenID); got != 0 {
+		t.Errorf("total supply after rejected batches = %d, want 0", got)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{items[0].Account: 0, items[1].Account: 0})
+}
+
+// airdropSize is how many recipients BenchmarkMintBatch mints to per iteration
+const airdropSize = 10000
+
+func BenchmarkMintBatch(b *testing.B) {
+	conn := erc20test.NewTestDB(b)
+	tokenID := newToken(b, conn, newAddress(b), 0)
+	items := mintItems(b, airdropSize)
+	b.Run("batched", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			err := erc20.MintBatch(ctx, conn, tokenID, items)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("loop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			mintEach(b, conn, tokenID, items)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrMintCapExceeded is returned when a mint would take an address past the token's per address cap
+var ErrMintCapExceeded = errors.New("ERC20: mint cap per address exceeded")
+
+// SetMaxMintPerAddress caps how much may ever be minted to a single address, to throttle faucets
+// The cap counts every mint to the address, including those before it was set. Zero removes the cap
+func SetMaxMintPerAddress(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, maxMint int) error {
+	if maxMint < 0 {
+		return terror.Error(ErrInvalidAmount, "Mint cap can not be negative")
+	}
+	q := `UPDATE tokens SET max_mint_per_address = NULLIF($1, 0), updated_at = now() WHERE id = $2`
+	tag, err := conn.Exec(ctx, q, maxMint, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "maxMint", maxMint)
+		return terror.Error(err, "Could not set mint cap")
+	}
+	if tag.RowsAffected() == 0 {
+		return terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	return nil
+}
+
+// trackMint adds amount to the account's cumulative minted total inside tx
+// Returns ErrMintCapExceeded if the total passes the token's cap. The upsert locks the total,
+// so concurrent mints to the same address can not both slip under the cap
+func trackMint(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, account Address, amount int) error {
+	q := `
+INSERT INTO mint_totals (token_id, account, minted) VALUES ($1, $2, $3)
+ON CONFLICT (token_id, account) DO UPDATE SET minted = mint_totals.minted + EXCLUDED.minted
+RETURNING minted, (SELECT max_mint_per_address FROM tokens WHERE id = $1)`
+	var minted int
+	var maxMint *int
+	err := tx.QueryRow(ctx, q, tokenID, account, amount).Scan(&minted, &maxMint)
+	if err != nil {
+		return err
+	}
+	if maxMint != nil && *maxMint > 0 && minted > *maxMint {
+		return ErrMintCapExceeded
+	}
+	return nil
+}
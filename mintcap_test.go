@@ -0,0 +1,40 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestMaxMintPerAddress(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	alice, bob := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, newAddress(t), 0)
+	err := erc20.SetMaxMintPerAddress(ctx, conn, tokenID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mint(t, conn, tokenID, alice, 60)
+	mint(t, conn, tokenID, alice, 40)
+	err = erc20.Mint(ctx, conn, tokenID, alice, 1)
+	if !errors.Is(err, erc20.ErrMintCapExceeded) {
+		t.Errorf("Mint over the cap error = %v, want ErrMintCapExceeded", err)
+	}
+	// The cap is per address
+	mint(t, conn, tokenID, bob, 100)
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{alice: 100, bob: 100})
+	if got := totalSupply(t, conn, tokenID); got != 200 {
+		t.Errorf("total supply = %d, want 200", got)
+	}
+
+	err = erc20.SetMaxMintPerAddress(ctx, conn, tokenID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mint(t, conn, tokenID, alice, 1)
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{alice: 101})
+	wantConserved(t, conn, tokenID)
+}
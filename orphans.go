@@ -0,0 +1,50 @@
+package erc20
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// orphanQ matches addresses with no token, left behind by older create on miss code
+const orphanQ = `(addresses.token_id IS NULL OR NOT EXISTS (SELECT 1 FROM tokens WHERE tokens.id = addresses.token_id))`
+
+// FindOrphanAddresses returns the IDs of addresses whose token is missing
+func FindOrphanAddresses(ctx context.Context, conn *pgxpool.Pool) ([]uuid.UUID, error) {
+	q := `SELECT id FROM addresses WHERE ` + orphanQ + ` ORDER BY id`
+	rows, err := conn.Query(ctx, q)
+	if err != nil {
+		logger(ctx).Errorw(err.Error())
+		return nil, terror.Error(err, "Could not find orphan addresses")
+	}
+	defer rows.Close()
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		err = rows.Scan(&id)
+		if err != nil {
+			logger(ctx).Errorw(err.Error())
+			return nil, terror.Error(err, "Could not scan address")
+		}
+		ids = append(ids, id)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error())
+		return nil, terror.Error(rows.Err(), "Could not find orphan addresses")
+	}
+	return ids, nil
+}
+
+// PurgeOrphanAddresses deletes orphan addresses holding no balance and returns how many were removed
+// Orphans with a balance are kept for manual review
+func PurgeOrphanAddresses(ctx context.Context, conn *pgxpool.Pool) (int, error) {
+	q := `DELETE FROM addresses WHERE balance = 0 AND ` + orphanQ
+	tag, err := conn.Exec(ctx, q)
+	if err != nil {
+		logger(ctx).Errorw(err.Error())
+		return 0, terror.Error(err, "Could not purge orphan addresses")
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,54 @@
+package erc20_test
+
+import (
+	"reflect"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestOrphanAddresses(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+	orphan := func(balance int) uuid.UUID {
+		var id uuid.UUID
+		err := conn.QueryRow(ctx, `INSERT INTO addresses (token_id, owner, balance) VALUES (NULL, $1, $2) RETURNING id`, newAddress(t), balance).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	empty, funded := orphan(0), orphan(5)
+
+	found, err := erc20.FindOrphanAddresses(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uuid.UUID{empty, funded}
+	if want[1].String() < want[0].String() {
+		want[0], want[1] = want[1], want[0]
+	}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("FindOrphanAddresses = %v, want %v", found, want)
+	}
+
+	purged, err := erc20.PurgeOrphanAddresses(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Errorf("PurgeOrphanAddresses = %d, want only the zero balance orphan", purged)
+	}
+	found, err = erc20.FindOrphanAddresses(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(found, []uuid.UUID{funded}) {
+		t.Errorf("orphans after purge = %v, want only %v", found, funded)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 100})
+}
@@ -0,0 +1,116 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrNotOwner is returned when a caller other than the token owner attempts an administrative action
+var ErrNotOwner = errors.New("ERC20: caller is not the owner")
+
+// ErrPaused is returned when balances of a paused token would change
+var ErrPaused = errors.New("ERC20: token is paused")
+
+// requireOwner returns ErrNotOwner unless caller owns the token
+// The token row is share locked so ownership can not change before tx ends
+func requireOwner(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, caller Address) error {
+	q := `SELECT owner FROM tokens WHERE id = $1 AND deleted_at IS NULL FOR SHARE`
+	var owner Address
+	err := tx.QueryRow(ctx, q, tokenID).Scan(&owner)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTokenNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if owner != caller {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+// TransferOwnership hands administration of a token to newOwner
+// Only the current owner may call it
+func TransferOwnership(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, caller, newOwner Address) error {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := requireOwner(ctx, tx, tokenID, caller)
+		if err != nil {
+			return err
+		}
+		q := `UPDATE tokens SET owner = $1, updated_at = now() WHERE id = $2`
+		_, err = tx.Exec(ctx, q, newOwner, tokenID)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "caller", caller, "newOwner", newOwner)
+		return terror.Error(err, "Could not transfer ownership")
+	}
+	return nil
+}
+
+// MintAs mints like Mint but only when caller owns the token or holds RoleMinter
+func MintAs(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, caller, account Address, amount int) error {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := requireRole(ctx, tx, tokenID, caller, RoleMinter)
+		if err != nil {
+			return err
+		}
+		return mint(ctx, tx, tokenID, account, amount)
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "caller", caller, "account", account, "amount", amount)
+		return terror.Error(err, "Could not update balances")
+	}
+	return nil
+}
+
+// Pause stops every balance change of a token until Unpause
+func Pause(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) error {
+	return setPaused(ctx, conn, tokenID, nil, true)
+}
+
+// Unpause lets balances of a paused token change again
+func Unpause(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) error {
+	return setPaused(ctx, conn, tokenID, nil, false)
+}
+
+// PauseAs pauses like Pause but only when caller owns the token or holds RolePauser
+func PauseAs(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, caller Address) error {
+	return setPaused(ctx, conn, tokenID, &caller, true)
+}
+
+// UnpauseAs unpauses like Unpause but only when caller owns the token or holds RolePauser
+func UnpauseAs(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, caller Address) error {
+	return setPaused(ctx, conn, tokenID, &caller, false)
+}
+
+// setPaused sets the paused flag, checking the caller's role first when caller is set
+func setPaused(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, caller *Address, paused bool) error {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		if caller != nil {
+			err := requireRole(ctx, tx, tokenID, *caller, RolePauser)
+			if err != nil {
+				return err
+			}
+		}
+		q := `UPDATE tokens SET paused = $1, updated_at = now() WHERE id = $2 AND deleted_at IS NULL`
+		tag, err := tx.Exec(ctx, q, paused, tokenID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrTokenNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "paused", paused)
+		return terror.Error(err, "Could not set paused")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestTransferOwnership(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, newOwner, stranger := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+
+	token, err := erc20.GetToken(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Owner != owner {
+		t.Fatal("Factory did not record the owner")
+	}
+
+	err = erc20.TransferOwnership(ctx, conn, tokenID, stranger, stranger)
+	if !errors.Is(err, erc20.ErrNotOwner) {
+		t.Errorf("TransferOwnership by a non-owner error = %v, want ErrNotOwner", err)
+	}
+	err = erc20.TransferOwnership(ctx, conn, tokenID, owner, newOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err = erc20.GetToken(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Owner != newOwner {
+		t.Error("TransferOwnership did not change the owner")
+	}
+	err = erc20.TransferOwnership(ctx, conn, tokenID, owner, owner)
+	if !errors.Is(err, erc20.ErrNotOwner) {
+		t.Errorf("TransferOwnership by the previous owner error = %v, want ErrNotOwner", err)
+	}
+}
+
+func TestClientCallerOwnership(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, stranger := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+
+	err := erc20.NewClient(conn, erc20.WithCaller(stranger)).Mint(ctx, tokenID, stranger, 10)
+	if !errors.Is(err, erc20.ErrUnauthorized) {
+		t.Errorf("Mint by a non-owner error = %v, want ErrUnauthorized", err)
+	}
+	err = erc20.NewClient(conn, erc20.WithCaller(stranger)).Pause(ctx, tokenID)
+	if !errors.Is(err, erc20.ErrUnauthorized) {
+		t.Errorf("Pause by a non-owner error = %v, want ErrUnauthorized", err)
+	}
+	err = erc20.NewClient(conn, erc20.WithCaller(owner)).Mint(ctx, tokenID, owner, 10)
+	if err != nil {
+		t.Errorf("Mint by the owner: %v", err)
+	}
+	if got := totalSupply(t, conn, tokenID); got != 110 {
+		t.Errorf("total supply = %d, want 110", got)
+	}
+}
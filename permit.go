@@ -0,0 +1,181 @@
+package erc20
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrInvalidSignature is returned when a permit signature does not verify against the owner's key
+var ErrInvalidSignature = errors.New("ERC20: invalid permit signature")
+
+// ErrPermitExpired is returned when a permit is submitted after its deadline
+var ErrPermitExpired = errors.New("ERC20: permit expired")
+
+// ErrNoPermitKey is returned when the owner has not registered a permit key
+var ErrNoPermitKey = errors.New("ERC20: no permit key registered")
+
+// permitDomain separates permit signatures from anything else the same key may sign
+const permitDomain = "ERC20Permit"
+
+// permitKeyDomain separates key replacement signatures from permits
+const permitKeyDomain = "ERC20PermitKey"
+
+// RegisterPermitKey sets the Ed25519 public key that verifies permits signed by owner
+// The first key registered for an owner is accepted as is, authenticating that call is the caller's responsibility.
+// Replacing a key requires signature, made by the registered key over PermitKeyMessage, otherwise ErrInvalidSignature is returned
+func RegisterPermitKey(ctx context.Context, conn *pgxpool.Pool, owner Address, publicKey ed25519.PublicKey, signature []byte) error {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return terror.Error(ErrInvalidSignature, "Invalid public key")
+	}
+	err := beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		var current []byte
+		var version int
+		err := tx.QueryRow(ctx, `SELECT public_key, version FROM permit_keys WHERE owner = $1 FOR UPDATE`, owner).Scan(&current, &version)
+		if errors.Is(err, pgx.ErrNoRows) {
+			q := `INSERT INTO permit_keys (owner, public_key) VALUES ($1, $2) ON CONFLICT (owner) DO NOTHING`
+			tag, err := tx.Exec(ctx, q, owner, []byte(publicKey))
+			if err != nil {
+				return err
+			}
+			if tag.RowsAffected() == 0 {
+				// registered concurrently, so this is a replacement without a signature
+				return ErrInvalidSignature
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		msg := PermitKeyMessage(owner, publicKey, version)
+		if !ed25519.Verify(ed25519.PublicKey(current), msg, signature) {
+			return ErrInvalidSignature
+		}
+		q := `UPDATE permit_keys SET public_key = $1, version = version + 1, updated_at = now() WHERE owner = $2`
+		_, err = tx.Exec(ctx, q, []byte(publicKey), owner)
+		return err
+	})
+	if errors.Is(err, ErrInvalidSignature) {
+		return terror.Error(err, "Replacing a permit key requires a signature by the registered key")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "owner", owner)
+		return terror.Error(err, "Could not register permit key")
+	}
+	return nil
+}
+
+// PermitKeyMessage is the canonical message the registered key signs to replace itself with newKey
+// version is the registration's current PermitKeyVersion, so a signature works once and can not restore a replaced key
+func PermitKeyMessage(owner Address, newKey ed25519.PublicKey, version int) []byte {
+	msg := make([]byte, 0, len(permitKeyDomain)+uuid.Size+ed25519.PublicKeySize+8)
+	msg = append(msg, permitKeyDomain...)
+	msg = append(msg, uuid.UUID(owner).Bytes()...)
+	msg = append(msg, newKey...)
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(version))
+	msg = append(msg, buf[:]...)
+	return msg
+}
+
+// PermitKeyVersion returns how many times owner's permit key has been replaced, for signing PermitKeyMessage
+// Returns ErrNoPermitKey if owner has not registered a key
+func PermitKeyVersion(ctx context.Context, conn *pgxpool.Pool, owner Address) (int, error) {
+	var version int
+	err := conn.QueryRow(ctx, `SELECT version FROM permit_keys WHERE owner = $1`, owner).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, terror.Error(ErrNoPermitKey, "No permit key registered")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "owner", owner)
+		return 0, terror.Error(err, "Could not get permit key version")
+	}
+	return version, nil
+}
+
+// PermitNonce returns the nonce the next permit from owner on the token must be signed with
+func PermitNonce(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner Address) (int, error) {
+	q := `SELECT nonce FROM permit_nonces WHERE token_id = $1 AND owner = $2`
+	var nonce int
+	err := conn.QueryRow(ctx, q, tokenID, owner).Scan(&nonce)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "owner", owner)
+		return 0, terror.Error(err, "Could not get permit nonce")
+	}
+	return nonce, nil
+}
+
+// PermitMessage is the canonical message an owner signs to permit spender an allowance of amount
+// The nonce binds the signature to a single use, the deadline is truncated to the second
+func PermitMessage(tokenID uuid.UUID, owner, spender Address, amount int, nonce int, deadline time.Time) []byte {
+	msg := make([]byte, 0, len(permitDomain)+3*uuid.Size+3*8)
+	msg = append(msg, permitDomain...)
+	msg = append(msg, tokenID.Bytes()...)
+	msg = append(msg, uuid.UUID(owner).Bytes()...)
+	msg = append(msg, uuid.UUID(spender).Bytes()...)
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(amount))
+	msg = append(msg, buf[:]...)
+	binary.BigEndian.PutUint64(buf[:], uint64(nonce))
+	msg = append(msg, buf[:]...)
+	binary.BigEndian.PutUint64(buf[:], uint64(deadline.Unix()))
+	msg = append(msg, buf[:]...)
+	return msg
+}
+
+// Permit sets an allowance from a signature made by the owner, so a relayer can submit it on their behalf
+// The signature covers PermitMessage with the owner's current nonce, which is then used up
+func Permit(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner, spender Address, amount int, deadline time.Time, signature []byte) error {
+	if amount < 0 {
+		return terror.Error(ErrInvalidAmount, "Allowance can not be negative")
+	}
+	if time.Now().After(deadline) {
+		return terror.Error(ErrPermitExpired, "Permit expired")
+	}
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		var publicKey []byte
+		keyQ := `SELECT public_key FROM permit_keys WHERE owner = $1`
+		err := tx.QueryRow(ctx, keyQ, owner).Scan(&publicKey)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNoPermitKey
+		}
+		if err != nil {
+			return err
+		}
+		nonceQ := `
+INSERT INTO permit_nonces (token_id, owner) VALUES ($1, $2)
+ON CONFLICT (token_id, owner) DO UPDATE SET nonce = permit_nonces.nonce
+RETURNING nonce`
+		var nonce int
+		err = tx.QueryRow(ctx, nonceQ, tokenID, owner).Scan(&nonce)
+		if err != nil {
+			return err
+		}
+		msg := PermitMessage(tokenID, owner, spender, amount, nonce, deadline)
+		if !ed25519.Verify(ed25519.PublicKey(publicKey), msg, signature) {
+			return ErrInvalidSignature
+		}
+		bumpQ := `UPDATE permit_nonces SET nonce = nonce + 1 WHERE token_id = $1 AND owner = $2`
+		_, err = tx.Exec(ctx, bumpQ, tokenID, owner)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, upsertAllowanceQ, tokenID, owner, spender, amount, nil)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "owner", owner, "spender", spender, "amount", amount)
+		return terror.Error(err, "Could not permit")
+	}
+	return nil
+}
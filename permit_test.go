@@ -0,0 +1,152 @@
+package erc20_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return public, private
+}
+
+// signPermit signs a permit with the owner's current nonce
+func signPermit(t *testing.T, conn *pgxpool.Pool, key ed25519.PrivateKey, tokenID uuid.UUID, owner, spender erc20.Address, amount int, deadline time.Time) []byte {
+	t.Helper()
+	nonce, err := erc20.PermitNonce(ctx, conn, tokenID, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ed25519.Sign(key, erc20.PermitMessage(tokenID, owner, spender, amount, nonce, deadline))
+}
+
+func allowance(t *testing.T, conn *pgxpool.Pool, tokenID uuid.UUID, owner, spender erc20.Address) int {
+	t.Helper()
+	amount, err := erc20.Allowance(ctx, conn, tokenID, owner, spender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amount
+}
+
+func TestPermit(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, spender := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+	public, private := newKey(t)
+	err := erc20.RegisterPermitKey(ctx, conn, owner, public, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Hour)
+
+	sig := signPermit(t, conn, private, tokenID, owner, spender, 40, deadline)
+	err = erc20.Permit(ctx, conn, tokenID, owner, spender, 40, deadline, sig)
+	if err != nil {
+		t.Fatalf("valid permit: %v", err)
+	}
+	if got := allowance(t, conn, tokenID, owner, spender); got != 40 {
+		t.Errorf("allowance = %d, want 40", got)
+	}
+
+	err = erc20.Permit(ctx, conn, tokenID, owner, spender, 40, deadline, sig)
+	if !errors.Is(err, erc20.ErrInvalidSignature) {
+		t.Errorf("replayed permit = %v, want %v", err, erc20.ErrInvalidSignature)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	sig = signPermit(t, conn, private, tokenID, owner, spender, 90, expired)
+	err = erc20.Permit(ctx, conn, tokenID, owner, spender, 90, expired, sig)
+	if !errors.Is(err, erc20.ErrPermitExpired) {
+		t.Errorf("expired permit = %v, want %v", err, erc20.ErrPermitExpired)
+	}
+
+	_, other := newKey(t)
+	sig = signPermit(t, conn, other, tokenID, owner, spender, 90, deadline)
+	err = erc20.Permit(ctx, conn, tokenID, owner, spender, 90, deadline, sig)
+	if !errors.Is(err, erc20.ErrInvalidSignature) {
+		t.Errorf("permit signed by another key = %v, want %v", err, erc20.ErrInvalidSignature)
+	}
+	if got := allowance(t, conn, tokenID, owner, spender); got != 40 {
+		t.Errorf("allowance after rejected permits = %d, want 40", got)
+	}
+}
+
+func TestPermitWithoutKey(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, spender := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+	_, private := newKey(t)
+	deadline := time.Now().Add(time.Hour)
+
+	sig := signPermit(t, conn, private, tokenID, owner, spender, 40, deadline)
+	err := erc20.Permit(ctx, conn, tokenID, owner, spender, 40, deadline, sig)
+	if !errors.Is(err, erc20.ErrNoPermitKey) {
+		t.Errorf("permit without a registered key = %v, want %v", err, erc20.ErrNoPermitKey)
+	}
+}
+
+func TestReplacePermitKey(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	first, firstPrivate := newKey(t)
+	second, secondPrivate := newKey(t)
+	attacker, attackerPrivate := newKey(t)
+
+	err := erc20.RegisterPermitKey(ctx, conn, owner, first, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.RegisterPermitKey(ctx, conn, owner, attacker, nil)
+	if !errors.Is(err, erc20.ErrInvalidSignature) {
+		t.Errorf("unsigned replacement = %v, want %v", err, erc20.ErrInvalidSignature)
+	}
+	err = erc20.RegisterPermitKey(ctx, conn, owner, attacker, ed25519.Sign(attackerPrivate, erc20.PermitKeyMessage(owner, attacker, 0)))
+	if !errors.Is(err, erc20.ErrInvalidSignature) {
+		t.Errorf("replacement signed by the new key = %v, want %v", err, erc20.ErrInvalidSignature)
+	}
+
+	rotate := ed25519.Sign(firstPrivate, erc20.PermitKeyMessage(owner, second, 0))
+	err = erc20.RegisterPermitKey(ctx, conn, owner, second, rotate)
+	if err != nil {
+		t.Fatalf("replacement signed by the registered key: %v", err)
+	}
+	version, err := erc20.PermitKeyVersion(ctx, conn, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 {
+		t.Errorf("PermitKeyVersion = %d, want 1", version)
+	}
+
+	err = erc20.RegisterPermitKey(ctx, conn, owner, first, ed25519.Sign(secondPrivate, erc20.PermitKeyMessage(owner, first, 1)))
+	if err != nil {
+		t.Fatalf("rotate back: %v", err)
+	}
+	err = erc20.RegisterPermitKey(ctx, conn, owner, second, rotate)
+	if !errors.Is(err, erc20.ErrInvalidSignature) {
+		t.Errorf("replayed replacement = %v, want %v", err, erc20.ErrInvalidSignature)
+	}
+
+	tokenID := newToken(t, conn, owner, 100)
+	spender := newAddress(t)
+	deadline := time.Now().Add(time.Hour)
+	err = erc20.Permit(ctx, conn, tokenID, owner, spender, 5, deadline, signPermit(t, conn, firstPrivate, tokenID, owner, spender, 5, deadline))
+	if err != nil {
+		t.Errorf("permit signed by the current key: %v", err)
+	}
+}
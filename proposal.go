@@ -0,0 +1,136 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrProposalNotFound is returned when a mint proposal does not exist
+var ErrProposalNotFound = errors.New("ERC20: mint proposal not found")
+
+// ErrAlreadyApproved is returned when an approver approves the same proposal twice
+var ErrAlreadyApproved = errors.New("ERC20: proposal already approved by this address")
+
+// ErrProposalExecuted is returned when approving a proposal that has already been minted
+var ErrProposalExecuted = errors.New("ERC20: proposal already executed")
+
+// SetMintThreshold sets how many distinct approvers new mint proposals need, the proposer included
+// Proposals already open keep the threshold they were created with
+func SetMintThreshold(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, threshold int) error {
+	if threshold < 1 {
+		return terror.Error(ErrInvalidAmount, "Mint threshold must be at least 1")
+	}
+	q := `UPDATE tokens SET mint_threshold = $1, updated_at = now() WHERE id = $2`
+	tag, err := conn.Exec(ctx, q, threshold, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "threshold", threshold)
+		return terror.Error(err, "Could not set mint threshold")
+	}
+	if tag.RowsAffected() == 0 {
+		return terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	return nil
+}
+
+// ProposeMint opens a proposal to mint amount to account, counting as the proposer's approval
+// The proposer must be allowed to mint. The mint happens once ApproveMint reaches the token's threshold
+func ProposeMint(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount int, proposer Address) (uuid.UUID, error) {
+	if amount < 0 {
+		return uuid.Nil, terror.Error(ErrInvalidAmount, "Amount can not be negative")
+	}
+	var proposalID uuid.UUID
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := requireRole(ctx, tx, tokenID, proposer, RoleMinter)
+		if err != nil {
+			return err
+		}
+		q := `
+INSERT INTO mint_proposals (token_id, account, amount, proposer, threshold)
+SELECT $1, $2, $3, $4, mint_threshold FROM tokens WHERE id = $1
+RETURNING id`
+		err = tx.QueryRow(ctx, q, tokenID, account, amount, proposer).Scan(&proposalID)
+		if err != nil {
+			return err
+		}
+		_, err = approveMint(ctx, tx, proposalID, proposer)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "account", account, "amount", amount, "proposer", proposer)
+		return uuid.Nil, terror.Error(err, "Could not propose mint")
+	}
+	return proposalID, nil
+}
+
+// ApproveMint records approver's approval of a proposal and mints once the threshold of distinct approvers is reached
+// The approver must be allowed to mint
+func ApproveMint(ctx context.Context, conn *pgxpool.Pool, proposalID uuid.UUID, approver Address) error {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		_, err := approveMint(ctx, tx, proposalID, approver)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "proposalID", proposalID, "approver", approver)
+		return terror.Error(err, "Could not approve mint")
+	}
+	return nil
+}
+
+// approveMint adds an approval inside tx and executes the proposal if it reached its threshold
+// Returns whether the mint was executed
+func approveMint(ctx context.Context, tx pgx.Tx, proposalID uuid.UUID, approver Address) (bool, error) {
+	q := `
+SELECT token_id, account, amount, threshold, executed_at FROM mint_proposals
+WHERE id = $1 FOR UPDATE`
+	var tokenID uuid.UUID
+	var account Address
+	var amount, threshold int
+	var executedAt *time.Time
+	err := tx.QueryRow(ctx, q, proposalID).Scan(&tokenID, &account, &amount, &threshold, &executedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrProposalNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	if executedAt != nil {
+		return false, ErrProposalExecuted
+	}
+	err = requireRole(ctx, tx, tokenID, approver, RoleMinter)
+	if err != nil {
+		return false, err
+	}
+	insertQ := `INSERT INTO mint_approvals (proposal_id, approver) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	tag, err := tx.Exec(ctx, insertQ, proposalID, approver)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, ErrAlreadyApproved
+	}
+	var approvals int
+	countQ := `SELECT count(*) FROM mint_approvals WHERE proposal_id = $1`
+	err = tx.QueryRow(ctx, countQ, proposalID).Scan(&approvals)
+	if err != nil {
+		return false, err
+	}
+	if approvals < threshold {
+		return false, nil
+	}
+	err = mint(ctx, tx, tokenID, account, amount)
+	if err != nil {
+		return false, err
+	}
+	executeQ := `UPDATE mint_proposals SET executed_at = now() WHERE id = $1`
+	_, err = tx.Exec(ctx, executeQ, proposalID)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
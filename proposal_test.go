@@ -0,0 +1,55 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestMintProposal(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, approver, account := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 0)
+	err := erc20.SetMintThreshold(ctx, conn, tokenID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.GrantRole(ctx, conn, tokenID, owner, approver, erc20.RoleMinter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proposalID, err := erc20.ProposeMint(ctx, conn, tokenID, account, 500, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := totalSupply(t, conn, tokenID); got != 0 {
+		t.Errorf("total supply with one approval = %d, want the mint pending", got)
+	}
+	err = erc20.ApproveMint(ctx, conn, proposalID, owner)
+	if !errors.Is(err, erc20.ErrAlreadyApproved) {
+		t.Errorf("proposer approving again error = %v, want ErrAlreadyApproved", err)
+	}
+	if got := totalSupply(t, conn, tokenID); got != 0 {
+		t.Errorf("total supply after a repeated approval = %d, want 0", got)
+	}
+
+	err = erc20.ApproveMint(ctx, conn, proposalID, approver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{account: 500})
+	if got := totalSupply(t, conn, tokenID); got != 500 {
+		t.Errorf("total supply = %d, want 500 once the threshold is reached", got)
+	}
+
+	err = erc20.ApproveMint(ctx, conn, proposalID, newAddress(t))
+	if err == nil {
+		t.Error("approving an executed proposal succeeded")
+	}
+	if got := totalSupply(t, conn, tokenID); got != 500 {
+		t.Errorf("total supply = %d, want the proposal minted once", got)
+	}
+}
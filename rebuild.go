@@ -0,0 +1,99 @@
+package erc20
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// RebuildBalances recomputes every balance and the total supply of a token by replaying ledger_events
+// Runs in one transaction holding the token lock, so no balance change of the token runs alongside it.
+// Balances are zeroed then rebuilt in created_at order. Must not be called from WithTokenLock, see there
+func RebuildBalances(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) error {
+	err := beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		err := lockToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		lockQ := `SELECT id FROM tokens WHERE id = $1 FOR UPDATE`
+		var id uuid.UUID
+		err = tx.QueryRow(ctx, lockQ, tokenID).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		eventsQ := `
+SELECT event_type, sender, recipient, amount FROM ledger_events
+WHERE token_id = $1
+ORDER BY created_at, id`
+		rows, err := tx.Query(ctx, eventsQ, tokenID)
+		if err != nil {
+			return err
+		}
+		balances := map[Address]int{}
+		totalSupply := 0
+		for rows.Next() {
+			var eventType string
+			var from, to Address
+			var amount int
+			err = rows.Scan(&eventType, &from, &to, &amount)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			switch EventType(eventType) {
+			case EventMint:
+				balances[to] += amount
+				totalSupply += amount
+			case EventBurn, EventAdminBurn:
+				balances[from] -= amount
+				totalSupply -= amount
+			case EventAdjustment:
+				// Only the owner's side is a balance, the zero address stands for the supply change
+				if from == ZeroAddress {
+					balances[to] += amount
+					totalSupply += amount
+				} else {
+					balances[from] -= amount
+					totalSupply -= amount
+				}
+			default:
+				balances[from] -= amount
+				balances[to] += amount
+			}
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+
+		zeroQ := `UPDATE addresses SET balance = 0, updated_at = now() WHERE token_id = $1`
+		_, err = tx.Exec(ctx, zeroQ, tokenID)
+		if err != nil {
+			return err
+		}
+		for address, balance := range balances {
+			if balance == 0 {
+				continue
+			}
+			_, err = credit(ctx, tx, tokenID, address, balance)
+			if err != nil {
+				return err
+			}
+		}
+		supplyQ := `UPDATE tokens SET total_supply = $1, updated_at = now() WHERE id = $2`
+		_, err = tx.Exec(ctx, supplyQ, totalSupply, tokenID)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return terror.Error(err, "Could not rebuild balances")
+	}
+	return nil
+}
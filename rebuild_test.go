@@ -0,0 +1,79 @@
+package erc20_test
+
+import (
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestRebuildBalances(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, alice, bob := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	transfer(t, conn, tokenID, owner, alice, 300)
+	mint(t, conn, tokenID, bob, 200)
+	transfer(t, conn, tokenID, alice, bob, 50)
+	err := erc20.Burn(ctx, conn, tokenID, bob, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[erc20.Address]int{owner: 700, alice: 250, bob: 225}
+
+	_, err = conn.Exec(ctx, `UPDATE addresses SET balance = 999 WHERE token_id = $1 AND owner = $2`, tokenID, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Exec(ctx, `UPDATE tokens SET total_supply = 1 WHERE id = $1`, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.RebuildBalances(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, want)
+	if got := totalSupply(t, conn, tokenID); got != 1175 {
+		t.Errorf("total supply = %d, want 1175", got)
+	}
+	wantConserved(t, conn, tokenID)
+}
+
+func TestRebuildBalancesAfterSetBalances(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances func(owner, a erc20.Address) map[erc20.Address]int
+		supply   int
+	}{
+		{"raises supply", func(owner, a erc20.Address) map[erc20.Address]int {
+			return map[erc20.Address]int{owner: 900, a: 300}
+		}, 1200},
+		{"lowers supply", func(owner, a erc20.Address) map[erc20.Address]int {
+			return map[erc20.Address]int{owner: 400, a: 50}
+		}, 450},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := erc20test.NewTestDB(t)
+			owner, a := newAddress(t), newAddress(t)
+			tokenID := newToken(t, conn, owner, 1000)
+			want := tt.balances(owner, a)
+			err := erc20.SetBalances(ctx, conn, tokenID, want, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = erc20.RebuildBalances(ctx, conn, tokenID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want[erc20.ZeroAddress] = 0
+			wantBalances(t, conn, tokenID, want)
+			if got := totalSupply(t, conn, tokenID); got != tt.supply {
+				t.Errorf("total supply after rebuild = %d, want %d", got, tt.supply)
+			}
+			wantConserved(t, conn, tokenID)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// SumBalances returns the sum of every address balance for a token
+// Used to cross check the stored total supply
+func SumBalances(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) (int, error) {
+	q := `SELECT COALESCE(SUM(balance), 0) FROM addresses WHERE token_id = $1`
+	var sum int
+	row := conn.QueryRow(ctx, q, tokenID)
+	err := row.Scan(&sum)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return 0, terror.Error(err, "Could not sum balances")
+	}
+	return sum, nil
+}
+
+// ReconcileSupply reports the stored total supply alongside the summed balances
+// The two should always match, any difference means the ledger has drifted
+func ReconcileSupply(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) (stored, summed int, err error) {
+	stored, err = TotalSupplyInt(ctx, conn, tokenID)
+	if err != nil {
+		return 0, 0, terror.Error(err, "get total supply")
+	}
+	summed, err = SumBalances(ctx, conn, tokenID)
+	if err != nil {
+		return 0, 0, terror.Error(err, "sum balances")
+	}
+	if stored != summed {
+		logger(ctx).Warnw("total supply drift", "id", tokenID, "stored", stored, "summed", summed)
+	}
+	return stored, summed, nil
+}
+
+// AssertConserved returns ErrSupplyMismatch, with both figures, unless the balances of a token add up to its total supply
+// Both are read in a single statement so concurrent transfers can not produce a false alarm
+func AssertConserved(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) error {
+	q := `
+SELECT total_supply, (SELECT COALESCE(SUM(balance), 0) FROM addresses WHERE token_id = $1)
+FROM tokens WHERE id = $1`
+	var stored, summed int
+	err := conn.QueryRow(ctx, q, tokenID).Scan(&stored, &summed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return terror.Error(ErrTokenNotFound, "Token not found")
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return terror.Error(err, "Could not check conservation")
+	}
+	if stored != summed {
+		err = fmt.Errorf("%w: total supply %d, balances sum to %d", ErrSupplyMismatch, stored, summed)
+		return terror.Error(err, "Supply not conserved")
+	}
+	return nil
+}
+
+// WatchConservation runs AssertConserved every interval until ctx is done
+// Each failure, drift or otherwise, is passed to report
+func WatchConservation(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, interval time.Duration, report func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := AssertConserved(ctx, conn, tokenID)
+			if err != nil {
+				report(err)
+			}
+		}
+	}
+}
+
+// StartReconciler checks every live token's stored supply against its summed balances each interval,
+// in the background until ctx is done. onDrift is called for each token that does not add up.
+// Each check is a single statement, so concurrent transfers can not produce a false alarm
+func StartReconciler(ctx context.Context, conn *pgxpool.Pool, interval time.Duration, onDrift func(tokenID uuid.UUID, stored, summed int)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := reconcileAll(ctx, conn, onDrift)
+				if err != nil && ctx.Err() == nil {
+					logger(ctx).Errorw(err.Error())
+				}
+			}
+		}
+	}()
+}
+
+// reconcileAll calls onDrift for every live token whose total supply differs from its summed balances
+func reconcileAll(ctx context.Context, conn *pgxpool.Pool, onDrift func(tokenID uuid.UUID, stored, summed int)) error {
+	q := `
+SELECT tokens.id, tokens.total_supply, COALESCE(SUM(addresses.balance), 0)
+FROM tokens LEFT JOIN addresses ON addresses.token_id = tokens.id
+WHERE tokens.deleted_at IS NULL
+GROUP BY tokens.id
+HAVING tokens.total_supply <> COALESCE(SUM(addresses.balance), 0)`
+	rows, err := conn.Query(ctx, q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	type drift struct {
+		tokenID        uuid.UUID
+		stored, summed int
+	}
+	drifts := []drift{}
+	for rows.Next() {
+		var d drift
+		err = rows.Scan(&d.tokenID, &d.stored, &d.summed)
+		if err != nil {
+			return err
+		}
+		drifts = append(drifts, d)
+	}
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+	for _, d := range drifts {
+		logger(ctx).Warnw("total supply drift", "id", d.tokenID, "stored", d.stored, "summed", d.summed)
+		onDrift(d.tokenID, d.stored, d.summed)
+	}
+	return nil
+}
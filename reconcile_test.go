@@ -0,0 +1,143 @@
+package erc20_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestReconcileSupply(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	transfer(t, conn, tokenID, owner, newAddress(t), 300)
+
+	stored, summed, err := erc20.ReconcileSupply(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 1000 || summed != 1000 {
+		t.Errorf("ReconcileSupply = %d, %d, want 1000, 1000", stored, summed)
+	}
+
+	_, err = conn.Exec(ctx, `UPDATE addresses SET balance = balance + 7 WHERE token_id = $1 AND owner = $2`, tokenID, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := erc20.SumBalances(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != 1007 {
+		t.Errorf("SumBalances = %d, want 1007", sum)
+	}
+	stored, summed, err = erc20.ReconcileSupply(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 1000 || summed != 1007 {
+		t.Errorf("ReconcileSupply after corruption = %d, %d, want 1000, 1007", stored, summed)
+	}
+}
+
+func TestAssertConserved(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	holders := []erc20.Address{owner, newAddress(t), newAddress(t), newAddress(t)}
+	for _, holder := range holders[1:] {
+		transfer(t, conn, tokenID, owner, holder, 200)
+	}
+	for i := 0; i < 20; i++ {
+		transfer(t, conn, tokenID, holders[i%4], holders[(i+1)%4], 10+i)
+	}
+	err := erc20.AssertConserved(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatalf("AssertConserved after transfers: %v", err)
+	}
+
+	_, err = conn.Exec(ctx, `UPDATE tokens SET total_supply = 999 WHERE id = $1`, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.AssertConserved(ctx, conn, tokenID)
+	if !errors.Is(err, erc20.ErrSupplyMismatch) {
+		t.Fatalf("AssertConserved error = %v, want ErrSupplyMismatch", err)
+	}
+	if !strings.Contains(err.Error(), "999") || !strings.Contains(err.Error(), "1000") {
+		t.Errorf("AssertConserved error %q does not give both figures", err)
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	reported := make(chan error, 1)
+	go erc20.WatchConservation(watchCtx, conn, tokenID, 10*time.Millisecond, func(err error) {
+		select {
+		case reported <- err:
+		default:
+		}
+	})
+	select {
+	case err := <-reported:
+		if !errors.Is(err, erc20.ErrSupplyMismatch) {
+			t.Errorf("WatchConservation reported %v, want ErrSupplyMismatch", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("WatchConservation did not report the drift")
+	}
+}
+
+func TestStartReconciler(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	healthy := newToken(t, conn, owner, 1000)
+	drifted := newToken(t, conn, owner, 1000)
+	_, err := conn.Exec(ctx, `UPDATE addresses SET balance = balance + 10 WHERE token_id = $1 AND owner = $2`, drifted, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type drift struct {
+		tokenID        uuid.UUID
+		stored, summed int
+	}
+	drifts := make(chan drift, 100)
+	const interval = 20 * time.Millisecond
+	reconcilerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	erc20.StartReconciler(reconcilerCtx, conn, interval, func(tokenID uuid.UUID, stored, summed int) {
+		drifts <- drift{tokenID, stored, summed}
+	})
+
+	select {
+	case got := <-drifts:
+		if got != (drift{drifted, 1000, 1010}) {
+			t.Errorf("onDrift(%s, %d, %d), want the drifted token at 1000, 1010", got.tokenID, got.stored, got.summed)
+		}
+	case <-time.After(50 * interval):
+		t.Fatal("onDrift was not called")
+	}
+	for len(drifts) > 0 {
+		if got := <-drifts; got.tokenID == healthy {
+			t.Error("onDrift called for a token that adds up")
+		}
+	}
+
+	cancel()
+	// Let a tick already in flight finish before checking nothing else arrives
+	time.Sleep(5 * interval)
+	for len(drifts) > 0 {
+		<-drifts
+	}
+	time.Sleep(5 * interval)
+	if len(drifts) != 0 {
+		t.Errorf("onDrift called %d times after cancel", len(drifts))
+	}
+}
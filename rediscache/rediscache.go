@@ -0,0 +1,60 @@
+// Package rediscache implements erc20.BalanceCache on Redis
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"erc20"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gofrs/uuid"
+)
+
+var _ erc20.BalanceCache = (*Cache)(nil)
+
+// Cache stores balances as plain Redis keys with a TTL
+type Cache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// New creates a cache on an existing Redis client
+// Entries expire after ttl even if never invalidated. Zero keeps them until invalidated
+func New(client *redis.Client, ttl time.Duration) *Cache {
+	return &Cache{client: client, ttl: ttl}
+}
+
+func key(tokenID uuid.UUID, owner erc20.Address) string {
+	return "erc20:balance:" + tokenID.String() + ":" + uuid.UUID(owner).String()
+}
+
+// Get returns the cached balance and whether there was one
+func (c *Cache) Get(ctx context.Context, tokenID uuid.UUID, owner erc20.Address) (int, bool, error) {
+	balance, err := c.client.Get(ctx, key(tokenID, owner)).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return balance, true, nil
+}
+
+// Set caches a balance
+func (c *Cache) Set(ctx context.Context, tokenID uuid.UUID, owner erc20.Address, balance int) error {
+	return c.client.Set(ctx, key(tokenID, owner), balance, c.ttl).Err()
+}
+
+// Invalidate drops the cached balances of owners
+func (c *Cache) Invalidate(ctx context.Context, tokenID uuid.UUID, owners ...erc20.Address) error {
+	if len(owners) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(owners))
+	for _, owner := range owners {
+		keys = append(keys, key(tokenID, owner))
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,103 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// maxAttempts is how many times withRetry runs a transaction before giving up
+const maxAttempts = 5
+
+// retryBaseDelay is the backoff before the first retry, doubled on each attempt
+const retryBaseDelay = 10 * time.Millisecond
+
+// withRetry runs fn in a transaction, retrying with exponential backoff
+// when postgres aborts it with a serialization failure or deadlock
+// Balance and supply CHECK violations are returned as ErrInsufficientBalance
+func withRetry(ctx context.Context, conn *pgxpool.Pool, fn func(pgx.Tx) error) error {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := beginFunc(ctx, conn, fn)
+		if err == nil || attempt == maxAttempts || !isRetryable(err) {
+			return constraintError(err)
+		}
+		logger(ctx).Warnw("retrying transaction", "attempt", attempt, "err", err.Error())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// rollbackTimeout bounds the rollback of a transaction whose context is already done
+const rollbackTimeout = 5 * time.Second
+
+// beginFunc runs fn in a transaction, committing if it returns nil and rolling back otherwise
+func beginFunc(ctx context.Context, conn *pgxpool.Pool, fn func(pgx.Tx) error) error {
+	return beginTxFunc(ctx, conn, pgx.TxOptions{}, fn)
+}
+
+// beginTxFunc is pgxpool's BeginTxFunc made safe against a cancelled ctx
+// pgx rolls back with the caller's ctx, which fails once it is done and closes the connection.
+// Here the rollback runs on its own short deadline, so the transaction always ends and the
+// connection goes back to the pool idle. A ctx done before commit rolls back instead
+func beginTxFunc(ctx context.Context, conn *pgxpool.Pool, opts pgx.TxOptions, fn func(pgx.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	closed := false
+	defer func() {
+		if closed {
+			return
+		}
+		rollbackCtx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+		defer cancel()
+		err := tx.Rollback(rollbackCtx)
+		if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logger(ctx).Warnw("rollback failed", "err", err.Error())
+		}
+	}()
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	closed = true
+	return tx.Commit(ctx)
+}
+
+// constraintError translates a non-negative balance or supply CHECK violation (23514) into ErrInsufficientBalance
+func constraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23514" {
+		return err
+	}
+	switch pgErr.ConstraintName {
+	case "addresses_balance_non_negative", "tokens_total_supply_non_negative":
+		return ErrInsufficientBalance
+	}
+	return err
+}
+
+// isRetryable reports whether err is a serialization failure (40001), deadlock (40P01)
+// or a lost compare and swap
+func isRetryable(err error) bool {
+	if errors.Is(err, errVersionMismatch) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
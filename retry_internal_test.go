@@ -0,0 +1,30 @@
+package erc20
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgconn"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&pgconn.PgError{Code: "40001"}, true},
+		{&pgconn.PgError{Code: "40P01"}, true},
+		{fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40001"}), true},
+		{errVersionMismatch, true},
+		{&pgconn.PgError{Code: "23505"}, false},
+		{ErrInsufficientBalance, false},
+		{errors.New("connection reset"), false},
+	}
+	for _, tt := range tests {
+		got := isRetryable(tt.err)
+		if got != tt.want {
+			t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
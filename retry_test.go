@@ -0,0 +1,96 @@
+package erc20_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+func TestWithRetry(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	q := `UPDATE addresses SET balance = balance + 10 WHERE token_id = $1 AND owner = $2`
+
+	attempts := 0
+	err := erc20.WithRetry(ctx, conn, func(tx pgx.Tx) error {
+		attempts++
+		_, err := tx.Exec(ctx, q, tokenID, owner)
+		if err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithRetry: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("WithRetry ran %d attempts, want 2", attempts)
+	}
+	if got := balanceOf(t, conn, tokenID, owner); got != 1010 {
+		t.Errorf("balance = %d, want the failed attempt rolled back and 1010", got)
+	}
+
+	attempts = 0
+	err = erc20.WithRetry(ctx, conn, func(tx pgx.Tx) error {
+		attempts++
+		return erc20.ErrInsufficientBalance
+	})
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("WithRetry error = %v, want ErrInsufficientBalance", err)
+	}
+	if attempts != 1 {
+		t.Errorf("WithRetry retried a non retryable error, %d attempts", attempts)
+	}
+}
+
+func TestCancelledTransactionRollsBack(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	q := `UPDATE addresses SET balance = balance + 10 WHERE token_id = $1 AND owner = $2`
+
+	t.Run("mid statement", func(t *testing.T) {
+		opCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		err := erc20.WithRetry(opCtx, conn, func(tx pgx.Tx) error {
+			_, err := tx.Exec(opCtx, q, tokenID, owner)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(opCtx, `SELECT pg_sleep(5)`)
+			return err
+		})
+		if err == nil {
+			t.Fatal("transaction outlived its deadline")
+		}
+	})
+
+	t.Run("before commit", func(t *testing.T) {
+		opCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		err := erc20.WithRetry(opCtx, conn, func(tx pgx.Tx) error {
+			_, err := tx.Exec(opCtx, q, tokenID, owner)
+			cancel()
+			return err
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	})
+
+	if acquired := conn.Stat().AcquiredConns(); acquired != 0 {
+		t.Errorf("%d connections still acquired after cancelled transactions", acquired)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 1000})
+}
@@ -0,0 +1,148 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrNoHolders is returned when distributing to a token nobody holds
+var ErrNoHolders = errors.New("ERC20: token has no holders")
+
+// notEscrowQ excludes the escrow addresses of vesting schedules, scheduled transfers, holds and stakes
+// Their balances are tracked elsewhere and must not grow on their own
+const notEscrowQ = `
+NOT EXISTS (SELECT 1 FROM vesting_schedules WHERE vesting_schedules.id = addresses.owner)
+AND NOT EXISTS (SELECT 1 FROM scheduled_transfers WHERE scheduled_transfers.id = addresses.owner)
+AND NOT EXISTS (SELECT 1 FROM holds WHERE holds.id = addresses.owner)
+AND NOT EXISTS (SELECT 1 FROM stakes WHERE stakes.id = addresses.owner)`
+
+// DistributeRewards mints totalReward to the current holders in proportion to their balances, in one transaction
+// Shares are rounded down and the units left over go to the largest remainders, ties broken by address,
+// so exactly totalReward is minted. Escrowed balances do not earn rewards
+func DistributeRewards(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, totalReward int) error {
+	_, err := distributeRewards(ctx, conn, tokenID, totalReward, RoundFloor)
+	return err
+}
+
+// distributeRewards is DistributeRewards with the shares rounded by rounding, returning the holders credited
+// Only RoundFloor guarantees exactly totalReward is minted
+func distributeRewards(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, totalReward int, rounding RoundingMode) ([]Address, error) {
+	if totalReward < 0 {
+		return nil, terror.Error(ErrInvalidAmount, "Reward can not be negative")
+	}
+	var credited []Address
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := activeToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		q := `
+SELECT owner, balance FROM addresses
+WHERE token_id = $1 AND balance > 0 AND ` + notEscrowQ + `
+ORDER BY owner
+FOR UPDATE`
+		rows, err := tx.Query(ctx, q, tokenID)
+		if err != nil {
+			return err
+		}
+		holders := []HolderBalance{}
+		for rows.Next() {
+			var holder HolderBalance
+			err = rows.Scan(&holder.Address, &holder.Balance)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			holders = append(holders, holder)
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		if len(holders) == 0 {
+			return ErrNoHolders
+		}
+
+		shares := allocateRewards(holders, totalReward, rounding)
+		minted := 0
+		for _, share := range shares {
+			minted += share
+		}
+		supplyQ := `UPDATE tokens SET total_supply = total_supply + $1, updated_at = now() WHERE id = $2`
+		_, err = tx.Exec(ctx, supplyQ, minted, tokenID)
+		if err != nil {
+			return err
+		}
+		credited = credited[:0]
+		for i, holder := range holders {
+			if shares[i] == 0 {
+				continue
+			}
+			credited = append(credited, holder.Address)
+			_, err = credit(ctx, tx, tokenID, holder.Address, shares[i])
+			if err != nil {
+				return err
+			}
+			_, err = recordEvent(ctx, tx, tokenID, EventMint, ZeroAddress, holder.Address, shares[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "reward", totalReward)
+		return nil, terror.Error(err, "Could not distribute rewards")
+	}
+	return credited, nil
+}
+
+// allocateRewards splits total across holders in proportion to their balances
+// With RoundFloor it uses the largest remainder method so the shares add up to total, holders must be
+// in address order, which breaks ties between equal remainders. Other modes round each share on its own
+func allocateRewards(holders []HolderBalance, total int, rounding RoundingMode) []int {
+	sum := new(big.Int)
+	for _, holder := range holders {
+		sum.Add(sum, big.NewInt(int64(holder.Balance)))
+	}
+	shares := make([]int, len(holders))
+	remainders := make([]*big.Int, len(holders))
+	allocated := 0
+	for i, holder := range holders {
+		share, rem := new(big.Int).QuoRem(
+			new(big.Int).Mul(big.NewInt(int64(total)), big.NewInt(int64(holder.Balance))),
+			sum,
+			new(big.Int),
+		)
+		shares[i] = int(share.Int64())
+		remainders[i] = rem
+		allocated += shares[i]
+		switch {
+		case rounding == RoundCeil && rem.Sign() > 0:
+			shares[i]++
+		case rounding == RoundHalfUp && new(big.Int).Lsh(rem, 1).Cmp(sum) >= 0:
+			shares[i]++
+		}
+	}
+	if rounding != RoundFloor {
+		return shares
+	}
+	order := make([]int, len(holders))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]].Cmp(remainders[order[b]]) > 0
+	})
+	for i := 0; i < total-allocated; i++ {
+		shares[order[i]]++
+	}
+	return shares
+}
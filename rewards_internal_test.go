@@ -0,0 +1,39 @@
+package erc20
+
+import (
+	"testing"
+)
+
+func TestAllocateRewards(t *testing.T) {
+	tests := []struct {
+		balances []int
+		total    int
+		rounding RoundingMode
+		want     []int
+	}{
+		{[]int{1, 1, 1}, 100, RoundFloor, []int{34, 33, 33}},
+		{[]int{5, 3, 2}, 7, RoundFloor, []int{4, 2, 1}},
+		{[]int{600, 300, 100}, 7, RoundFloor, []int{4, 2, 1}},
+		{[]int{10, 0}, 9, RoundFloor, []int{9, 0}},
+		{[]int{5, 3, 2}, 7, RoundCeil, []int{4, 3, 2}},
+		{[]int{5, 3, 2}, 7, RoundHalfUp, []int{4, 2, 1}},
+	}
+	for _, tt := range tests {
+		holders := make([]HolderBalance, len(tt.balances))
+		for i, balance := range tt.balances {
+			holders[i] = HolderBalance{Balance: balance}
+		}
+		got := allocateRewards(holders, tt.total, tt.rounding)
+		sum := 0
+		for i := range got {
+			sum += got[i]
+			if got[i] != tt.want[i] {
+				t.Errorf("allocateRewards(%v, %d, %v) = %v, want %v", tt.balances, tt.total, tt.rounding, got, tt.want)
+				break
+			}
+		}
+		if tt.rounding == RoundFloor && sum != tt.total {
+			t.Errorf("allocateRewards(%v, %d) shares add up to %d", tt.balances, tt.total, sum)
+		}
+	}
+}
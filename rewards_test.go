@@ -0,0 +1,37 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestDistributeRewards(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, alice, bob := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	transfer(t, conn, tokenID, owner, alice, 300)
+	transfer(t, conn, tokenID, owner, bob, 100)
+
+	// Shares of 7 are 4.2, 2.1 and 0.7, the leftover unit goes to bob's largest remainder
+	err := erc20.DistributeRewards(ctx, conn, tokenID, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 604, alice: 302, bob: 101})
+	if got := totalSupply(t, conn, tokenID); got != 1007 {
+		t.Errorf("total supply = %d, want exactly 7 more", got)
+	}
+	wantConserved(t, conn, tokenID)
+}
+
+func TestDistributeRewardsNoHolders(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	tokenID := newToken(t, conn, newAddress(t), 0)
+	err := erc20.DistributeRewards(ctx, conn, tokenID, 10)
+	if !errors.Is(err, erc20.ErrNoHolders) {
+		t.Errorf("DistributeRewards with no holders error = %v, want ErrNoHolders", err)
+	}
+}
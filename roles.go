@@ -0,0 +1,94 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrUnauthorized is returned when a caller lacks the role an action requires
+var ErrUnauthorized = errors.New("ERC20: caller is missing role")
+
+// Role is a permission granted to an address on a single token
+type Role string
+
+// Roles checked by the administrative functions
+// The token owner implicitly holds every role
+const (
+	RoleMinter Role = "MINTER"
+	RolePauser Role = "PAUSER"
+	RoleBurner Role = "BURNER"
+)
+
+// requireRole returns ErrUnauthorized unless caller owns the token or holds role on it
+func requireRole(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, caller Address, role Role) error {
+	err := requireOwner(ctx, tx, tokenID, caller)
+	if !errors.Is(err, ErrNotOwner) {
+		return err
+	}
+	q := `SELECT EXISTS (SELECT 1 FROM token_roles WHERE token_id = $1 AND address = $2 AND role = $3)`
+	var held bool
+	err = tx.QueryRow(ctx, q, tokenID, caller, string(role)).Scan(&held)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// GrantRole gives account a role on the token
+// Only the token owner may grant roles. Granting a held role is a no-op
+func GrantRole(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, caller, account Address, role Role) error {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := requireOwner(ctx, tx, tokenID, caller)
+		if err != nil {
+			return err
+		}
+		q := `INSERT INTO token_roles (token_id, address, role) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+		_, err = tx.Exec(ctx, q, tokenID, account, string(role))
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "caller", caller, "account", account, "role", role)
+		return terror.Error(err, "Could not grant role")
+	}
+	return nil
+}
+
+// RevokeRole takes a role away from account
+// Only the token owner may revoke roles. Revoking a role that is not held is a no-op
+func RevokeRole(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, caller, account Address, role Role) error {
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := requireOwner(ctx, tx, tokenID, caller)
+		if err != nil {
+			return err
+		}
+		q := `DELETE FROM token_roles WHERE token_id = $1 AND address = $2 AND role = $3`
+		_, err = tx.Exec(ctx, q, tokenID, account, string(role))
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "caller", caller, "account", account, "role", role)
+		return terror.Error(err, "Could not revoke role")
+	}
+	return nil
+}
+
+// HasRole reports whether account was granted role on the token
+// Ownership is not considered, only explicit grants
+func HasRole(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address, role Role) (bool, error) {
+	q := `SELECT EXISTS (SELECT 1 FROM token_roles WHERE token_id = $1 AND address = $2 AND role = $3)`
+	var held bool
+	err := conn.QueryRow(ctx, q, tokenID, account, string(role)).Scan(&held)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "account", account, "role", role)
+		return false, terror.Error(err, "Could not check role")
+	}
+	return held, nil
+}
@@ -0,0 +1,78 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestMinterRole(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, minter := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 0)
+
+	err := erc20.MintAs(ctx, conn, tokenID, minter, minter, 10)
+	if !errors.Is(err, erc20.ErrUnauthorized) {
+		t.Errorf("MintAs without the role error = %v, want ErrUnauthorized", err)
+	}
+	err = erc20.GrantRole(ctx, conn, tokenID, minter, minter, erc20.RoleMinter)
+	if !errors.Is(err, erc20.ErrNotOwner) {
+		t.Errorf("GrantRole by a non-owner error = %v, want ErrNotOwner", err)
+	}
+
+	err = erc20.GrantRole(ctx, conn, tokenID, owner, minter, erc20.RoleMinter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held, err := erc20.HasRole(ctx, conn, tokenID, minter, erc20.RoleMinter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !held {
+		t.Error("HasRole = false after GrantRole")
+	}
+	err = erc20.MintAs(ctx, conn, tokenID, minter, minter, 10)
+	if err != nil {
+		t.Fatalf("MintAs with the role: %v", err)
+	}
+
+	err = erc20.RevokeRole(ctx, conn, tokenID, owner, minter, erc20.RoleMinter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.MintAs(ctx, conn, tokenID, minter, minter, 10)
+	if !errors.Is(err, erc20.ErrUnauthorized) {
+		t.Errorf("MintAs after RevokeRole error = %v, want ErrUnauthorized", err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{minter: 10})
+}
+
+func TestPauserRole(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, pauser := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+
+	err := erc20.PauseAs(ctx, conn, tokenID, pauser)
+	if !errors.Is(err, erc20.ErrUnauthorized) {
+		t.Errorf("PauseAs without the role error = %v, want ErrUnauthorized", err)
+	}
+	err = erc20.GrantRole(ctx, conn, tokenID, owner, pauser, erc20.RolePauser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.PauseAs(ctx, conn, tokenID, pauser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.Transfer(ctx, conn, tokenID, owner, pauser, 1)
+	if !errors.Is(err, erc20.ErrPaused) {
+		t.Errorf("Transfer while paused error = %v, want ErrPaused", err)
+	}
+	err = erc20.UnpauseAs(ctx, conn, tokenID, pauser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer(t, conn, tokenID, owner, pauser, 1)
+}
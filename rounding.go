@@ -0,0 +1,32 @@
+package erc20
+
+// RoundingMode decides how fee, reward and swap calculations round a fractional base unit
+// Whatever the mode, balances and total supply always reconcile: a transfer's net amount is what is left
+// after the fee and burn, and supply moves by exactly what is minted or burned. The mode only decides who
+// gets the fraction
+type RoundingMode int
+
+const (
+	// RoundFloor rounds down and is the default. Fees, burns and swap outputs never exceed the exact figure,
+	// and rewards mint exactly the requested total, the units left over going to the largest remainders
+	RoundFloor RoundingMode = iota
+	// RoundCeil rounds up. A transfer's fee and burn may each take up to one unit more than exact, a swap may
+	// mint one unit more than the rate gives, and rewards may mint up to one unit per holder over the total
+	RoundCeil
+	// RoundHalfUp rounds to the nearest unit, halves up. Each figure is off by at most half a unit either way,
+	// so rewards may mint slightly more or less than the total
+	RoundHalfUp
+)
+
+// divide returns n / d rounded by m, for n >= 0 and d > 0
+func (m RoundingMode) divide(n, d int) int {
+	q, r := n/d, n%d
+	switch {
+	case m == RoundCeil && r > 0:
+		return q + 1
+	case m == RoundHalfUp && 2*r >= d:
+		return q + 1
+	default:
+		return q
+	}
+}
@@ -0,0 +1,24 @@
+package erc20
+
+import "testing"
+
+func TestRoundingDivide(t *testing.T) {
+	tests := []struct {
+		n, d                int
+		floor, ceil, halfUp int
+	}{
+		{10, 4, 2, 3, 3},
+		{9, 4, 2, 3, 2},
+		{11, 4, 2, 3, 3},
+		{8, 4, 2, 2, 2},
+		{0, 4, 0, 0, 0},
+		{1, 10000, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		for mode, want := range map[RoundingMode]int{RoundFloor: tt.floor, RoundCeil: tt.ceil, RoundHalfUp: tt.halfUp} {
+			if got := mode.divide(tt.n, tt.d); got != want {
+				t.Errorf("mode %d: divide(%d, %d) = %d, want %d", mode, tt.n, tt.d, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package erc20_test
+
+import (
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestClientRounding(t *testing.T) {
+	tests := []struct {
+		name string
+		mode erc20.RoundingMode
+		// A 150 bps fee on 100 is 1.5 units, a 3/2 swap of 3 gives 4.5
+		fee, swapped int
+		// Shares of a 7 unit reward over balances 5, 3 and 2
+		rewards [3]int
+	}{
+		{"floor", erc20.RoundFloor, 1, 4, [3]int{4, 2, 1}},
+		{"ceil", erc20.RoundCeil, 2, 5, [3]int{4, 3, 2}},
+		{"half up", erc20.RoundHalfUp, 2, 5, [3]int{4, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := erc20test.NewTestDB(t)
+			client := erc20.NewClient(conn, erc20.WithRounding(tt.mode))
+			owner, collector, recipient := newAddress(t), newAddress(t), newAddress(t)
+			tokenID := newToken(t, conn, owner, 1000)
+			err := erc20.SetTransferFee(ctx, conn, tokenID, 150, collector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = client.Transfer(ctx, tokenID, owner, recipient, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 900, collector: tt.fee, recipient: 100 - tt.fee})
+			wantConserved(t, conn, tokenID)
+
+			toToken := newToken(t, conn, newAddress(t), 0)
+			got, err := client.Swap(ctx, tokenID, toToken, owner, 3, 3, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.swapped {
+				t.Errorf("Swap of 3 at 3/2 = %d, want %d", got, tt.swapped)
+			}
+			wantConserved(t, conn, toToken)
+
+			holders := [3]erc20.Address{newAddress(t), newAddress(t), newAddress(t)}
+			rewardToken := newToken(t, conn, holders[0], 10)
+			transfer(t, conn, rewardToken, holders[0], holders[1], 3)
+			transfer(t, conn, rewardToken, holders[0], holders[2], 2)
+			err = client.DistributeRewards(ctx, rewardToken, 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			minted := 0
+			for i, holder := range holders {
+				want := []int{5, 3, 2}[i] + tt.rewards[i]
+				if got := balanceOf(t, conn, rewardToken, holder); got != want {
+					t.Errorf("holder %d balance = %d, want %d", i, got, want)
+				}
+				minted += tt.rewards[i]
+			}
+			if got := totalSupply(t, conn, rewardToken); got != 10+minted {
+				t.Errorf("total supply = %d, want %d", got, 10+minted)
+			}
+			wantConserved(t, conn, rewardToken)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ScheduleTransfer locks amount from sender now and moves it to recipient once releaseAt has passed
+// The balance is held by the scheduled transfer until ReleaseDue completes it
+func ScheduleTransfer(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, from, to Address, amount int, releaseAt time.Time) (uuid.UUID, error) {
+	if amount < 0 {
+		return uuid.Nil, terror.Error(ErrInvalidAmount, "Amount can not be negative")
+	}
+	var scheduleID uuid.UUID
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := activeToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		q := `
+INSERT INTO scheduled_transfers (token_id, sender, recipient, amount, release_at)
+VALUES ($1, $2, $3, $4, $5) RETURNING id`
+		err = tx.QueryRow(ctx, q, tokenID, from, to, amount, releaseAt).Scan(&scheduleID)
+		if err != nil {
+			return err
+		}
+		_, err = debit(ctx, tx, tokenID, from, amount)
+		if err != nil {
+			return err
+		}
+		_, err = credit(ctx, tx, tokenID, Address(scheduleID), amount)
+		if err != nil {
+			return err
+		}
+		_, err = recordEvent(ctx, tx, tokenID, EventTransfer, from, Address(scheduleID), amount)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "from", from, "to", to, "amount", amount)
+		return uuid.Nil, terror.Error(err, "Could not schedule transfer")
+	}
+	return scheduleID, nil
+}
+
+// ReleaseDue completes every scheduled transfer whose release time is at or before now
+// Transfers of paused or deleted tokens stay pending until the token is active again. Returns how many were released
+func ReleaseDue(ctx context.Context, conn *pgxpool.Pool, now time.Time) (int, error) {
+	var released int
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		released = 0
+		q := `
+SELECT scheduled_transfers.id, scheduled_transfers.token_id, scheduled_transfers.recipient, scheduled_transfers.amount
+FROM scheduled_transfers
+JOIN tokens ON tokens.id = scheduled_transfers.token_id AND NOT tokens.paused AND tokens.deleted_at IS NULL
+WHERE scheduled_transfers.released_at IS NULL AND scheduled_transfers.release_at <= $1
+ORDER BY scheduled_transfers.release_at
+FOR UPDATE OF scheduled_transfers SKIP LOCKED`
+		rows, err := tx.Query(ctx, q, now)
+		if err != nil {
+			return err
+		}
+		type due struct {
+			id        uuid.UUID
+			tokenID   uuid.UUID
+			recipient Address
+			amount    int
+		}
+		dues := []due{}
+		for rows.Next() {
+			var d due
+			err = rows.Scan(&d.id, &d.tokenID, &d.recipient, &d.amount)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			dues = append(dues, d)
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		for _, d := range dues {
+			// The token may have been paused or deleted since it was selected, leave its transfers for later
+			err = activeToken(ctx, tx, d.tokenID)
+			if errors.Is(err, ErrPaused) || errors.Is(err, ErrTokenNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			_, err = debit(ctx, tx, d.tokenID, Address(d.id), d.amount)
+			if err != nil {
+				return err
+			}
+			_, err = credit(ctx, tx, d.tokenID, d.recipient, d.amount)
+			if err != nil {
+				return err
+			}
+			_, err = recordEvent(ctx, tx, d.tokenID, EventTransfer, Address(d.id), d.recipient, d.amount)
+			if err != nil {
+				return err
+			}
+			updateQ := `UPDATE scheduled_transfers SET released_at = now() WHERE id = $1`
+			_, err = tx.Exec(ctx, updateQ, d.id)
+			if err != nil {
+				return err
+			}
+			released++
+		}
+		return nil
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "now", now)
+		return 0, terror.Error(err, "Could not release scheduled transfers")
+	}
+	return released, nil
+}
@@ -0,0 +1,125 @@
+package erc20_test
+
+import (
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestScheduleTransfer(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	sender, recipient := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, sender, 1000)
+	now := time.Now()
+
+	_, err := erc20.ScheduleTransfer(ctx, conn, tokenID, sender, recipient, 300, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{sender: 700, recipient: 0})
+	wantConserved(t, conn, tokenID)
+
+	released, err := erc20.ReleaseDue(ctx, conn, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released != 0 {
+		t.Errorf("ReleaseDue before the release time released %d, want 0", released)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{sender: 700, recipient: 0})
+
+	released, err = erc20.ReleaseDue(ctx, conn, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released != 1 {
+		t.Errorf("ReleaseDue after the release time released %d, want 1", released)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{sender: 700, recipient: 300})
+
+	released, err = erc20.ReleaseDue(ctx, conn, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released != 0 {
+		t.Errorf("ReleaseDue released %d transfers twice", released)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{recipient: 300})
+	wantConserved(t, conn, tokenID)
+
+	_, err = erc20.ScheduleTransfer(ctx, conn, tokenID, sender, recipient, 100, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.Pause(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	released, err = erc20.ReleaseDue(ctx, conn, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released != 0 {
+		t.Errorf("ReleaseDue while paused released %d, want 0", released)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{sender: 600, recipient: 300})
+	err = erc20.Unpause(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	released, err = erc20.ReleaseDue(ctx, conn, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released != 1 {
+		t.Errorf("ReleaseDue after unpausing released %d, want 1", released)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{sender: 600, recipient: 400})
+	wantConserved(t, conn, tokenID)
+}
+
+func TestReleaseDueSkipsInactiveTokens(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	sender, recipient := newAddress(t), newAddress(t)
+	paused := newToken(t, conn, sender, 1000)
+	deleted := newToken(t, conn, sender, 1000)
+	active := newToken(t, conn, sender, 1000)
+	now := time.Now()
+	for _, tokenID := range []uuid.UUID{paused, deleted, active} {
+		_, err := erc20.ScheduleTransfer(ctx, conn, tokenID, sender, recipient, 100, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := erc20.Pause(ctx, conn, paused)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.DeleteToken(ctx, conn, deleted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A paused token must not hold up the release of other tokens' transfers
+	released, err := erc20.ReleaseDue(ctx, conn, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released != 1 {
+		t.Errorf("ReleaseDue released %d, want only the active token's transfer", released)
+	}
+	wantBalances(t, conn, active, map[erc20.Address]int{recipient: 100})
+	wantBalances(t, conn, paused, map[erc20.Address]int{sender: 900, recipient: 0})
+	var pending bool
+	err = conn.QueryRow(ctx, `SELECT released_at IS NULL FROM scheduled_transfers WHERE token_id = $1`, deleted).Scan(&pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pending {
+		t.Error("ReleaseDue released a transfer of a deleted token")
+	}
+}
@@ -0,0 +1,30 @@
+package erc20
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// SearchTokens fuzzy matches tokens in an account book by name or symbol
+// Uses pg_trgm similarity, best match first
+func SearchTokens(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID, query string, limit int) ([]Token, error) {
+	q := `
+SELECT ` + tokenColumns + ` FROM tokens
+WHERE account_book_id = $1 AND (name % $2 OR symbol % $2) AND deleted_at IS NULL
+ORDER BY GREATEST(similarity(name, $2), similarity(symbol, $2)) DESC, tokens.id
+LIMIT $3`
+	rows, err := conn.Query(ctx, q, accountBookID, query, limit)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "query", query)
+		return nil, terror.Error(err, "Could not search tokens")
+	}
+	tokens, err := scanTokens(rows)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "query", query)
+		return nil, terror.Error(err, "Could not search tokens")
+	}
+	return tokens, nil
+}
@@ -0,0 +1,38 @@
+package erc20_test
+
+import (
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestSearchTokens(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	bookID := erc20test.NewAccountBook(t, conn)
+	ethereumID, err := erc20.Factory(ctx, conn, bookID, owner, "Ethereum", "ETH", 18, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.Factory(ctx, conn, bookID, owner, "Bitcoin", "BTC", 8, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := erc20.SearchTokens(ctx, conn, bookID, "Etherium", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) == 0 {
+		t.Fatal("SearchTokens found nothing for a misspelled name")
+	}
+	if tokens[0].ID != ethereumID {
+		t.Errorf("SearchTokens ranked %q first, want Ethereum", tokens[0].Name)
+	}
+	for _, token := range tokens {
+		if token.Symbol == "BTC" {
+			t.Errorf("SearchTokens matched unrelated token %q", token.Name)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// errDryRun rolls back a simulated transaction that would otherwise have committed
+var errDryRun = errors.New("dry run")
+
+// simulate runs fn in a transaction that is always rolled back
+// Returns nil if fn would have succeeded, otherwise the error it would have produced
+func simulate(ctx context.Context, conn *pgxpool.Pool, fn func(pgx.Tx) error) error {
+	err := beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		err := fn(tx)
+		if err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	return constraintError(err)
+}
+
+// SimulateTransfer runs every check of Transfer and rolls back, leaving balances untouched
+// Returns nil if the transfer would succeed right now, otherwise the error it would fail with
+func SimulateTransfer(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, sender, recipient Address, amount int) error {
+	return simulate(ctx, conn, func(tx pgx.Tx) error {
+		_, err := transfer(ctx, tx, tokenID, sender, recipient, amount, RoundFloor)
+		return err
+	})
+}
+
+// SimulateMint runs every check of Mint and rolls back, leaving balances and supply untouched
+func SimulateMint(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount int) error {
+	return simulate(ctx, conn, func(tx pgx.Tx) error {
+		return mint(ctx, tx, tokenID, account, amount)
+	})
+}
+
+// SimulateTransferMany runs every leg of TransferMany and rolls back
+func SimulateTransferMany(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, legs []TransferLeg) error {
+	return simulate(ctx, conn, func(tx pgx.Tx) error {
+		return transferMany(ctx, tx, tokenID, legs, transferOptions{})
+	})
+}
@@ -0,0 +1,41 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestSimulateTransfer(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, recipient := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+
+	err := erc20.SimulateTransfer(ctx, conn, tokenID, owner, recipient, 101)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("SimulateTransfer over balance error = %v, want ErrInsufficientBalance", err)
+	}
+	err = erc20.SimulateTransfer(ctx, conn, tokenID, owner, recipient, 60)
+	if err != nil {
+		t.Errorf("SimulateTransfer within balance: %v", err)
+	}
+	err = erc20.SimulateMint(ctx, conn, tokenID, recipient, 50)
+	if err != nil {
+		t.Errorf("SimulateMint: %v", err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 100, recipient: 0})
+	if got := totalSupply(t, conn, tokenID); got != 100 {
+		t.Errorf("total supply after simulations = %d, want 100", got)
+	}
+
+	err = erc20.SimulateTransferMany(ctx, conn, tokenID, []erc20.TransferLeg{
+		{From: owner, To: recipient, Amount: 60},
+		{From: owner, To: recipient, Amount: 60},
+	})
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("SimulateTransferMany over balance error = %v, want ErrInsufficientBalance", err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 100, recipient: 0})
+}
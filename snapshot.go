@@ -0,0 +1,52 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// TakeSnapshot records every non-zero balance of a token as it is right now
+// Snapshot balances can not be changed once taken
+func TakeSnapshot(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) (snapshotID uuid.UUID, err error) {
+	err = beginFunc(ctx, conn, func(tx pgx.Tx) error {
+		q := `INSERT INTO snapshots (token_id) VALUES ($1) RETURNING id`
+		err := tx.QueryRow(ctx, q, tokenID).Scan(&snapshotID)
+		if err != nil {
+			return err
+		}
+		copyQ := `
+INSERT INTO snapshot_balances (snapshot_id, owner, balance)
+SELECT $1, owner, balance FROM addresses WHERE token_id = $2 AND balance > 0`
+		_, err = tx.Exec(ctx, copyQ, snapshotID, tokenID)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return uuid.Nil, terror.Error(err, "Could not take snapshot")
+	}
+	return snapshotID, nil
+}
+
+// BalanceOfAt returns the balance owner held when the snapshot was taken
+// Owners absent from the snapshot held zero
+func BalanceOfAt(ctx context.Context, conn *pgxpool.Pool, snapshotID uuid.UUID, owner Address) (int, error) {
+	q := `SELECT balance FROM snapshot_balances WHERE snapshot_id = $1 AND owner = $2`
+	var balance int
+	err := conn.QueryRow(ctx, q, snapshotID, owner).Scan(&balance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "snapshotID", snapshotID, "owner", owner)
+		return 0, terror.Error(err, "Could not get snapshot balance")
+	}
+	return balance, nil
+}
@@ -0,0 +1,43 @@
+package erc20_test
+
+import (
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestSnapshot(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, alice, bob := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	transfer(t, conn, tokenID, owner, alice, 300)
+
+	snapshotID, err := erc20.TakeSnapshot(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer(t, conn, tokenID, alice, bob, 100)
+	transfer(t, conn, tokenID, owner, bob, 50)
+
+	want := map[erc20.Address]int{owner: 700, alice: 300, bob: 0}
+	for addr, balance := range want {
+		got, err := erc20.BalanceOfAt(ctx, conn, snapshotID, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != balance {
+			t.Errorf("BalanceOfAt = %d, want %d", got, balance)
+		}
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 650, alice: 200, bob: 150})
+
+	_, err = conn.Exec(ctx, `UPDATE snapshot_balances SET balance = 1 WHERE snapshot_id = $1`, snapshotID)
+	if err == nil {
+		t.Error("updating a snapshot balance succeeded, snapshots must be immutable")
+	}
+	_, err = conn.Exec(ctx, `DELETE FROM snapshot_balances WHERE snapshot_id = $1`, snapshotID)
+	if err == nil {
+		t.Error("deleting a snapshot balance succeeded, snapshots must be immutable")
+	}
+}
@@ -0,0 +1,74 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrSpendLimitExceeded is returned when a spender would move more than its limit within the current window
+var ErrSpendLimitExceeded = errors.New("ERC20: spend limit exceeded")
+
+// SetSpendLimit caps how much spender may move of the owner's balance per window, on top of the allowance
+// The first window starts now. Replaces any existing limit
+func SetSpendLimit(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner, spender Address, amount int, window time.Duration) error {
+	if amount < 0 {
+		return terror.Error(ErrInvalidAmount, "Spend limit can not be negative")
+	}
+	if window <= 0 {
+		return terror.Error(ErrInvalidAmount, "Spend limit window must be positive")
+	}
+	q := `
+INSERT INTO spend_limits (token_id, owner, spender, amount, window_length) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (token_id, owner, spender) DO UPDATE
+SET amount = EXCLUDED.amount, window_length = EXCLUDED.window_length, window_start = now(), spent = 0, updated_at = now()`
+	_, err := conn.Exec(ctx, q, tokenID, owner, spender, amount, window)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "owner", owner, "spender", spender, "amount", amount)
+		return terror.Error(err, "Could not set spend limit")
+	}
+	return nil
+}
+
+// RemoveSpendLimit lifts the spend limit on a spender, leaving only the allowance
+func RemoveSpendLimit(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, owner, spender Address) error {
+	q := `DELETE FROM spend_limits WHERE token_id = $1 AND owner = $2 AND spender = $3`
+	_, err := conn.Exec(ctx, q, tokenID, owner, spender)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "owner", owner, "spender", spender)
+		return terror.Error(err, "Could not remove spend limit")
+	}
+	return nil
+}
+
+// spendLimit records amount against the spender's limit inside tx, if it has one
+// A window that has ended is replaced by a new one starting now
+func spendLimit(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, owner, spender Address, amount int) error {
+	q := `
+SELECT amount, CASE WHEN window_start + window_length <= now() THEN 0 ELSE spent END
+FROM spend_limits WHERE token_id = $1 AND owner = $2 AND spender = $3
+FOR UPDATE`
+	var limit, spent int
+	err := tx.QueryRow(ctx, q, tokenID, owner, spender).Scan(&limit, &spent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if spent+amount > limit {
+		return ErrSpendLimitExceeded
+	}
+	updateQ := `
+UPDATE spend_limits SET
+	window_start = CASE WHEN window_start + window_length <= now() THEN now() ELSE window_start END,
+	spent = $1, updated_at = now()
+WHERE token_id = $2 AND owner = $3 AND spender = $4`
+	_, err = tx.Exec(ctx, updateQ, spent+amount, tokenID, owner, spender)
+	return err
+}
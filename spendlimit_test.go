@@ -0,0 +1,77 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestSpendLimit(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, spender, recipient := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	err := erc20.Approve(ctx, conn, tokenID, owner, spender, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.SetSpendLimit(ctx, conn, tokenID, owner, spender, 100, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spend := func(amount int) error {
+		_, err := erc20.TransferFrom(ctx, conn, tokenID, spender, owner, recipient, amount)
+		return err
+	}
+
+	for _, amount := range []int{60, 40} {
+		err = spend(amount)
+		if err != nil {
+			t.Fatalf("TransferFrom %d within the limit: %v", amount, err)
+		}
+	}
+	err = spend(1)
+	if !errors.Is(err, erc20.ErrSpendLimitExceeded) {
+		t.Errorf("TransferFrom over the limit error = %v, want ErrSpendLimitExceeded", err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 900, recipient: 100})
+
+	// Roll the window by moving its start a full window into the past
+	_, err = conn.Exec(ctx, `UPDATE spend_limits SET window_start = now() - window_length WHERE token_id = $1 AND owner = $2 AND spender = $3`, tokenID, owner, spender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = spend(100)
+	if err != nil {
+		t.Fatalf("TransferFrom after the window rolled: %v", err)
+	}
+	if got := allowance(t, conn, tokenID, owner, spender); got != 300 {
+		t.Errorf("allowance = %d, want 300", got)
+	}
+
+	err = erc20.RemoveSpendLimit(ctx, conn, tokenID, owner, spender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = spend(300)
+	if err != nil {
+		t.Fatalf("TransferFrom without a limit: %v", err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 500, recipient: 500})
+}
+
+func TestSetSpendLimitRejectsBadInput(t *testing.T) {
+	owner, spender := newAddress(t), newAddress(t)
+	err := erc20.SetSpendLimit(ctx, nil, uuid.Nil, owner, spender, -1, time.Hour)
+	if !errors.Is(err, erc20.ErrInvalidAmount) {
+		t.Errorf("negative limit error = %v, want ErrInvalidAmount", err)
+	}
+	err = erc20.SetSpendLimit(ctx, nil, uuid.Nil, owner, spender, 100, 0)
+	if !errors.Is(err, erc20.ErrInvalidAmount) {
+		t.Errorf("zero window error = %v, want ErrInvalidAmount", err)
+	}
+}
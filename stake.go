@@ -0,0 +1,110 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// Stake locks amount of the account's balance until Unstake
+// Staked funds are held by an escrow address owned by the account's stake, so they drop out of BalanceOf and
+// AvailableBalance and can not be transferred. The escrow is not counted as a holder
+func Stake(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount int) error {
+	if amount < 0 {
+		return terror.Error(ErrInvalidAmount, "Amount can not be negative")
+	}
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := activeToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		q := `
+INSERT INTO stakes (token_id, account, amount) VALUES ($1, $2, $3)
+ON CONFLICT (token_id, account) DO UPDATE SET amount = stakes.amount + EXCLUDED.amount, updated_at = now()
+RETURNING id`
+		var stakeID uuid.UUID
+		err = tx.QueryRow(ctx, q, tokenID, account, amount).Scan(&stakeID)
+		if err != nil {
+			return err
+		}
+		_, err = debit(ctx, tx, tokenID, account, amount)
+		if err != nil {
+			return err
+		}
+		_, err = credit(ctx, tx, tokenID, Address(stakeID), amount)
+		if err != nil {
+			return err
+		}
+		_, err = recordEvent(ctx, tx, tokenID, EventTransfer, account, Address(stakeID), amount)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "account", account, "amount", amount)
+		return terror.Error(err, "Could not stake")
+	}
+	return nil
+}
+
+// Unstake returns amount of the account's staked funds to its balance
+// Returns ErrInsufficientBalance if less than amount is staked
+func Unstake(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address, amount int) error {
+	if amount < 0 {
+		return terror.Error(ErrInvalidAmount, "Amount can not be negative")
+	}
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := activeToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		q := `SELECT id, amount FROM stakes WHERE token_id = $1 AND account = $2 FOR UPDATE`
+		var stakeID uuid.UUID
+		var staked int
+		err = tx.QueryRow(ctx, q, tokenID, account).Scan(&stakeID, &staked)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if staked < amount {
+			return ErrInsufficientBalance
+		}
+		updateQ := `UPDATE stakes SET amount = amount - $1, updated_at = now() WHERE id = $2`
+		_, err = tx.Exec(ctx, updateQ, amount, stakeID)
+		if err != nil {
+			return err
+		}
+		_, err = debit(ctx, tx, tokenID, Address(stakeID), amount)
+		if err != nil {
+			return err
+		}
+		_, err = credit(ctx, tx, tokenID, account, amount)
+		if err != nil {
+			return err
+		}
+		_, err = recordEvent(ctx, tx, tokenID, EventTransfer, Address(stakeID), account, amount)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "account", account, "amount", amount)
+		return terror.Error(err, "Could not unstake")
+	}
+	return nil
+}
+
+// StakedBalance returns how much of the token the account has staked
+// It is never part of AvailableBalance
+func StakedBalance(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, account Address) (int, error) {
+	q := `SELECT amount FROM stakes WHERE token_id = $1 AND account = $2`
+	var staked int
+	err := conn.QueryRow(ctx, q, tokenID, account).Scan(&staked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "account", account)
+		return 0, terror.Error(err, "Could not get staked balance")
+	}
+	return staked, nil
+}
@@ -0,0 +1,54 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestStake(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	account, other := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, account, 100)
+
+	err := erc20.Stake(ctx, conn, tokenID, account, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	staked, err := erc20.StakedBalance(ctx, conn, tokenID, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if staked != 60 {
+		t.Errorf("StakedBalance = %d, want 60", staked)
+	}
+	if got := availableBalance(t, conn, tokenID, account); got != 40 {
+		t.Errorf("AvailableBalance while staked = %d, want 40", got)
+	}
+	_, err = erc20.Transfer(ctx, conn, tokenID, account, other, 41)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("transferring staked funds = %v, want %v", err, erc20.ErrInsufficientBalance)
+	}
+	count, err := erc20.HolderCount(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("HolderCount = %d, want 1, the stake escrow is not a holder", count)
+	}
+
+	err = erc20.Unstake(ctx, conn, tokenID, account, 61)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("unstaking more than staked = %v, want %v", err, erc20.ErrInsufficientBalance)
+	}
+	err = erc20.Unstake(ctx, conn, tokenID, account, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := availableBalance(t, conn, tokenID, account); got != 100 {
+		t.Errorf("AvailableBalance after unstaking = %d, want 100", got)
+	}
+	wantConserved(t, conn, tokenID)
+}
@@ -0,0 +1,75 @@
+package erc20
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// StatementLine is one event on an account statement
+// Exactly one of Debit and Credit is set, except for a transfer to self which sets both
+type StatementLine struct {
+	Event   Event
+	Debit   int
+	Credit  int
+	Balance int
+}
+
+// AccountStatement is an address's activity on a token over a window
+type AccountStatement struct {
+	TokenID        uuid.UUID
+	Address        Address
+	OpeningBalance int
+	Lines          []StatementLine
+	ClosingBalance int
+}
+
+// Statement builds an account statement for addr from the ledger events in [from, to)
+// A zero time leaves that side unbounded, so with no upper bound the closing balance is the current balance
+func Statement(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, addr Address, from, to time.Time) (AccountStatement, error) {
+	statement := AccountStatement{TokenID: tokenID, Address: addr}
+	openingQ := `
+SELECT COALESCE(SUM(CASE WHEN recipient = $2 THEN amount ELSE 0 END) - SUM(CASE WHEN sender = $2 THEN amount ELSE 0 END), 0)
+FROM ledger_events
+WHERE token_id = $1 AND (sender = $2 OR recipient = $2) AND $3::TIMESTAMPTZ IS NOT NULL AND created_at < $3`
+	err := conn.QueryRow(ctx, openingQ, tokenID, addr, optionalTime(from)).Scan(&statement.OpeningBalance)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "addr", addr)
+		return statement, terror.Error(err, "Could not get opening balance")
+	}
+	q := `
+SELECT ` + eventColumns + ` FROM ledger_events
+WHERE token_id = $1 AND (sender = $2 OR recipient = $2)
+AND ($3::TIMESTAMPTZ IS NULL OR created_at >= $3)
+AND ($4::TIMESTAMPTZ IS NULL OR created_at < $4)
+ORDER BY seq`
+	rows, err := conn.Query(ctx, q, tokenID, addr, optionalTime(from), optionalTime(to))
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "addr", addr)
+		return statement, terror.Error(err, "Could not get events")
+	}
+	events, err := scanEvents(rows)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "addr", addr)
+		return statement, terror.Error(err, "Could not get events")
+	}
+	balance := statement.OpeningBalance
+	statement.Lines = make([]StatementLine, 0, len(events))
+	for _, event := range events {
+		line := StatementLine{Event: event}
+		if event.From == addr {
+			line.Debit = event.Amount
+		}
+		if event.To == addr {
+			line.Credit = event.Amount
+		}
+		balance += line.Credit - line.Debit
+		line.Balance = balance
+		statement.Lines = append(statement.Lines, line)
+	}
+	statement.ClosingBalance = balance
+	return statement, nil
+}
@@ -0,0 +1,69 @@
+package erc20_test
+
+import (
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestStatement(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, alice, bob := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	transfer(t, conn, tokenID, owner, alice, 100)
+	transfer(t, conn, tokenID, alice, bob, 30)
+	time.Sleep(10 * time.Millisecond)
+	cut := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	mint(t, conn, tokenID, alice, 50)
+	transfer(t, conn, tokenID, bob, alice, 10)
+	err := erc20.Burn(ctx, conn, tokenID, alice, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type line struct{ debit, credit, balance int }
+	wantLines := func(statement erc20.AccountStatement, want []line) {
+		t.Helper()
+		if len(statement.Lines) != len(want) {
+			t.Fatalf("statement has %d lines, want %d", len(statement.Lines), len(want))
+		}
+		for i, l := range statement.Lines {
+			if got := (line{l.Debit, l.Credit, l.Balance}); got != want[i] {
+				t.Errorf("line %d = %+v, want %+v", i, got, want[i])
+			}
+		}
+	}
+
+	statement, err := erc20.Statement(ctx, conn, tokenID, alice, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statement.OpeningBalance != 0 {
+		t.Errorf("opening balance = %d, want 0", statement.OpeningBalance)
+	}
+	wantLines(statement, []line{{0, 100, 100}, {30, 0, 70}, {0, 50, 120}, {0, 10, 130}, {20, 0, 110}})
+	if balance := balanceOf(t, conn, tokenID, alice); statement.ClosingBalance != balance {
+		t.Errorf("closing balance = %d, BalanceOf = %d", statement.ClosingBalance, balance)
+	}
+
+	statement, err = erc20.Statement(ctx, conn, tokenID, alice, cut, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statement.OpeningBalance != 70 {
+		t.Errorf("opening balance from the cut = %d, want 70", statement.OpeningBalance)
+	}
+	wantLines(statement, []line{{0, 50, 120}, {0, 10, 130}, {20, 0, 110}})
+
+	statement, err = erc20.Statement(ctx, conn, tokenID, alice, time.Time{}, cut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantLines(statement, []line{{0, 100, 100}, {30, 0, 70}})
+	if statement.ClosingBalance != 70 {
+		t.Errorf("closing balance at the cut = %d, want 70", statement.ClosingBalance)
+	}
+}
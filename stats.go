@@ -0,0 +1,93 @@
+package erc20
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// nonZeroBalances returns the balance of every holder of a token, smallest first
+// Escrow addresses are not holders
+func nonZeroBalances(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) ([]int, error) {
+	q := `SELECT balance FROM addresses WHERE token_id = $1 AND balance > 0 AND ` + notEscrowQ + ` ORDER BY balance`
+	rows, err := conn.Query(ctx, q, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	balances := []int{}
+	for rows.Next() {
+		var balance int
+		err = rows.Scan(&balance)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return balances, nil
+}
+
+// Distribution counts holders per balance bucket
+// Each bucket is a lower bound, a holder falls in the largest bucket not above its balance.
+// Holders below the smallest bucket are not counted
+func Distribution(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, buckets []int) (map[int]int, error) {
+	balances, err := nonZeroBalances(ctx, conn, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return nil, terror.Error(err, "Could not get balances")
+	}
+	bounds := append([]int{}, buckets...)
+	sort.Ints(bounds)
+	counts := map[int]int{}
+	for _, bound := range bounds {
+		counts[bound] = 0
+	}
+	for _, balance := range balances {
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i] > balance })
+		if i == 0 {
+			continue
+		}
+		counts[bounds[i-1]]++
+	}
+	return counts, nil
+}
+
+// GiniCoefficient measures how concentrated a token is across its holders
+// 0 is perfectly even and 1 is everything held by one address, including when there is a single holder.
+// Zero when there are no holders
+func GiniCoefficient(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) (float64, error) {
+	balances, err := nonZeroBalances(ctx, conn, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return 0, terror.Error(err, "Could not get balances")
+	}
+	return gini(balances), nil
+}
+
+// gini computes the Gini coefficient of balances sorted smallest first
+// It is scaled by n/(n-1) so one holder owning almost everything scores near 1 however many holders
+// there are, a lone holder is defined as 1
+func gini(balances []int) float64 {
+	n := float64(len(balances))
+	if n == 0 {
+		return 0
+	}
+	if n == 1 {
+		return 1
+	}
+	var sum, weighted float64
+	for i, balance := range balances {
+		sum += float64(balance)
+		weighted += float64(i+1) * float64(balance)
+	}
+	if sum == 0 {
+		return 0
+	}
+	return (2*weighted/(n*sum) - (n+1)/n) * n / (n - 1)
+}
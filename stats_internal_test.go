@@ -0,0 +1,28 @@
+package erc20
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGini(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances []int
+		want     float64
+	}{
+		{"no holders", nil, 0},
+		{"single holder", []int{1000}, 1},
+		{"uniform", []int{100, 100, 100, 100}, 0},
+		{"one whale", []int{1, 1, 1, 1_000_000}, 1},
+		{"two even halves of four", []int{0, 0, 50, 50}, 2.0 / 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gini(tt.balances)
+			if math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("gini(%v) = %f, want %f", tt.balances, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package erc20_test
+
+import (
+	"math"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestGiniCoefficient(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+
+	got, err := erc20.GiniCoefficient(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got-1) > 0.001 {
+		t.Errorf("GiniCoefficient with a single holder = %f, want 1", got)
+	}
+
+	for i := 0; i < 9; i++ {
+		transfer(t, conn, tokenID, owner, newAddress(t), 100)
+	}
+	got, err = erc20.GiniCoefficient(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got) > 0.001 {
+		t.Errorf("GiniCoefficient with a uniform distribution = %f, want 0", got)
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	for _, amount := range []int{5, 50, 60, 500} {
+		transfer(t, conn, tokenID, owner, newAddress(t), amount)
+	}
+
+	got, err := erc20.Distribution(ctx, conn, tokenID, []int{100, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]int{10: 2, 100: 2}
+	for bucket, count := range want {
+		if got[bucket] != count {
+			t.Errorf("bucket %d = %d, want %d", bucket, got[bucket], count)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package erc20
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// TokenSupply is the supply and holder count of a single token in a BookSummary
+type TokenSupply struct {
+	TokenID     uuid.UUID
+	Symbol      string
+	TotalSupply int
+	Holders     int
+}
+
+// BookSummary aggregates every live token of an account book
+// Holders counts distinct owners across all tokens, so someone holding two tokens is counted once.
+// Escrow addresses are not counted as holders
+type BookSummary struct {
+	TokenCount int
+	Holders    int
+	Tokens     []TokenSupply
+}
+
+// AccountBookSummary returns token count, distinct holders and per token supply for an account book
+// Deleted tokens are excluded
+func AccountBookSummary(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID) (*BookSummary, error) {
+	q := `
+SELECT tokens.id, tokens.symbol, tokens.total_supply, count(addresses.id)
+FROM tokens
+LEFT JOIN addresses ON addresses.token_id = tokens.id AND addresses.balance > 0 AND ` + notEscrowQ + `
+WHERE tokens.account_book_id = $1 AND tokens.deleted_at IS NULL
+GROUP BY tokens.id
+ORDER BY tokens.symbol`
+	rows, err := conn.Query(ctx, q, accountBookID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID)
+		return nil, terror.Error(err, "Could not summarise account book")
+	}
+	defer rows.Close()
+	summary := &BookSummary{Tokens: []TokenSupply{}}
+	for rows.Next() {
+		var token TokenSupply
+		err = rows.Scan(&token.TokenID, &token.Symbol, &token.TotalSupply, &token.Holders)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID)
+			return nil, terror.Error(err, "Could not scan token supply")
+		}
+		summary.Tokens = append(summary.Tokens, token)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error(), "accountBookID", accountBookID)
+		return nil, terror.Error(rows.Err(), "Could not summarise account book")
+	}
+	summary.TokenCount = len(summary.Tokens)
+
+	holdersQ := `
+SELECT count(DISTINCT addresses.owner)
+FROM addresses JOIN tokens ON tokens.id = addresses.token_id
+WHERE tokens.account_book_id = $1 AND tokens.deleted_at IS NULL AND addresses.balance > 0 AND ` + notEscrowQ
+	err = conn.QueryRow(ctx, holdersQ, accountBookID).Scan(&summary.Holders)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID)
+		return nil, terror.Error(err, "Could not count holders")
+	}
+	return summary, nil
+}
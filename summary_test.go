@@ -0,0 +1,44 @@
+package erc20_test
+
+import (
+	"reflect"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestAccountBookSummary(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	bookID := erc20test.NewAccountBook(t, conn)
+	owner, alice, bob := newAddress(t), newAddress(t), newAddress(t)
+	gold, err := erc20.Factory(ctx, conn, bookID, owner, "Gold", "GLD", 18, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	silver, err := erc20.Factory(ctx, conn, bookID, owner, "Silver", "SLV", 18, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer(t, conn, gold, owner, alice, 100)
+	transfer(t, conn, gold, owner, bob, 100)
+	transfer(t, conn, silver, owner, alice, 500)
+	// Another book's token must not be counted
+	newToken(t, conn, newAddress(t), 999)
+
+	summary, err := erc20.AccountBookSummary(ctx, conn, bookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &erc20.BookSummary{
+		TokenCount: 2,
+		Holders:    3,
+		Tokens: []erc20.TokenSupply{
+			{TokenID: gold, Symbol: "GLD", TotalSupply: 1000, Holders: 3},
+			{TokenID: silver, Symbol: "SLV", TotalSupply: 500, Holders: 1},
+		},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("AccountBookSummary = %+v, want %+v", summary, want)
+	}
+}
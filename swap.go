@@ -0,0 +1,131 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// Swap exchanges amountIn of fromToken for toToken at a fixed rate of rateNumerator/rateDenominator
+// The input is burned and the output minted in one transaction, the output rounded down
+func Swap(ctx context.Context, conn *pgxpool.Pool, fromToken, toToken uuid.UUID, account Address, amountIn int, rateNumerator, rateDenominator int) (int, error) {
+	return swap(ctx, conn, fromToken, toToken, account, amountIn, rateNumerator, rateDenominator, RoundFloor)
+}
+
+// swap is Swap with the output rounded by rounding
+func swap(ctx context.Context, conn *pgxpool.Pool, fromToken, toToken uuid.UUID, account Address, amountIn int, rateNumerator, rateDenominator int, rounding RoundingMode) (int, error) {
+	if amountIn < 0 || rateNumerator < 0 || rateDenominator <= 0 {
+		return 0, terror.Error(ErrInvalidAmount, "Invalid swap amount or rate")
+	}
+	amountOut := rounding.divide(amountIn*rateNumerator, rateDenominator)
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := burn(ctx, tx, fromToken, account, amountIn)
+		if err != nil {
+			return err
+		}
+		return mint(ctx, tx, toToken, account, amountOut)
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "from", fromToken, "to", toToken, "account", account, "amount", amountIn)
+		return 0, terror.Error(err, "Could not swap")
+	}
+	return amountOut, nil
+}
+
+// MigrateHolders reissues oldToken as newToken, converting every holder's balance at rateNumerator/rateDenominator
+// Each holder's old balance is burned and the converted amount minted to the same address in one transaction.
+// Amounts are rounded down per holder. Escrowed balances stay on the old token until released
+func MigrateHolders(ctx context.Context, conn *pgxpool.Pool, oldToken, newToken uuid.UUID, rateNumerator, rateDenominator int) error {
+	if rateNumerator < 0 || rateDenominator <= 0 {
+		return terror.Error(ErrInvalidAmount, "Invalid migration rate")
+	}
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		q := `
+SELECT owner, balance FROM addresses
+WHERE token_id = $1 AND balance > 0 AND ` + notEscrowQ + `
+ORDER BY owner
+FOR UPDATE`
+		rows, err := tx.Query(ctx, q, oldToken)
+		if err != nil {
+			return err
+		}
+		holders := []HolderBalance{}
+		for rows.Next() {
+			var holder HolderBalance
+			err = rows.Scan(&holder.Address, &holder.Balance)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			holders = append(holders, holder)
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		for _, holder := range holders {
+			err = burn(ctx, tx, oldToken, holder.Address, holder.Balance)
+			if err != nil {
+				return err
+			}
+			err = mint(ctx, tx, newToken, holder.Address, holder.Balance*rateNumerator/rateDenominator)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "from", oldToken, "to", newToken, "numerator", rateNumerator, "denominator", rateDenominator)
+		return terror.Error(err, "Could not migrate holders")
+	}
+	return nil
+}
+
+// ErrTokenMismatch is returned by CrossBookTransfer when the two tokens differ in symbol or decimals
+var ErrTokenMismatch = errors.New("ERC20: tokens do not match")
+
+// CrossBookTransfer moves amount of the same asset between account books, such as an exchange's internal and external ledgers
+// The amount is burned from fromToken and minted to toToken in one transaction, so both supplies stay consistent.
+// The tokens must share a symbol and decimals
+func CrossBookTransfer(ctx context.Context, conn *pgxpool.Pool, fromToken, toToken uuid.UUID, from, to Address, amount int) error {
+	if amount < 0 {
+		return terror.Error(ErrInvalidAmount, "Amount must not be negative")
+	}
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		q := `SELECT symbol, decimals FROM tokens WHERE id = $1`
+		var fromSymbol, toSymbol string
+		var fromDecimals, toDecimals int
+		err := tx.QueryRow(ctx, q, fromToken).Scan(&fromSymbol, &fromDecimals)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+		err = tx.QueryRow(ctx, q, toToken).Scan(&toSymbol, &toDecimals)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if fromSymbol != toSymbol || fromDecimals != toDecimals {
+			return ErrTokenMismatch
+		}
+		err = burn(ctx, tx, fromToken, from, amount)
+		if err != nil {
+			return err
+		}
+		return mint(ctx, tx, toToken, to, amount)
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "from", fromToken, "to", toToken, "sender", from, "recipient", to, "amount", amount)
+		return terror.Error(err, "Could not transfer between books")
+	}
+	return nil
+}
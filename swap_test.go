@@ -0,0 +1,128 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestSwap(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	account := newAddress(t)
+	fromToken := newToken(t, conn, account, 1000)
+	toToken := newToken(t, conn, newAddress(t), 0)
+
+	for _, tt := range []struct {
+		amountIn, num, den int
+		want               int
+	}{{100, 1, 1, 100}, {100, 2, 1, 200}, {3, 1, 2, 1}} {
+		got, err := erc20.Swap(ctx, conn, fromToken, toToken, account, tt.amountIn, tt.num, tt.den)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Swap(%d at %d/%d) = %d, want %d", tt.amountIn, tt.num, tt.den, got, tt.want)
+		}
+	}
+	wantBalances(t, conn, fromToken, map[erc20.Address]int{account: 797})
+	wantBalances(t, conn, toToken, map[erc20.Address]int{account: 301})
+	if got := totalSupply(t, conn, fromToken); got != 797 {
+		t.Errorf("input token supply = %d, want 797", got)
+	}
+	if got := totalSupply(t, conn, toToken); got != 301 {
+		t.Errorf("output token supply = %d, want 301", got)
+	}
+
+	_, err := erc20.Swap(ctx, conn, fromToken, toToken, account, 798, 1, 1)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("Swap over balance error = %v, want ErrInsufficientBalance", err)
+	}
+	wantBalances(t, conn, fromToken, map[erc20.Address]int{account: 797})
+	wantBalances(t, conn, toToken, map[erc20.Address]int{account: 301})
+	wantConserved(t, conn, fromToken)
+	wantConserved(t, conn, toToken)
+}
+
+func TestSwapRejectsZeroDenominator(t *testing.T) {
+	_, err := erc20.Swap(ctx, nil, uuid.Nil, uuid.Nil, erc20.ZeroAddress, 100, 1, 0)
+	if !errors.Is(err, erc20.ErrInvalidAmount) {
+		t.Errorf("Swap with a zero denominator error = %v, want ErrInvalidAmount", err)
+	}
+}
+
+func TestMigrateHolders(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, alice, bob := newAddress(t), newAddress(t), newAddress(t)
+	v1 := newToken(t, conn, owner, 1000)
+	v2 := newToken(t, conn, newAddress(t), 0)
+	transfer(t, conn, v1, owner, alice, 333)
+	transfer(t, conn, v1, owner, bob, 167)
+
+	// At 3/2 alice's 499.5 and bob's 250.5 round down
+	err := erc20.MigrateHolders(ctx, conn, v1, v2, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, v1, map[erc20.Address]int{owner: 0, alice: 0, bob: 0})
+	wantBalances(t, conn, v2, map[erc20.Address]int{owner: 750, alice: 499, bob: 250})
+	if got := totalSupply(t, conn, v1); got != 0 {
+		t.Errorf("old token supply = %d, want 0", got)
+	}
+	if got := totalSupply(t, conn, v2); got != 1499 {
+		t.Errorf("new token supply = %d, want 1499", got)
+	}
+	wantConserved(t, conn, v1)
+	wantConserved(t, conn, v2)
+
+	err = erc20.MigrateHolders(ctx, conn, v1, v2, 1, 0)
+	if !errors.Is(err, erc20.ErrInvalidAmount) {
+		t.Errorf("MigrateHolders at a zero denominator error = %v, want ErrInvalidAmount", err)
+	}
+}
+
+func TestCrossBookTransfer(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	sender, recipient := newAddress(t), newAddress(t)
+	factory := func(symbol string, decimals int, supply int) uuid.UUID {
+		tokenID, err := erc20.Factory(ctx, conn, erc20test.NewAccountBook(t, conn), sender, "Dollar", symbol, decimals, supply)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tokenID
+	}
+	internal, external := factory("USD", 6, 1000), factory("USD", 6, 0)
+
+	err := erc20.CrossBookTransfer(ctx, conn, internal, external, sender, recipient, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, internal, map[erc20.Address]int{sender: 700})
+	wantBalances(t, conn, external, map[erc20.Address]int{recipient: 300})
+	if got := totalSupply(t, conn, internal); got != 700 {
+		t.Errorf("source supply = %d, want 700", got)
+	}
+	if got := totalSupply(t, conn, external); got != 300 {
+		t.Errorf("destination supply = %d, want 300", got)
+	}
+	wantConserved(t, conn, internal)
+	wantConserved(t, conn, external)
+
+	for _, mismatched := range []uuid.UUID{factory("EUR", 6, 0), factory("USD", 2, 0)} {
+		err = erc20.CrossBookTransfer(ctx, conn, internal, mismatched, sender, recipient, 100)
+		if !errors.Is(err, erc20.ErrTokenMismatch) {
+			t.Errorf("CrossBookTransfer to a mismatched token error = %v, want ErrTokenMismatch", err)
+		}
+		wantBalances(t, conn, mismatched, map[erc20.Address]int{recipient: 0})
+	}
+	wantBalances(t, conn, internal, map[erc20.Address]int{sender: 700})
+
+	err = erc20.CrossBookTransfer(ctx, conn, internal, external, sender, recipient, 701)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("CrossBookTransfer over balance error = %v, want ErrInsufficientBalance", err)
+	}
+	wantBalances(t, conn, external, map[erc20.Address]int{recipient: 300})
+}
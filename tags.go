@@ -0,0 +1,95 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrInvalidTag is returned for an empty tag
+var ErrInvalidTag = errors.New("ERC20: invalid tag")
+
+// NormalizeTag trims and lower cases a tag so "Stablecoin " and "stablecoin" are the same category
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// AddTag puts a token in a category such as stablecoin, governance or lp
+// A token may have any number of tags. Adding a tag it already has is a no-op
+func AddTag(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, tag string) error {
+	tag = NormalizeTag(tag)
+	if tag == "" {
+		return terror.Error(ErrInvalidTag, "Tag can not be empty")
+	}
+	q := `INSERT INTO token_tags (token_id, tag) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	_, err := conn.Exec(ctx, q, tokenID, tag)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "tag", tag)
+		return terror.Error(err, "Could not add tag")
+	}
+	return nil
+}
+
+// RemoveTag takes a token out of a category
+// Removing a tag the token does not have is a no-op
+func RemoveTag(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, tag string) error {
+	q := `DELETE FROM token_tags WHERE token_id = $1 AND tag = $2`
+	_, err := conn.Exec(ctx, q, tokenID, NormalizeTag(tag))
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "tag", tag)
+		return terror.Error(err, "Could not remove tag")
+	}
+	return nil
+}
+
+// TagsFor returns the tags of a token in alphabetical order
+func TagsFor(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID) ([]string, error) {
+	q := `SELECT tag FROM token_tags WHERE token_id = $1 ORDER BY tag`
+	rows, err := conn.Query(ctx, q, tokenID)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID)
+		return nil, terror.Error(err, "Could not get tags")
+	}
+	defer rows.Close()
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		err = rows.Scan(&tag)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "id", tokenID)
+			return nil, terror.Error(err, "Could not get tags")
+		}
+		tags = append(tags, tag)
+	}
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error(), "id", tokenID)
+		return nil, terror.Error(rows.Err(), "Could not get tags")
+	}
+	return tags, nil
+}
+
+// ListTokensByTag returns a page of the tokens in an account book with the given tag, ordered by symbol
+// Deleted tokens are excluded
+func ListTokensByTag(ctx context.Context, conn *pgxpool.Pool, accountBookID uuid.UUID, tag string, limit, offset int) ([]Token, error) {
+	q := `
+SELECT ` + tokenColumns + ` FROM tokens
+JOIN token_tags ON token_tags.token_id = tokens.id
+WHERE tokens.account_book_id = $1 AND token_tags.tag = $2 AND tokens.deleted_at IS NULL
+ORDER BY tokens.symbol
+LIMIT $3 OFFSET $4`
+	rows, err := conn.Query(ctx, q, accountBookID, NormalizeTag(tag), limit, offset)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "tag", tag)
+		return nil, terror.Error(err, "Could not list tokens")
+	}
+	tokens, err := scanTokens(rows)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "accountBookID", accountBookID, "tag", tag)
+		return nil, terror.Error(err, "Could not list tokens")
+	}
+	return tokens, nil
+}
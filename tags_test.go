@@ -0,0 +1,87 @@
+package erc20_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestListTokensByTag(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	bookID := erc20test.NewAccountBook(t, conn)
+	owner := newAddress(t)
+	factory := func(name, symbol string) uuid.UUID {
+		tokenID, err := erc20.Factory(ctx, conn, bookID, owner, name, symbol, 18, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tokenID
+	}
+	usd, eur, gov := factory("Dollar", "USD"), factory("Euro", "EUR"), factory("Governance", "GOV")
+	addTag := func(tokenID uuid.UUID, tag string) {
+		err := erc20.AddTag(ctx, conn, tokenID, tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	addTag(usd, "stablecoin")
+	addTag(eur, " Stablecoin")
+	addTag(eur, "stablecoin")
+	addTag(usd, "governance")
+	addTag(gov, "governance")
+
+	listed := func(tag string, limit, offset int) []uuid.UUID {
+		tokens, err := erc20.ListTokensByTag(ctx, conn, bookID, tag, limit, offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []uuid.UUID{}
+		for _, token := range tokens {
+			ids = append(ids, token.ID)
+		}
+		return ids
+	}
+	tests := []struct {
+		tag           string
+		limit, offset int
+		want          []uuid.UUID
+	}{
+		{"stablecoin", 10, 0, []uuid.UUID{eur, usd}},
+		{"STABLECOIN", 10, 0, []uuid.UUID{eur, usd}},
+		{"governance", 10, 0, []uuid.UUID{gov, usd}},
+		{"governance", 1, 1, []uuid.UUID{usd}},
+		{"lp", 10, 0, []uuid.UUID{}},
+	}
+	for _, tt := range tests {
+		if got := listed(tt.tag, tt.limit, tt.offset); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListTokensByTag(%q, %d, %d) = %v, want %v", tt.tag, tt.limit, tt.offset, got, tt.want)
+		}
+	}
+
+	tags, err := erc20.TagsFor(ctx, conn, usd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, []string{"governance", "stablecoin"}) {
+		t.Errorf("TagsFor = %v, want [governance stablecoin]", tags)
+	}
+	err = erc20.RemoveTag(ctx, conn, usd, "stablecoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := listed("stablecoin", 10, 0); !reflect.DeepEqual(got, []uuid.UUID{eur}) {
+		t.Errorf("ListTokensByTag after RemoveTag = %v, want only %v", got, eur)
+	}
+}
+
+func TestAddTagRejectsEmpty(t *testing.T) {
+	err := erc20.AddTag(ctx, nil, uuid.Nil, "  ")
+	if !errors.Is(err, erc20.ErrInvalidTag) {
+		t.Errorf("AddTag of a blank tag error = %v, want ErrInvalidTag", err)
+	}
+}
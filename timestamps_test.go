@@ -0,0 +1,55 @@
+package erc20_test
+
+import (
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func addressTimes(t *testing.T, conn *pgxpool.Pool, tokenID uuid.UUID, owner erc20.Address) (created, updated time.Time) {
+	t.Helper()
+	q := `SELECT created_at, updated_at FROM addresses WHERE token_id = $1 AND owner = $2`
+	err := conn.QueryRow(ctx, q, tokenID, owner).Scan(&created, &updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created, updated
+}
+
+func TestTimestamps(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+
+	token, err := erc20.GetToken(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.CreatedAt.IsZero() {
+		t.Error("token CreatedAt is not set")
+	}
+	created, updated := addressTimes(t, conn, tokenID, owner)
+
+	time.Sleep(10 * time.Millisecond)
+	transfer(t, conn, tokenID, owner, newAddress(t), 100)
+
+	createdAfter, updatedAfter := addressTimes(t, conn, tokenID, owner)
+	if !createdAfter.Equal(created) {
+		t.Errorf("created_at changed from %v to %v", created, createdAfter)
+	}
+	if !updatedAfter.After(updated) {
+		t.Errorf("updated_at = %v after the transfer, want later than %v", updatedAfter, updated)
+	}
+	after, err := erc20.GetToken(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.CreatedAt.Equal(token.CreatedAt) {
+		t.Errorf("token CreatedAt changed from %v to %v", token.CreatedAt, after.CreatedAt)
+	}
+}
@@ -0,0 +1,135 @@
+package erc20_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recorder is an in-memory trace.TracerProvider keeping every span it starts
+type recorder struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *recorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{r}
+}
+
+func (r *recorder) ended(name string) []*recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := []*recordedSpan{}
+	for _, span := range r.spans {
+		if span.name == name && span.ended {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+type recordingTracer struct {
+	r *recorder
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordedSpan{r: t.r, name: name, attrs: map[attribute.Key]attribute.Value{}}
+	config := trace.NewSpanStartConfig(opts...)
+	span.SetAttributes(config.Attributes()...)
+	t.r.mu.Lock()
+	t.r.spans = append(t.r.spans, span)
+	t.r.mu.Unlock()
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordedSpan struct {
+	r      *recorder
+	name   string
+	attrs  map[attribute.Key]attribute.Value
+	errs   []error
+	status codes.Code
+	ended  bool
+}
+
+func (s *recordedSpan) End(...trace.SpanEndOption)            { s.ended = true }
+func (s *recordedSpan) AddEvent(string, ...trace.EventOption) {}
+func (s *recordedSpan) IsRecording() bool                     { return !s.ended }
+func (s *recordedSpan) RecordError(err error, _ ...trace.EventOption) {
+	s.errs = append(s.errs, err)
+}
+func (s *recordedSpan) SpanContext() trace.SpanContext       { return trace.SpanContext{} }
+func (s *recordedSpan) SetStatus(code codes.Code, _ string)  { s.status = code }
+func (s *recordedSpan) SetName(name string)                  { s.name = name }
+func (s *recordedSpan) TracerProvider() trace.TracerProvider { return s.r }
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func TestTransferSpan(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner := newAddress(t)
+	tokenID := newToken(t, conn, owner, 100)
+	r := &recorder{}
+	client := erc20.NewClient(conn, erc20.WithTracerProvider(r))
+
+	_, err := client.Transfer(ctx, tokenID, owner, newAddress(t), 101)
+	if err == nil {
+		t.Fatal("Transfer over balance succeeded")
+	}
+
+	spans := r.ended("erc20.Transfer")
+	if len(spans) != 1 {
+		t.Fatalf("got %d erc20.Transfer spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.status != codes.Error {
+		t.Errorf("span status = %v, want Error", span.status)
+	}
+	if len(span.errs) != 1 {
+		t.Errorf("span recorded %d errors, want 1", len(span.errs))
+	}
+	if got := span.attrs["token.id"].AsString(); got != tokenID.String() {
+		t.Errorf("token.id = %q, want %q", got, tokenID)
+	}
+	if got := span.attrs["amount"].AsInt64(); got != 101 {
+		t.Errorf("amount = %d, want 101", got)
+	}
+	if got := span.attrs["outcome"].AsString(); got == "" || got == "success" {
+		t.Errorf("outcome = %q, want the error type", got)
+	}
+}
+
+func TestClientSpansCoverEveryWrite(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	r := &recorder{}
+	client := erc20.NewClient(conn, erc20.WithTracerProvider(r))
+
+	clientWrites(t, conn, client)
+
+	for _, name := range []string{
+		"Factory", "Transfer", "TransferMany", "TransferBestEffort", "TransferCAS", "Approve", "TransferFrom",
+		"BurnFrom", "Mint", "Burn", "Swap", "DistributeRewards", "Pause", "Unpause", "DeleteToken",
+	} {
+		spans := r.ended("erc20." + name)
+		if len(spans) != 1 {
+			t.Errorf("got %d erc20.%s spans, want 1", len(spans), name)
+			continue
+		}
+		if got := spans[0].attrs["outcome"].AsString(); got != "success" {
+			t.Errorf("erc20.%s outcome = %q, want success", name, got)
+		}
+		if got := spans[0].attrs["token.id"].AsString(); got == "" || got == uuid.Nil.String() {
+			t.Errorf("erc20.%s token.id = %q, want the token", name, got)
+		}
+	}
+}
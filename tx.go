@@ -0,0 +1,321 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// debit removes amount from an address inside tx and returns the new balance
+// The address row is locked until the transaction ends
+func debit(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, account Address, amount int) (int, error) {
+	if amount < 0 {
+		return 0, ErrInvalidAmount
+	}
+	q := `SELECT balance FROM addresses WHERE token_id = $1 AND owner = $2 FOR UPDATE`
+	var bal int
+	err := tx.QueryRow(ctx, q, tokenID, account).Scan(&bal)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+	if bal < amount {
+		return 0, ErrInsufficientBalance
+	}
+	if amount == 0 {
+		return bal, nil
+	}
+	updateQ := `UPDATE addresses SET balance = balance - $1, updated_at = now() WHERE token_id = $2 AND owner = $3 RETURNING balance`
+	err = tx.QueryRow(ctx, updateQ, amount, tokenID, account).Scan(&bal)
+	return bal, err
+}
+
+// credit adds amount to an address inside tx, creating the address if needed, and returns the new balance
+func credit(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, account Address, amount int) (int, error) {
+	if amount < 0 {
+		return 0, ErrInvalidAmount
+	}
+	q := `
+INSERT INTO addresses (token_id, owner, balance) VALUES ($1, $2, $3)
+ON CONFLICT (token_id, owner) DO UPDATE SET balance = addresses.balance + EXCLUDED.balance, updated_at = now()
+RETURNING balance`
+	var bal int
+	err := tx.QueryRow(ctx, q, tokenID, account, amount).Scan(&bal)
+	return bal, err
+}
+
+// moveQ debits the sender and credits the recipient of a transfer in one statement
+const moveQ = `
+WITH debited AS (
+	UPDATE addresses SET balance = balance - $3, updated_at = now()
+	WHERE token_id = $1 AND owner = $2 AND balance >= $3
+	RETURNING balance
+), credited AS (
+	INSERT INTO addresses (token_id, owner, balance)
+	SELECT $1, $4, $5 WHERE EXISTS (SELECT 1 FROM debited)
+	ON CONFLICT (token_id, owner) DO UPDATE SET balance = addresses.balance + EXCLUDED.balance, updated_at = now()
+	RETURNING balance
+)
+SELECT debited.balance, credited.balance FROM debited, credited`
+
+// move debits debitAmount from sender and credits creditAmount to recipient in a single statement inside tx
+// The debit only applies if the sender holds enough, otherwise nothing changes and ErrInsufficientBalance is returned.
+// sender and recipient must differ, a statement can not update the same row twice
+func move(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, sender, recipient Address, debitAmount, creditAmount int) (senderBalance, recipientBalance int, err error) {
+	if debitAmount < 0 || creditAmount < 0 {
+		return 0, 0, ErrInvalidAmount
+	}
+	err = tx.QueryRow(ctx, moveQ, tokenID, sender, debitAmount, recipient, creditAmount).Scan(&senderBalance, &recipientBalance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, 0, ErrInsufficientBalance
+	}
+	return senderBalance, recipientBalance, err
+}
+
+// TransferResult is the state of both parties once a transfer has been applied
+type TransferResult struct {
+	SenderBalance    int
+	RecipientBalance int
+	// EventID is the ledger event of the move to the recipient, fee and burn events are separate
+	EventID uuid.UUID
+}
+
+// transfer moves amount from sender to recipient inside tx
+// The token's transfer burn and fee are taken out of amount, each rounded by rounding.
+// The burn is removed from the total supply, the fee is credited to the fee collector
+func transfer(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, sender Address, recipient Address, amount int, rounding RoundingMode) (*TransferResult, error) {
+	plan, err := planTransfer(ctx, tx, tokenID, amount, rounding)
+	if err != nil {
+		return nil, err
+	}
+	result := &TransferResult{}
+	if sender == recipient || amount == 0 {
+		result.SenderBalance, err = debit(ctx, tx, tokenID, sender, amount)
+		if err != nil {
+			return nil, err
+		}
+		result.RecipientBalance, err = credit(ctx, tx, tokenID, recipient, plan.net)
+		if err != nil {
+			return nil, err
+		}
+		if sender == recipient {
+			result.SenderBalance = result.RecipientBalance
+		}
+	} else {
+		result.SenderBalance, result.RecipientBalance, err = move(ctx, tx, tokenID, sender, recipient, amount, plan.net)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = plan.settle(ctx, tx, tokenID, sender, recipient, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// transferPlan is how a transfer's amount splits between the recipient, the fee collector and the burn
+type transferPlan struct {
+	net       int
+	fee       int
+	burned    int
+	collector *Address
+}
+
+// planTransferQ reads the token settings every transfer is checked against
+const planTransferQ = `SELECT transfer_burn_bps, fee_bps, fee_collector, paused, max_transfer FROM tokens WHERE id = $1 AND deleted_at IS NULL`
+
+// planTransfer checks inside tx that the token allows a transfer of amount and works out how it splits
+// The transfer burn and fee are each rounded by rounding. Takes the token lock shared, see WithTokenLock
+func planTransfer(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, amount int, rounding RoundingMode) (*transferPlan, error) {
+	err := shareTokenLock(ctx, tx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	var burnBps, feeBps int
+	var collector *Address
+	var paused bool
+	var maxTransfer *int
+	err = tx.QueryRow(ctx, planTransferQ, tokenID).Scan(&burnBps, &feeBps, &collector, &paused, &maxTransfer)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if paused {
+		return nil, ErrPaused
+	}
+	if maxTransfer != nil && *maxTransfer > 0 && amount > *maxTransfer {
+		return nil, ErrTransferTooLarge
+	}
+	if amount < 0 {
+		return nil, ErrInvalidAmount
+	}
+	plan := &transferPlan{collector: collector}
+	plan.burned = rounding.divide(amount*burnBps, 10000)
+	if collector != nil {
+		plan.fee = rounding.divide(amount*feeBps, 10000)
+	}
+	plan.net = amount - plan.burned - plan.fee
+	if plan.net < 0 {
+		return nil, ErrInvalidAmount
+	}
+	return plan, nil
+}
+
+// settle finishes a transfer inside tx once amount has left sender and the net reached recipient
+// It records the transfer, credits the fee to the collector and removes the burn from the total supply.
+// result is kept current when the collector is one of the parties
+func (p *transferPlan) settle(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, sender, recipient Address, result *TransferResult) error {
+	var err error
+	result.EventID, err = recordEvent(ctx, tx, tokenID, EventTransfer, sender, recipient, p.net)
+	if err != nil {
+		return err
+	}
+	if p.fee > 0 {
+		collected, err := credit(ctx, tx, tokenID, *p.collector, p.fee)
+		if err != nil {
+			return err
+		}
+		if *p.collector == sender {
+			result.SenderBalance = collected
+		}
+		if *p.collector == recipient {
+			result.RecipientBalance = collected
+		}
+		_, err = recordEvent(ctx, tx, tokenID, EventTransfer, sender, *p.collector, p.fee)
+		if err != nil {
+			return err
+		}
+	}
+	if p.burned > 0 {
+		supplyQ := `UPDATE tokens SET total_supply = total_supply - $1, updated_at = now() WHERE id = $2`
+		_, err = tx.Exec(ctx, supplyQ, p.burned, tokenID)
+		if err != nil {
+			return err
+		}
+		_, err = recordEvent(ctx, tx, tokenID, EventBurn, sender, ZeroAddress, p.burned)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mint credits amount to account inside tx and adds it to the total supply
+func mint(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, account Address, amount int) error {
+	if amount < 0 {
+		return ErrInvalidAmount
+	}
+	err := activeToken(ctx, tx, tokenID)
+	if err != nil {
+		return err
+	}
+	q := `UPDATE tokens SET total_supply = total_supply + $1, updated_at = now() WHERE id = $2`
+	_, err = tx.Exec(ctx, q, amount, tokenID)
+	if err != nil {
+		return err
+	}
+	_, err = credit(ctx, tx, tokenID, account, amount)
+	if err != nil {
+		return err
+	}
+	err = trackMint(ctx, tx, tokenID, account, amount)
+	if err != nil {
+		return err
+	}
+	_, err = recordEvent(ctx, tx, tokenID, EventMint, ZeroAddress, account, amount)
+	return err
+}
+
+// burn debits amount from account inside tx and removes it from the total supply
+// Returns ErrBelowMinSupply if the total supply would drop below the token's floor
+func burn(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, account Address, amount int) error {
+	return burnAs(ctx, tx, tokenID, account, amount, EventBurn)
+}
+
+// burnAs is burn recording the event as eventType
+func burnAs(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, account Address, amount int, eventType EventType) error {
+	err := activeToken(ctx, tx, tokenID)
+	if err != nil {
+		return err
+	}
+	_, err = debit(ctx, tx, tokenID, account, amount)
+	if err != nil {
+		return err
+	}
+	q := `UPDATE tokens SET total_supply = total_supply - $1, updated_at = now() WHERE id = $2 RETURNING total_supply, min_supply`
+	var totalSupply, minSupply int
+	err = tx.QueryRow(ctx, q, amount, tokenID).Scan(&totalSupply, &minSupply)
+	if err != nil {
+		return err
+	}
+	if totalSupply < minSupply {
+		return ErrBelowMinSupply
+	}
+	_, err = recordEvent(ctx, tx, tokenID, eventType, account, ZeroAddress, amount)
+	return err
+}
+
+// activeToken returns ErrTokenNotFound unless the token exists and is not deleted,
+// and ErrPaused while it is paused. Balance changes check it first, so it takes the token lock shared, see WithTokenLock
+func activeToken(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID) error {
+	err := shareTokenLock(ctx, tx, tokenID)
+	if err != nil {
+		return err
+	}
+	q := `SELECT paused FROM tokens WHERE id = $1 AND deleted_at IS NULL`
+	var paused bool
+	err = tx.QueryRow(ctx, q, tokenID).Scan(&paused)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTokenNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if paused {
+		return ErrPaused
+	}
+	return nil
+}
+
+// transferOptions are the client level checks run before a transfer touches any balance
+type transferOptions struct {
+	hook TransferHook
+	// strict rejects a sender or recipient address that does not exist yet
+	strict bool
+	// rounding applies to the transfer burn and fee
+	rounding RoundingMode
+}
+
+// check runs the options inside tx
+func (o transferOptions) check(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, sender, recipient Address, amount int) error {
+	if o.hook != nil {
+		err := o.hook(ctx, tokenID, sender, recipient, amount)
+		if err != nil {
+			return err
+		}
+	}
+	if o.strict {
+		return requireAddresses(ctx, tx, tokenID, sender, recipient)
+	}
+	return nil
+}
+
+// requireAddresses returns ErrAddressNotFound unless every owner already has an address for the token
+func requireAddresses(ctx context.Context, tx pgx.Tx, tokenID uuid.UUID, owners ...Address) error {
+	q := `SELECT EXISTS (SELECT 1 FROM addresses WHERE token_id = $1 AND owner = $2)`
+	for _, owner := range owners {
+		var exists bool
+		err := tx.QueryRow(ctx, q, tokenID, owner).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrAddressNotFound
+		}
+	}
+	return nil
+}
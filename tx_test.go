@@ -0,0 +1,238 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestMove(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	sender, recipient := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, sender, 100)
+
+	senderBalance, recipientBalance, err := erc20.Move(ctx, conn, tokenID, sender, recipient, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if senderBalance != 40 || recipientBalance != 60 {
+		t.Errorf("Move returned %d, %d, want 40, 60", senderBalance, recipientBalance)
+	}
+
+	_, _, err = erc20.Move(ctx, conn, tokenID, sender, recipient, 41)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("Move overdraw = %v, want %v", err, erc20.ErrInsufficientBalance)
+	}
+	_, _, err = erc20.Move(ctx, conn, tokenID, newAddress(t), recipient, 1)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) {
+		t.Errorf("Move from a missing address = %v, want %v", err, erc20.ErrInsufficientBalance)
+	}
+	_, _, err = erc20.Move(ctx, conn, tokenID, sender, recipient, -1)
+	if !errors.Is(err, erc20.ErrInvalidAmount) {
+		t.Errorf("Move of a negative amount = %v, want %v", err, erc20.ErrInvalidAmount)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{sender: 40, recipient: 60})
+}
+
+func TestMoveMatchesSplit(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	a, b := newAddress(t), newAddress(t)
+	moved := newToken(t, conn, a, 100)
+	split := newToken(t, conn, a, 100)
+
+	for _, amount := range []int{30, 0, 70, 1} {
+		movedSender, movedRecipient, movedErr := erc20.Move(ctx, conn, moved, a, b, amount)
+		splitSender, splitRecipient, splitErr := erc20.MoveSplit(ctx, conn, split, a, b, amount)
+		if !errors.Is(movedErr, splitErr) && !errors.Is(splitErr, movedErr) {
+			t.Errorf("moving %d: Move = %v, split = %v", amount, movedErr, splitErr)
+		}
+		if movedErr == nil && (movedSender != splitSender || movedRecipient != splitRecipient) {
+			t.Errorf("moving %d: Move = %d, %d, split = %d, %d", amount, movedSender, movedRecipient, splitSender, splitRecipient)
+		}
+	}
+	wantBalances(t, conn, moved, map[erc20.Address]int{a: 0, b: 100})
+	wantBalances(t, conn, split, map[erc20.Address]int{a: 0, b: 100})
+}
+
+func BenchmarkMove(b *testing.B) {
+	conn := erc20test.NewTestDB(b)
+	sender, recipient := newAddress(b), newAddress(b)
+	tokenID := newToken(b, conn, sender, 1<<30)
+	b.Run("single statement", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _, err := erc20.Move(ctx, conn, tokenID, sender, recipient, 1)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("multi statement", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _, err := erc20.MoveSplit(ctx, conn, tokenID, sender, recipient, 1)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestTransferBurn(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, recipient := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 10000)
+
+	transfer(t, conn, tokenID, owner, recipient, 1000)
+	if got := totalSupply(t, conn, tokenID); got != 10000 {
+		t.Errorf("total supply with no burn = %d, want 10000", got)
+	}
+
+	err := erc20.SetTransferBurn(ctx, conn, tokenID, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer(t, conn, tokenID, owner, recipient, 1000)
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 8000, recipient: 1975})
+	if got := totalSupply(t, conn, tokenID); got != 9975 {
+		t.Errorf("total supply = %d, want 9975", got)
+	}
+
+	// 2.5% of 39 is 0.975 and of 41 is 1.025, both round down
+	transfer(t, conn, tokenID, owner, recipient, 39)
+	transfer(t, conn, tokenID, owner, recipient, 41)
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 7920, recipient: 2054})
+	if got := totalSupply(t, conn, tokenID); got != 9974 {
+		t.Errorf("total supply after rounding = %d, want 9974", got)
+	}
+
+	spender := newAddress(t)
+	err = erc20.Approve(ctx, conn, tokenID, owner, spender, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.TransferFrom(ctx, conn, tokenID, spender, owner, recipient, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 7720, recipient: 2249})
+	wantConserved(t, conn, tokenID)
+}
+
+func TestTransferFee(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, recipient, collector := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 10000)
+	err := erc20.SetTransferFee(ctx, conn, tokenID, 100, collector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transfer(t, conn, tokenID, owner, recipient, 1000)
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 9000, recipient: 990, collector: 10})
+
+	spender := newAddress(t)
+	err = erc20.Approve(ctx, conn, tokenID, owner, spender, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.TransferFrom(ctx, conn, tokenID, spender, owner, recipient, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 8500, recipient: 1485, collector: 15})
+	if got := totalSupply(t, conn, tokenID); got != 10000 {
+		t.Errorf("total supply = %d, want 10000, a fee moves value without destroying it", got)
+	}
+	wantConserved(t, conn, tokenID)
+}
+
+func TestTransferWithResult(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, recipient := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+
+	for _, tr := range []struct {
+		from, to erc20.Address
+		amount   int
+	}{{owner, recipient, 300}, {recipient, owner, 100}, {owner, owner, 50}} {
+		result, err := erc20.TransferWithResult(ctx, conn, tokenID, tr.from, tr.to, tr.amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := balanceOf(t, conn, tokenID, tr.from); result.SenderBalance != got {
+			t.Errorf("SenderBalance = %d, BalanceOf = %d", result.SenderBalance, got)
+		}
+		if got := balanceOf(t, conn, tokenID, tr.to); result.RecipientBalance != got {
+			t.Errorf("RecipientBalance = %d, BalanceOf = %d", result.RecipientBalance, got)
+		}
+		if result.EventID == uuid.Nil {
+			t.Error("TransferWithResult returned no event")
+		}
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 800, recipient: 200})
+}
+
+func TestTransferWithEvent(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, recipient := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+
+	eventID, err := erc20.TransferWithEvent(ctx, conn, tokenID, owner, recipient, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err := erc20.GetEvent(ctx, conn, eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.TokenID != tokenID || event.Type != erc20.EventTransfer || event.From != owner || event.To != recipient || event.Amount != 250 {
+		t.Errorf("event = %+v, want the transfer of 250 from owner to recipient", event)
+	}
+
+	eventID, err = erc20.TransferWithEvent(ctx, conn, tokenID, recipient, owner, 251)
+	if !errors.Is(err, erc20.ErrInsufficientBalance) || eventID != uuid.Nil {
+		t.Errorf("failed TransferWithEvent = %s, %v, want no event and ErrInsufficientBalance", eventID, err)
+	}
+}
+
+func TestMaxTransfer(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, spender, recipient := newAddress(t), newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	err := erc20.SetMaxTransfer(ctx, conn, tokenID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = erc20.Approve(ctx, conn, tokenID, owner, spender, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transfer(t, conn, tokenID, owner, recipient, 100)
+	_, err = erc20.Transfer(ctx, conn, tokenID, owner, recipient, 101)
+	if !errors.Is(err, erc20.ErrTransferTooLarge) {
+		t.Errorf("Transfer over the cap error = %v, want ErrTransferTooLarge", err)
+	}
+	_, err = erc20.TransferFrom(ctx, conn, tokenID, spender, owner, recipient, 100)
+	if err != nil {
+		t.Fatalf("TransferFrom at the cap: %v", err)
+	}
+	_, err = erc20.TransferFrom(ctx, conn, tokenID, spender, owner, recipient, 101)
+	if !errors.Is(err, erc20.ErrTransferTooLarge) {
+		t.Errorf("TransferFrom over the cap error = %v, want ErrTransferTooLarge", err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 800, recipient: 200})
+	if got := allowance(t, conn, tokenID, owner, spender); got != 400 {
+		t.Errorf("allowance = %d, want 400", got)
+	}
+
+	err = erc20.SetMaxTransfer(ctx, conn, tokenID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer(t, conn, tokenID, owner, recipient, 500)
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{owner: 300, recipient: 700})
+}
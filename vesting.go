@@ -0,0 +1,167 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// ErrVestingNotFound is returned when a vesting schedule does not exist
+var ErrVestingNotFound = errors.New("ERC20: vesting schedule not found")
+
+// VestingSchedule linearly releases total to a beneficiary over duration from start
+// Nothing is releasable before the cliff
+type VestingSchedule struct {
+	ID          uuid.UUID
+	TokenID     uuid.UUID
+	Beneficiary Address
+	Total       int
+	Released    int
+	Start       time.Time
+	Cliff       time.Time
+	Duration    time.Duration
+}
+
+// Vested returns how much of the schedule has vested at the given time, released or not
+func (v *VestingSchedule) Vested(at time.Time) int {
+	if at.Before(v.Cliff) || at.Before(v.Start) {
+		return 0
+	}
+	elapsed := at.Sub(v.Start)
+	if elapsed >= v.Duration {
+		return v.Total
+	}
+	vested := new(big.Int).Mul(big.NewInt(int64(v.Total)), big.NewInt(int64(elapsed)))
+	vested.Quo(vested, big.NewInt(int64(v.Duration)))
+	return int(vested.Int64())
+}
+
+// releasable returns how much vested at the given time is still unreleased
+// Never negative, a time before the last release has nothing left to release
+func (v *VestingSchedule) releasable(at time.Time) int {
+	releasable := v.Vested(at) - v.Released
+	if releasable < 0 {
+		return 0
+	}
+	return releasable
+}
+
+// escrow is the address holding the unreleased balance of the schedule
+func (v *VestingSchedule) escrow() Address {
+	return Address(v.ID)
+}
+
+// CreateVesting moves total from funder into a new vesting schedule for beneficiary
+// The balance is held by the schedule until released
+func CreateVesting(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, funder, beneficiary Address, total int, start, cliff time.Time, duration time.Duration) (uuid.UUID, error) {
+	if total < 0 {
+		return uuid.Nil, terror.Error(ErrInvalidAmount, "Vesting total can not be negative")
+	}
+	var scheduleID uuid.UUID
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		err := activeToken(ctx, tx, tokenID)
+		if err != nil {
+			return err
+		}
+		q := `
+INSERT INTO vesting_schedules (token_id, beneficiary, total, start_at, cliff_at, duration)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+		err = tx.QueryRow(ctx, q, tokenID, beneficiary, total, start, cliff, duration).Scan(&scheduleID)
+		if err != nil {
+			return err
+		}
+		_, err = debit(ctx, tx, tokenID, funder, total)
+		if err != nil {
+			return err
+		}
+		_, err = credit(ctx, tx, tokenID, Address(scheduleID), total)
+		if err != nil {
+			return err
+		}
+		_, err = recordEvent(ctx, tx, tokenID, EventTransfer, funder, Address(scheduleID), total)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "funder", funder, "beneficiary", beneficiary, "total", total)
+		return uuid.Nil, terror.Error(err, "Could not create vesting schedule")
+	}
+	return scheduleID, nil
+}
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func getVesting(ctx context.Context, conn queryRower, scheduleID uuid.UUID, lock bool) (*VestingSchedule, error) {
+	q := `
+SELECT id, token_id, beneficiary, total, released, start_at, cliff_at, duration
+FROM vesting_schedules WHERE id = $1`
+	if lock {
+		q += ` FOR UPDATE`
+	}
+	v := &VestingSchedule{}
+	err := conn.QueryRow(ctx, q, scheduleID).Scan(&v.ID, &v.TokenID, &v.Beneficiary, &v.Total, &v.Released, &v.Start, &v.Cliff, &v.Duration)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVestingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Releasable returns how much of a schedule could be released at the given time
+// This is the vested amount minus what was already released, or zero if more than that has been released
+func Releasable(ctx context.Context, conn *pgxpool.Pool, scheduleID uuid.UUID, at time.Time) (int, error) {
+	v, err := getVesting(ctx, conn, scheduleID, false)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "scheduleID", scheduleID)
+		return 0, terror.Error(err, "Could not get vesting schedule")
+	}
+	return v.releasable(at), nil
+}
+
+// Release transfers everything releasable right now to the beneficiary and returns the amount
+func Release(ctx context.Context, conn *pgxpool.Pool, scheduleID uuid.UUID) (int, error) {
+	var released int
+	err := withRetry(ctx, conn, func(tx pgx.Tx) error {
+		v, err := getVesting(ctx, tx, scheduleID, true)
+		if err != nil {
+			return err
+		}
+		err = activeToken(ctx, tx, v.TokenID)
+		if err != nil {
+			return err
+		}
+		released = v.releasable(time.Now())
+		if released == 0 {
+			return nil
+		}
+		_, err = debit(ctx, tx, v.TokenID, v.escrow(), released)
+		if err != nil {
+			return err
+		}
+		_, err = credit(ctx, tx, v.TokenID, v.Beneficiary, released)
+		if err != nil {
+			return err
+		}
+		q := `UPDATE vesting_schedules SET released = released + $1, updated_at = now() WHERE id = $2`
+		_, err = tx.Exec(ctx, q, released, scheduleID)
+		if err != nil {
+			return err
+		}
+		_, err = recordEvent(ctx, tx, v.TokenID, EventTransfer, v.escrow(), v.Beneficiary, released)
+		return err
+	})
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "scheduleID", scheduleID)
+		return 0, terror.Error(err, "Could not release vesting")
+	}
+	return released, nil
+}
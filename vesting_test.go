@@ -0,0 +1,111 @@
+package erc20_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"erc20"
+	"erc20/erc20test"
+)
+
+func TestVested(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	v := &erc20.VestingSchedule{
+		Total:    1200,
+		Start:    start,
+		Cliff:    start.Add(90 * 24 * time.Hour),
+		Duration: 360 * 24 * time.Hour,
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{"before start", start.Add(-time.Hour), 0},
+		{"at start", start, 0},
+		{"before cliff", v.Cliff.Add(-time.Second), 0},
+		{"at cliff", v.Cliff, 300},
+		{"half way", start.Add(180 * 24 * time.Hour), 600},
+		{"at end", start.Add(v.Duration), 1200},
+		{"after end", start.Add(2 * v.Duration), 1200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := v.Vested(tt.at)
+			if got != tt.want {
+				t.Errorf("Vested = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelease(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	funder, beneficiary := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, funder, 1000)
+	start := time.Now().Add(-50 * time.Hour)
+	scheduleID, err := erc20.CreateVesting(ctx, conn, tokenID, funder, beneficiary, 1000, start, start.Add(10*time.Hour), 100*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{funder: 0, beneficiary: 0})
+
+	releasable, err := erc20.Releasable(ctx, conn, scheduleID, start.Add(5*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if releasable != 0 {
+		t.Errorf("Releasable before the cliff = %d, want 0", releasable)
+	}
+
+	released, err := erc20.Release(ctx, conn, scheduleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released < 500 || released > 510 {
+		t.Errorf("Release half way = %d, want about 500", released)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{beneficiary: released})
+	wantConserved(t, conn, tokenID)
+
+	releasable, err = erc20.Releasable(ctx, conn, scheduleID, start.Add(20*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if releasable != 0 {
+		t.Errorf("Releasable at a time before the last release = %d, want 0", releasable)
+	}
+	releasable, err = erc20.Releasable(ctx, conn, scheduleID, start.Add(200*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if releasable != 1000-released {
+		t.Errorf("Releasable after the end = %d, want %d", releasable, 1000-released)
+	}
+}
+
+func TestVestingInactiveToken(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	funder, beneficiary := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, funder, 1000)
+	start := time.Now().Add(-time.Hour)
+	scheduleID, err := erc20.CreateVesting(ctx, conn, tokenID, funder, beneficiary, 500, start, start, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = erc20.Pause(ctx, conn, tokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = erc20.Release(ctx, conn, scheduleID)
+	if !errors.Is(err, erc20.ErrPaused) {
+		t.Errorf("Release while paused = %v, want %v", err, erc20.ErrPaused)
+	}
+	_, err = erc20.CreateVesting(ctx, conn, tokenID, funder, beneficiary, 500, start, start, time.Hour)
+	if !errors.Is(err, erc20.ErrPaused) {
+		t.Errorf("CreateVesting while paused = %v, want %v", err, erc20.ErrPaused)
+	}
+	wantBalances(t, conn, tokenID, map[erc20.Address]int{funder: 500, beneficiary: 0})
+}
@@ -0,0 +1,183 @@
+package erc20
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/ninja-software/terror/v2"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the webhook body, keyed by the webhook secret
+const SignatureHeader = "X-ERC20-Signature"
+
+// RegisterWebhook stores an endpoint that is sent every ledger event of the token recorded from now on
+func RegisterWebhook(ctx context.Context, conn *pgxpool.Pool, tokenID uuid.UUID, endpoint string, secret string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return terror.Error(fmt.Errorf("invalid webhook url %q", endpoint), "Invalid webhook URL")
+	}
+	q := `INSERT INTO webhooks (token_id, url, secret) VALUES ($1, $2, $3)`
+	_, err = conn.Exec(ctx, q, tokenID, endpoint, secret)
+	if err != nil {
+		logger(ctx).Errorw(err.Error(), "id", tokenID, "url", endpoint)
+		return terror.Error(err, "Could not register webhook")
+	}
+	return nil
+}
+
+// WebhookPayload is the JSON body POSTed for each ledger event
+type WebhookPayload struct {
+	ID        string    `json:"id"`
+	TokenID   string    `json:"token_id"`
+	Type      EventType `json:"type"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Amount    int       `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDispatcher delivers ledger events to registered webhooks
+type WebhookDispatcher struct {
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// NewWebhookDispatcher creates a dispatcher posting with client
+// Each delivery is attempted up to 3 times before being left for the next DispatchPending
+func NewWebhookDispatcher(client *http.Client) *WebhookDispatcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookDispatcher{
+		client:      client,
+		maxAttempts: 3,
+		backoff:     100 * time.Millisecond,
+	}
+}
+
+// Sign returns the signature sent in SignatureHeader for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type pendingDelivery struct {
+	webhookID uuid.UUID
+	url       string
+	secret    string
+	event     Event
+}
+
+// DispatchPending delivers every event not yet delivered to each webhook of its token and marks it delivered
+// Failed deliveries stay pending for the next call, the first failure is returned once all are attempted
+func (d *WebhookDispatcher) DispatchPending(ctx context.Context, conn *pgxpool.Pool) error {
+	q := `
+SELECT webhooks.id, webhooks.url, webhooks.secret, ` + eventColumns + `
+FROM webhooks
+JOIN ledger_events ON ledger_events.token_id = webhooks.token_id AND ledger_events.created_at >= webhooks.created_at
+WHERE NOT EXISTS (
+	SELECT 1 FROM webhook_deliveries
+	WHERE webhook_deliveries.webhook_id = webhooks.id AND webhook_deliveries.event_id = ledger_events.id
+)
+ORDER BY ledger_events.created_at`
+	rows, err := conn.Query(ctx, q)
+	if err != nil {
+		logger(ctx).Errorw(err.Error())
+		return terror.Error(err, "Could not get pending webhook deliveries")
+	}
+	pending := []pendingDelivery{}
+	for rows.Next() {
+		var p pendingDelivery
+		var eventType string
+		err = rows.Scan(&p.webhookID, &p.url, &p.secret, &p.event.ID, &p.event.TokenID, &eventType, &p.event.From, &p.event.To, &p.event.Amount, &p.event.CreatedAt)
+		if err != nil {
+			rows.Close()
+			logger(ctx).Errorw(err.Error())
+			return terror.Error(err, "Could not scan webhook delivery")
+		}
+		p.event.Type = EventType(eventType)
+		pending = append(pending, p)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		logger(ctx).Errorw(rows.Err().Error())
+		return terror.Error(rows.Err(), "Could not get pending webhook deliveries")
+	}
+
+	var firstErr error
+	for _, p := range pending {
+		err = d.deliver(ctx, p)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "webhook", p.webhookID, "event", p.event.ID)
+			if firstErr == nil {
+				firstErr = terror.Error(err, "Could not deliver webhook")
+			}
+			continue
+		}
+		markQ := `INSERT INTO webhook_deliveries (webhook_id, event_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		_, err = conn.Exec(ctx, markQ, p.webhookID, p.event.ID)
+		if err != nil {
+			logger(ctx).Errorw(err.Error(), "webhook", p.webhookID, "event", p.event.ID)
+			return terror.Error(err, "Could not mark webhook delivered")
+		}
+	}
+	return firstErr
+}
+
+// deliver POSTs a single event, retrying with a linear backoff on transport errors and non 2xx responses
+func (d *WebhookDispatcher) deliver(ctx context.Context, p pendingDelivery) error {
+	body, err := json.Marshal(WebhookPayload{
+		ID:        p.event.ID.String(),
+		TokenID:   p.event.TokenID.String(),
+		Type:      p.event.Type,
+		From:      uuid.UUID(p.event.From).String(),
+		To:        uuid.UUID(p.event.To).String(),
+		Amount:    p.event.Amount,
+		CreatedAt: p.event.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	signature := Sign(p.secret, body)
+	for attempt := 1; ; attempt++ {
+		err = d.post(ctx, p.url, body, signature)
+		if err == nil || attempt >= d.maxAttempts {
+			return err
+		}
+		select {
+		case <-time.After(d.backoff * time.Duration(attempt)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (d *WebhookDispatcher) post(ctx context.Context, endpoint string, body []byte, signature string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package erc20_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"erc20"
+	"erc20/erc20test"
+
+	"github.com/gofrs/uuid"
+)
+
+// webhookServer records every body it accepts, answering its first failures requests with a 500
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	failures int
+	requests int
+	bodies   [][]byte
+	sigs     []string
+}
+
+func newWebhookServer(t *testing.T, failures int) *webhookServer {
+	s := &webhookServer{failures: failures}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if s.requests <= s.failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.bodies = append(s.bodies, body)
+		s.sigs = append(s.sigs, r.Header.Get(erc20.SignatureHeader))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestWebhookDeliverRetries(t *testing.T) {
+	server := newWebhookServer(t, 1)
+	event := erc20.Event{
+		ID:      uuid.Must(uuid.NewV4()),
+		TokenID: uuid.Must(uuid.NewV4()),
+		Type:    erc20.EventTransfer,
+		From:    newAddress(t),
+		To:      newAddress(t),
+		Amount:  25,
+	}
+
+	err := erc20.NewWebhookDispatcher(server.Client()).Deliver(ctx, server.URL, "secret", event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.requests != 2 {
+		t.Errorf("server saw %d requests, want a retry after the 500", server.requests)
+	}
+	if len(server.bodies) != 1 {
+		t.Fatalf("server accepted %d bodies, want 1", len(server.bodies))
+	}
+	if want := erc20.Sign("secret", server.bodies[0]); server.sigs[0] != want {
+		t.Errorf("signature = %q, want %q", server.sigs[0], want)
+	}
+	var payload erc20.WebhookPayload
+	err = json.Unmarshal(server.bodies[0], &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.ID != event.ID.String() || payload.Type != erc20.EventTransfer || payload.Amount != 25 {
+		t.Errorf("payload = %+v", payload)
+	}
+	if payload.From != uuid.UUID(event.From).String() || payload.To != uuid.UUID(event.To).String() {
+		t.Errorf("payload parties = %s to %s", payload.From, payload.To)
+	}
+}
+
+func TestDispatchPending(t *testing.T) {
+	conn := erc20test.NewTestDB(t)
+	owner, recipient := newAddress(t), newAddress(t)
+	tokenID := newToken(t, conn, owner, 1000)
+	server := newWebhookServer(t, 0)
+	err := erc20.RegisterWebhook(ctx, conn, tokenID, server.URL, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer(t, conn, tokenID, owner, recipient, 75)
+
+	dispatcher := erc20.NewWebhookDispatcher(server.Client())
+	err = dispatcher.DispatchPending(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(server.bodies) != 1 {
+		t.Fatalf("delivered %d events, want the transfer only", len(server.bodies))
+	}
+	var payload erc20.WebhookPayload
+	err = json.Unmarshal(server.bodies[0], &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.TokenID != tokenID.String() || payload.To != uuid.UUID(recipient).String() || payload.Amount != 75 {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	err = dispatcher.DispatchPending(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(server.bodies) != 1 {
+		t.Errorf("redelivered a delivered event, %d deliveries", len(server.bodies))
+	}
+}
+
+func TestRegisterWebhookRejectsBadURL(t *testing.T) {
+	for _, url := range []string{"", "ftp://example.com/hook", "http://", "not a url"} {
+		err := erc20.RegisterWebhook(ctx, nil, uuid.Nil, url, "secret")
+		if err == nil {
+			t.Errorf("RegisterWebhook(%q) succeeded", url)
+		}
+	}
+}